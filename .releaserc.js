@@ -20,9 +20,10 @@ module.exports = {
     ],
     "@semantic-release/release-notes-generator",
     [
-      "@semantic-release/changelog",
+      // Changelog entries only list commits touching the module directory
+      "@semantic-release/exec",
       {
-        changelogFile: `${process.env.MODULE_PATH}/CHANGELOG.md`,
+        prepareCmd: `./scripts/update-changelog.sh "${process.env.MODULE_PATH}" "\${nextRelease.version}"`,
       },
     ],
     [
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/essentials/artifacts/internal/dagger"
+)
+
+const (
+	// downloadPath is where downloaded artifacts are written
+	downloadPath = "/download"
+	// assetsPath is where release assets are prepared
+	assetsPath = "/assets"
+)
+
+// githubUploadScript uploads the artifacts of a run as release assets, named
+// <pipeline>-<run-id>-<name>, with directories packed as .tar.gz. The release
+// is created when the tag has none yet.
+const githubUploadScript = `set -eu
+gh release view "$TAG" --repo "$REPOSITORY" >/dev/null 2>&1 ||
+  gh release create "$TAG" --repo "$REPOSITORY" --title "$TAG" --notes "Pipeline artifacts"
+mkdir -p ` + assetsPath + `
+cd "` + stagePath + `/$RUN_PATH"
+for entry in *; do
+  if [ -d "$entry" ]; then
+    tar -czf "` + assetsPath + `/$ASSET_PREFIX$entry.tar.gz" -C "$entry" .
+  else
+    cp "$entry" "` + assetsPath + `/$ASSET_PREFIX$entry"
+  fi
+done
+gh release upload "$TAG" --repo "$REPOSITORY" --clobber ` + assetsPath + `/*`
+
+// githubDownloadScript downloads an artifact uploaded by githubUploadScript
+// and unpacks it to downloadPath/<name>
+const githubDownloadScript = `set -eu
+mkdir -p ` + assetsPath + ` ` + downloadPath + `
+gh release download "$TAG" --repo "$REPOSITORY" --dir ` + assetsPath + ` \
+  --pattern "$ASSET_PREFIX$NAME" --pattern "$ASSET_PREFIX$NAME.tar.gz"
+if [ -f "` + assetsPath + `/$ASSET_PREFIX$NAME.tar.gz" ]; then
+  mkdir -p "` + downloadPath + `/$NAME"
+  tar -xzf "` + assetsPath + `/$ASSET_PREFIX$NAME.tar.gz" -C "` + downloadPath + `/$NAME"
+else
+  mv "` + assetsPath + `/$ASSET_PREFIX$NAME" "` + downloadPath + `/$NAME"
+fi`
+
+// publishS3 copies the artifacts of the run to the bucket, tagging objects
+// with their retention for lifecycle rules
+func (m *Artifacts) publishS3(ctx context.Context, target *url.URL, artifacts *dagger.Directory) (string, error) {
+	destination := m.s3URL(target, m.RunID)
+	manifest := m.Manifest()
+
+	fmt.Printf("📤 Publishing %d artifacts to %s...\n", len(m.Entries), destination)
+	aws, err := m.aws()
+	if err != nil {
+		return "", err
+	}
+	_, err = aws.
+		WithDirectory(stagePath, artifacts).
+		WithExec(append([]string{
+			"aws", "s3", "cp", "--recursive",
+			path.Join(stagePath, m.runPath(m.RunID)), destination,
+			"--metadata", fmt.Sprintf("retention-days=%d,expires-at=%s", m.RetentionDays, manifest.ExpiresAt),
+		}, m.endpointArgs()...)).
+		Sync(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to publish artifacts to %s: %w", destination, err)
+	}
+
+	return destination, nil
+}
+
+// downloadS3 returns an artifact of a run stored in the bucket
+func (m *Artifacts) downloadS3(ctx context.Context, target *url.URL, runID, name string) (*dagger.Directory, error) {
+	if !namePattern.MatchString(name) || !namePattern.MatchString(runID) {
+		return nil, fmt.Errorf("invalid artifact %q of run %q", name, runID)
+	}
+
+	aws, err := m.aws()
+	if err != nil {
+		return nil, err
+	}
+	download := aws.
+		WithDirectory(downloadPath, dag.Directory()).
+		WithExec(append([]string{
+			"aws", "s3", "sync", m.s3URL(target, runID), downloadPath,
+			"--exclude", "*", "--include", name, "--include", name + "/*",
+		}, m.endpointArgs()...))
+
+	entries, err := download.Directory(downloadPath).Entries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download artifact %s: %w", name, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("artifact %s not found for run %s", name, runID)
+	}
+
+	return download.Directory(downloadPath), nil
+}
+
+// publishGitHub uploads the artifacts of the run as assets of a release
+func (m *Artifacts) publishGitHub(ctx context.Context, target *url.URL, artifacts *dagger.Directory) (string, error) {
+	repository, tag, err := githubRelease(target)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("📤 Publishing %d artifacts to %s@%s...\n", len(m.Entries), repository, tag)
+	gh, err := m.gh(repository, tag, m.RunID)
+	if err != nil {
+		return "", err
+	}
+	_, err = gh.
+		WithDirectory(stagePath, artifacts).
+		WithEnvVariable("RUN_PATH", m.runPath(m.RunID)).
+		WithExec([]string{"sh", "-c", githubUploadScript}).
+		Sync(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to publish artifacts to %s@%s: %w", repository, tag, err)
+	}
+
+	return fmt.Sprintf("https://github.com/%s/releases/tag/%s", repository, tag), nil
+}
+
+// downloadGitHub returns an artifact of a run stored as a release asset
+func (m *Artifacts) downloadGitHub(ctx context.Context, target *url.URL, runID, name string) (*dagger.Directory, error) {
+	if !namePattern.MatchString(name) || !namePattern.MatchString(runID) {
+		return nil, fmt.Errorf("invalid artifact %q of run %q", name, runID)
+	}
+
+	repository, tag, err := githubRelease(target)
+	if err != nil {
+		return nil, err
+	}
+
+	gh, err := m.gh(repository, tag, runID)
+	if err != nil {
+		return nil, err
+	}
+	download := gh.
+		WithEnvVariable("NAME", name).
+		WithExec([]string{"sh", "-c", githubDownloadScript})
+	if _, err := download.Sync(ctx); err != nil {
+		return nil, fmt.Errorf("failed to download artifact %s: %w", name, err)
+	}
+
+	return download.Directory(downloadPath), nil
+}
+
+// aws returns an uncached container with the AWS CLI and the S3 credentials
+func (m *Artifacts) aws() (*dagger.Container, error) {
+	if m.AccessKey == nil || m.SecretKey == nil {
+		return nil, fmt.Errorf("access and secret keys are required for %s", m.Destination)
+	}
+
+	return dag.Container().
+		From(awsCliImage).
+		WithoutEntrypoint().
+		WithSecretVariable("AWS_ACCESS_KEY_ID", m.AccessKey).
+		WithSecretVariable("AWS_SECRET_ACCESS_KEY", m.SecretKey).
+		WithEnvVariable("AWS_DEFAULT_REGION", m.Region).
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)), nil
+}
+
+// gh returns an uncached container with the GitHub CLI set up for a release
+func (m *Artifacts) gh(repository, tag, runID string) (*dagger.Container, error) {
+	if m.Token == nil {
+		return nil, fmt.Errorf("a GitHub token is required for %s", m.Destination)
+	}
+
+	return dag.Container().
+		From("alpine:3").
+		WithExec([]string{"apk", "add", "--no-cache", "github-cli"}).
+		WithSecretVariable("GH_TOKEN", m.Token).
+		WithEnvVariable("REPOSITORY", repository).
+		WithEnvVariable("TAG", tag).
+		WithEnvVariable("ASSET_PREFIX", fmt.Sprintf("%s-%s-", m.Pipeline, runID)).
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)), nil
+}
+
+// s3URL returns the location of the artifacts of a run in the bucket
+func (m *Artifacts) s3URL(target *url.URL, runID string) string {
+	return "s3://" + path.Join(target.Host, strings.Trim(target.Path, "/"), m.runPath(runID)) + "/"
+}
+
+// endpointArgs returns the AWS CLI arguments selecting the S3 endpoint
+func (m *Artifacts) endpointArgs() []string {
+	if m.Endpoint == "" {
+		return nil
+	}
+	return []string{"--endpoint-url", m.Endpoint}
+}
+
+// githubRelease parses a github://<owner>/<repo>@<tag> destination
+func githubRelease(target *url.URL) (string, string, error) {
+	repo, tag, ok := strings.Cut(strings.Trim(target.Path, "/"), "@")
+	if !ok || repo == "" || tag == "" || strings.Contains(repo, "/") {
+		return "", "", fmt.Errorf("invalid GitHub destination %q, expected github://<owner>/<repo>@<tag>", target)
+	}
+	return target.Host + "/" + repo, tag, nil
+}
@@ -0,0 +1,283 @@
+// A module to collect pipeline artifacts, such as test reports, SBOMs and
+// builds, and store them in one place: a directory to export, S3 compatible
+// storage (including DigitalOcean Spaces), or GitHub release assets.
+//
+// Artifacts are stored under <pipeline>/<run-id>/ together with an
+// artifacts.json manifest recording when they expire.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/essentials/artifacts/internal/dagger"
+)
+
+const (
+	awsCliImage  = "amazon/aws-cli:2.15.0"
+	manifestName = "artifacts.json"
+	stagePath    = "/artifacts"
+)
+
+// namePattern matches artifact names; names become paths, object keys and
+// release asset names
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Artifacts collects artifacts of a pipeline run and publishes them to a backend
+type Artifacts struct {
+	// Where artifacts are published: local, s3://<bucket>[/<prefix>] or
+	// github://<owner>/<repo>@<tag>
+	Destination   string
+	Pipeline      string
+	RunID         string
+	RetentionDays int
+	// S3 endpoint URL, e.g. https://nyc3.digitaloceanspaces.com
+	Endpoint string
+	Region   string
+
+	// +private
+	Entries []Entry
+	// +private
+	Staged *dagger.Directory
+	// +private
+	Token *dagger.Secret
+	// +private
+	AccessKey *dagger.Secret
+	// +private
+	SecretKey *dagger.Secret
+}
+
+// Entry is an artifact of the run
+type Entry struct {
+	Name string `json:"name"`
+	// file or directory
+	Kind    string `json:"kind"`
+	AddedAt string `json:"addedAt"`
+}
+
+// Manifest describes the artifacts of a run and their retention
+type Manifest struct {
+	Pipeline      string  `json:"pipeline"`
+	RunID         string  `json:"runId"`
+	CreatedAt     string  `json:"createdAt"`
+	RetentionDays int     `json:"retentionDays"`
+	ExpiresAt     string  `json:"expiresAt"`
+	Artifacts     []Entry `json:"artifacts"`
+}
+
+func New(
+	// Where artifacts are published: local (export the result of Directory),
+	// s3://<bucket>[/<prefix>] or github://<owner>/<repo>@<tag>
+	// +optional
+	// +default="local"
+	destination string,
+	// Pipeline name, the first level of the artifact layout
+	// +optional
+	// +default="pipeline"
+	pipeline string,
+	// Run identifier, e.g. $GITHUB_RUN_ID (defaults to the current UTC time)
+	// +optional
+	runId string,
+	// Days artifacts are kept; recorded in the manifest and S3 object metadata
+	// for lifecycle rules to act on
+	// +optional
+	// +default=30
+	retentionDays int,
+	// GitHub token for the github backend
+	// +optional
+	token *dagger.Secret,
+	// S3 access key ID
+	// +optional
+	accessKey *dagger.Secret,
+	// S3 secret access key
+	// +optional
+	secretKey *dagger.Secret,
+	// S3 endpoint URL, for Spaces or other S3 compatible storage
+	// +optional
+	endpoint string,
+	// +optional
+	// +default="us-east-1"
+	region string,
+) *Artifacts {
+	if destination == "" {
+		destination = "local"
+	}
+	if pipeline == "" {
+		pipeline = "pipeline"
+	}
+	if runId == "" {
+		runId = time.Now().UTC().Format("20060102T150405Z")
+	}
+	if retentionDays <= 0 {
+		retentionDays = 30
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	return &Artifacts{
+		Destination:   destination,
+		Pipeline:      pipeline,
+		RunID:         runId,
+		RetentionDays: retentionDays,
+		Endpoint:      endpoint,
+		Region:        region,
+		Staged:        dag.Directory(),
+		Token:         token,
+		AccessKey:     accessKey,
+		SecretKey:     secretKey,
+	}
+}
+
+// WithFile adds a file artifact, e.g. a test report or an SBOM
+func (m *Artifacts) WithFile(
+	ctx context.Context,
+	file *dagger.File,
+	// Artifact name (defaults to the file name)
+	// +optional
+	name string,
+) (*Artifacts, error) {
+	if name == "" {
+		var err error
+		if name, err = file.Name(ctx); err != nil {
+			return nil, fmt.Errorf("failed to get file name: %w", err)
+		}
+	}
+	if err := m.add(name, "file"); err != nil {
+		return nil, err
+	}
+
+	m.Staged = m.Staged.WithFile(m.path(name), file)
+	return m, nil
+}
+
+// WithDirectory adds a directory artifact, e.g. build output
+func (m *Artifacts) WithDirectory(directory *dagger.Directory, name string) (*Artifacts, error) {
+	if err := m.add(name, "directory"); err != nil {
+		return nil, err
+	}
+
+	m.Staged = m.Staged.WithDirectory(m.path(name), directory)
+	return m, nil
+}
+
+// Manifest returns the manifest of the collected artifacts
+func (m *Artifacts) Manifest() *Manifest {
+	created := time.Now().UTC()
+	if len(m.Entries) > 0 {
+		if t, err := time.Parse(time.RFC3339, m.Entries[0].AddedAt); err == nil {
+			created = t
+		}
+	}
+
+	return &Manifest{
+		Pipeline:      m.Pipeline,
+		RunID:         m.RunID,
+		CreatedAt:     created.Format(time.RFC3339),
+		RetentionDays: m.RetentionDays,
+		ExpiresAt:     created.AddDate(0, 0, m.RetentionDays).Format(time.RFC3339),
+		Artifacts:     m.Entries,
+	}
+}
+
+// Directory returns the artifacts in the <pipeline>/<run-id>/ layout with
+// their manifest, ready to export with the local backend
+func (m *Artifacts) Directory() (*dagger.Directory, error) {
+	manifest, err := json.MarshalIndent(m.Manifest(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	return m.Staged.WithNewFile(m.path(manifestName), string(manifest)+"\n"), nil
+}
+
+// Publish uploads the artifacts and their manifest to the destination and
+// returns where they were stored
+func (m *Artifacts) Publish(ctx context.Context) (string, error) {
+	if len(m.Entries) == 0 {
+		return "", fmt.Errorf("no artifacts to publish")
+	}
+
+	artifacts, err := m.Directory()
+	if err != nil {
+		return "", err
+	}
+
+	if m.Destination == "local" {
+		fmt.Printf("📦 %d artifacts staged, export the result of directory to keep them\n", len(m.Entries))
+		return m.path(""), nil
+	}
+
+	target, err := url.Parse(m.Destination)
+	if err != nil || target.Host == "" {
+		return "", fmt.Errorf("invalid destination %q", m.Destination)
+	}
+
+	switch target.Scheme {
+	case "s3":
+		return m.publishS3(ctx, target, artifacts)
+	case "github":
+		return m.publishGitHub(ctx, target, artifacts)
+	default:
+		return "", fmt.Errorf("unsupported destination scheme %q, expected local, s3 or github", target.Scheme)
+	}
+}
+
+// Download returns a published artifact of this pipeline run, or of another
+// run when runId is set
+func (m *Artifacts) Download(
+	ctx context.Context,
+	name string,
+	// +optional
+	runId string,
+) (*dagger.Directory, error) {
+	if runId == "" {
+		runId = m.RunID
+	}
+
+	target, err := url.Parse(m.Destination)
+	if err != nil || target.Host == "" {
+		return nil, fmt.Errorf("artifacts can only be downloaded from s3 or github destinations")
+	}
+
+	switch target.Scheme {
+	case "s3":
+		return m.downloadS3(ctx, target, runId, name)
+	case "github":
+		return m.downloadGitHub(ctx, target, runId, name)
+	default:
+		return nil, fmt.Errorf("unsupported destination scheme %q, expected s3 or github", target.Scheme)
+	}
+}
+
+// add records an artifact, replacing an earlier one with the same name
+func (m *Artifacts) add(name, kind string) error {
+	if !namePattern.MatchString(name) || name == manifestName {
+		return fmt.Errorf("invalid artifact name %q", name)
+	}
+
+	entry := Entry{Name: name, Kind: kind, AddedAt: time.Now().UTC().Format(time.RFC3339)}
+	for i := range m.Entries {
+		if m.Entries[i].Name == name {
+			m.Entries[i] = entry
+			return nil
+		}
+	}
+	m.Entries = append(m.Entries, entry)
+	return nil
+}
+
+// path returns the path of an artifact in the layout
+func (m *Artifacts) path(name string) string {
+	return path.Join(m.Pipeline, m.RunID, name)
+}
+
+// runPath returns the path of the artifacts of a run in the layout
+func (m *Artifacts) runPath(runID string) string {
+	return path.Join(m.Pipeline, runID)
+}
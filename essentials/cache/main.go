@@ -0,0 +1,186 @@
+// A module with the conventions for cache volumes shared by the other modules:
+// names namespaced by project and optionally keyed by lockfile contents,
+// explicit cache busting, and statistics on what a volume holds.
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/essentials/cache/internal/dagger"
+)
+
+// invalidName matches the characters replaced in volume names
+var invalidName = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Cache names and manages the cache volumes of a project
+type Cache struct {
+	// Prefix of every volume name, e.g. the project or module name
+	Namespace string
+	// Whether Bust invalidates cached operations
+	Busting bool
+}
+
+// CacheStats describes the contents of a cache volume
+type CacheStats struct {
+	Volume string
+	Bytes  int
+	Files  int
+}
+
+func New(
+	// Prefix of every volume name, e.g. the project or module name
+	namespace string,
+	// Make Bust invalidate cached operations, e.g. for a nightly clean run
+	// +optional
+	bust bool,
+) *Cache {
+	return &Cache{
+		Namespace: sanitize(namespace),
+		Busting:   bust,
+	}
+}
+
+// Name returns the name of a cache volume: <namespace>-<name>, followed by a
+// hash of the lockfiles when any are given, so the volume starts fresh when
+// dependencies change
+func (m *Cache) Name(
+	ctx context.Context,
+	name string,
+	// Directory the lockfiles are read from
+	// +optional
+	source *dagger.Directory,
+	// Lockfile paths or glob patterns relative to source, e.g. poetry.lock or
+	// **/package-lock.json; missing files are ignored
+	// +optional
+	lockfiles []string,
+) (string, error) {
+	volume := m.Namespace + "-" + sanitize(name)
+	if source == nil || len(lockfiles) == 0 {
+		return volume, nil
+	}
+
+	key, err := lockfileKey(ctx, source, lockfiles)
+	if err != nil {
+		return "", err
+	}
+	if key != "" {
+		volume += "-" + key
+	}
+	return volume, nil
+}
+
+// Volume returns the cache volume named by Name
+func (m *Cache) Volume(
+	ctx context.Context,
+	name string,
+	// Directory the lockfiles are read from
+	// +optional
+	source *dagger.Directory,
+	// Lockfile paths or glob patterns relative to source
+	// +optional
+	lockfiles []string,
+) (*dagger.CacheVolume, error) {
+	volume, err := m.Name(ctx, name, source, lockfiles)
+	if err != nil {
+		return nil, err
+	}
+	return dag.CacheVolume(volume), nil
+}
+
+// Bust returns the container with a unique CACHE_BUSTER variable when busting
+// is enabled, so the operations that follow run again; otherwise the
+// container is returned unchanged
+func (m *Cache) Bust(container *dagger.Container) *dagger.Container {
+	if !m.Busting {
+		return container
+	}
+	return container.WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano))
+}
+
+// Stats returns the size and file count of a cache volume
+func (m *Cache) Stats(
+	ctx context.Context,
+	name string,
+	// +optional
+	source *dagger.Directory,
+	// +optional
+	lockfiles []string,
+) (*CacheStats, error) {
+	volume, err := m.Name(ctx, name, source, lockfiles)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := dag.Container().
+		From("alpine:3").
+		WithMountedCache("/cache", dag.CacheVolume(volume)).
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)).
+		WithExec([]string{"sh", "-c", "du -sk /cache | cut -f1 && find /cache -type f | wc -l"}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache volume %s: %w", volume, err)
+	}
+
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("unexpected output reading cache volume %s: %q", volume, output)
+	}
+	kilobytes, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("unexpected size of cache volume %s: %q", volume, fields[0])
+	}
+	files, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("unexpected file count of cache volume %s: %q", volume, fields[1])
+	}
+
+	stats := &CacheStats{Volume: volume, Bytes: kilobytes * 1024, Files: files}
+	fmt.Printf("📊 %s: %d files, %d KiB\n", volume, files, kilobytes)
+	return stats, nil
+}
+
+// lockfileKey hashes the paths and contents of the lockfiles matching the
+// patterns; it is empty when none match
+func lockfileKey(ctx context.Context, source *dagger.Directory, patterns []string) (string, error) {
+	seen := map[string]bool{}
+	var paths []string
+	for _, pattern := range patterns {
+		matches, err := source.Glob(ctx, pattern)
+		if err != nil {
+			return "", fmt.Errorf("failed to find lockfiles matching %s: %w", pattern, err)
+		}
+		for _, match := range matches {
+			if !seen[match] {
+				seen[match] = true
+				paths = append(paths, match)
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return "", nil
+	}
+	sort.Strings(paths)
+
+	hash := sha256.New()
+	for _, path := range paths {
+		digest, err := source.File(path).Digest(ctx, dagger.FileDigestOpts{ExcludeMetadata: true})
+		if err != nil {
+			return "", fmt.Errorf("failed to hash lockfile %s: %w", path, err)
+		}
+		fmt.Fprintf(hash, "%s\x00%s\n", path, digest)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:12], nil
+}
+
+// sanitize makes a value safe to use in a volume name
+func sanitize(value string) string {
+	return strings.Trim(invalidName.ReplaceAllString(value, "-"), "-")
+}
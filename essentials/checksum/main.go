@@ -3,9 +3,8 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"path/filepath"
-	"strings"
+	"strconv"
 
 	"github.com/felipepimentel/daggerverse/essentials/checksum/internal/dagger"
 )
@@ -33,8 +32,33 @@ func (m *Sha256) Calculate(
 	// +optional
 	// +default="checksums.txt"
 	fileName string,
+
+	// Number of files hashed concurrently (default: number of CPUs).
+	// +optional
+	parallelism int,
 ) *dagger.File {
-	return calculate(ctx, "sha256", fileName, files)
+	return calculate(ctx, "sha256", fileName, files, parallelism)
+}
+
+// Calculate the SHA-256 checksum of every file in a directory, recursively.
+//
+// The directory is hashed in place; paths in the checksum file are relative to it.
+func (m *Sha256) CalculateDirectory(
+	ctx context.Context,
+
+	// The directory to calculate the checksums for.
+	directory *dagger.Directory,
+
+	// The name of the checksum file.
+	// +optional
+	// +default="checksums.txt"
+	fileName string,
+
+	// Number of files hashed concurrently (default: number of CPUs).
+	// +optional
+	parallelism int,
+) *dagger.File {
+	return calculateDirectory(ctx, "sha256", fileName, directory, parallelism)
 }
 
 // Check the SHA-256 checksum of the given files.
@@ -44,53 +68,101 @@ func (m *Sha256) Check(
 
 	// The files to check the checksum if.
 	files []*dagger.File,
+
+	// Number of files checked concurrently (default: number of CPUs).
+	// +optional
+	parallelism int,
+) *dagger.Container {
+	return check("sha256", checksums, files, parallelism)
+}
+
+// Check the SHA-256 checksums of files in a directory, without copying them.
+func (m *Sha256) CheckDirectory(
+	// Checksum file, with paths relative to the directory.
+	checksums *dagger.File,
+
+	// The directory containing the files.
+	directory *dagger.Directory,
+
+	// Number of files checked concurrently (default: number of CPUs).
+	// +optional
+	parallelism int,
 ) *dagger.Container {
-	return check("sha256", checksums, files)
+	return checkDirectory("sha256", checksums, directory, parallelism)
 }
 
-func calculate(ctx context.Context, algo string, fileName string, files []*dagger.File) *dagger.File {
+// calculateScript hashes the files under the working directory with
+// PARALLELISM concurrent processes, one file per process so output lines
+// never interleave, sorted by path for a stable checksum file.
+const calculateScript = `set -eo pipefail
+n="${PARALLELISM}"
+[ "$n" -gt 0 ] || n="$(nproc)"
+find . -type f | sed 's|^\./||' | xargs -P "$n" -I{} "${ALGO}sum" {} | sort -k 2 > "$OUTPUT"`
+
+// checkScript splits the checksum file into PARALLELISM chunks and checks
+// them concurrently, failing if any file does not match.
+const checkScript = `set -e
+n="${PARALLELISM}"
+[ "$n" -gt 0 ] || n="$(nproc)"
+size=$(( ($(wc -l < /checksums.txt) + n - 1) / n ))
+[ "$size" -gt 0 ] || size=1
+split -l "$size" /checksums.txt /tmp/chunk.
+pids=""
+for chunk in /tmp/chunk.*; do
+  "${ALGO}sum" -w -c "$chunk" &
+  pids="$pids $!"
+done
+status=0
+for pid in $pids; do
+  wait "$pid" || status=1
+done
+exit "$status"`
+
+func calculate(ctx context.Context, algo string, fileName string, files []*dagger.File, parallelism int) *dagger.File {
 	dir := dag.Directory()
 
 	for _, file := range files {
 		dir = dir.WithFile("", file)
 	}
 
-	return calculateDirectory(ctx, algo, fileName, dir)
+	return calculateDirectory(ctx, algo, fileName, dir, parallelism)
 }
 
-func calculateDirectory(ctx context.Context, algo string, fileName string, dir *dagger.Directory) *dagger.File {
+func calculateDirectory(ctx context.Context, algo string, fileName string, dir *dagger.Directory, parallelism int) *dagger.File {
 	if fileName == "" {
 		fileName = "checksums.txt"
 	}
 
 	file := filepath.Join("/", filepath.Base(fileName))
 
-	cmd := []string{algo + "sum", "$(ls)", ">", file}
-
 	return dag.Container().
 		From(alpineBaseImage).
 		WithWorkdir("/work").
 		WithMountedDirectory("/work", dir).
-		WithExec([]string{"sh", "-c", strings.Join(cmd, " ")}).
+		WithEnvVariable("ALGO", algo).
+		WithEnvVariable("OUTPUT", file).
+		WithEnvVariable("PARALLELISM", strconv.Itoa(parallelism)).
+		WithExec([]string{"sh", "-c", calculateScript}).
 		File(file)
 }
 
-func check(algo string, checksums *dagger.File, files []*dagger.File) *dagger.Container {
+func check(algo string, checksums *dagger.File, files []*dagger.File, parallelism int) *dagger.Container {
 	dir := dag.Directory()
 
 	for _, file := range files {
 		dir = dir.WithFile("", file)
 	}
 
-	return checkDirectory(algo, checksums, dir)
+	return checkDirectory(algo, checksums, dir, parallelism)
 }
 
-func checkDirectory(algo string, checksums *dagger.File, dir *dagger.Directory) *dagger.Container {
-	dir = dir.WithFile("checksums.txt", checksums)
-
+func checkDirectory(algo string, checksums *dagger.File, dir *dagger.Directory, parallelism int) *dagger.Container {
 	return dag.Container().
 		From(alpineBaseImage).
 		WithWorkdir("/work").
 		WithMountedDirectory("/work", dir).
-		WithExec([]string{"sh", "-c", fmt.Sprintf("%ssum -w -c checksums.txt", algo)})
+		WithMountedFile("/checksums.txt", checksums).
+		WithEnvVariable("ALGO", algo).
+		WithEnvVariable("PARALLELISM", strconv.Itoa(parallelism)).
+		WithExec([]string{"sh", "-c", checkScript})
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/felipepimentel/daggerverse/essentials/curl/internal/dagger"
 )
@@ -11,6 +12,10 @@ import (
 // Curl provides functionality for making HTTP requests
 type Curl struct {
 	client *dagger.Client
+
+	// Requests recorded with Record
+	// +private
+	Exchanges []Exchange
 }
 
 // Header represents an HTTP header
@@ -26,12 +31,16 @@ type RequestConfig struct {
 	Headers        []Header
 	FollowRedirect bool
 	Insecure       bool
+	Fail           bool
 	Output         string
 	Timeout        int
 	RetryAttempts  int
 	RetryDelay     int
 }
 
+// curlImage is the image requests are made from
+const curlImage = "curlimages/curl:latest"
+
 // New creates a new instance of the Curl module
 func New() *Curl {
 	return &Curl{}
@@ -39,6 +48,18 @@ func New() *Curl {
 
 // Request makes an HTTP request with the specified configuration
 func (c *Curl) Request(ctx context.Context, config RequestConfig) (*dagger.Container, error) {
+	args, err := requestArgs(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return dag.Container().
+		From(curlImage).
+		WithExec(args), nil
+}
+
+// requestArgs builds the curl command for a request
+func requestArgs(config RequestConfig) ([]string, error) {
 	if config.URL == "" {
 		return nil, fmt.Errorf("URL is required")
 	}
@@ -65,6 +86,11 @@ func (c *Curl) Request(ctx context.Context, config RequestConfig) (*dagger.Conta
 		args = append(args, "-k")
 	}
 
+	// Add fail option
+	if config.Fail {
+		args = append(args, "--fail")
+	}
+
 	// Add output option
 	if config.Output != "" {
 		args = append(args, "-o", config.Output)
@@ -88,9 +114,7 @@ func (c *Curl) Request(ctx context.Context, config RequestConfig) (*dagger.Conta
 	// Add URL
 	args = append(args, config.URL)
 
-	return dag.Container().
-		From("curlimages/curl:latest").
-		WithExec(args), nil
+	return args, nil
 }
 
 // Head makes a HEAD request to check endpoint availability
@@ -117,15 +141,25 @@ func (c *Curl) Get(ctx context.Context, url string) (*dagger.Container, error) {
 	})
 }
 
-// HealthCheck performs a health check on an endpoint
+// HealthCheck performs a health check on an endpoint, failing on HTTP error
+// responses. It is never cached, so each call hits the endpoint again.
 func (c *Curl) HealthCheck(ctx context.Context, url string) (*dagger.Container, error) {
-	return c.Request(ctx, RequestConfig{
+	args, err := requestArgs(RequestConfig{
 		URL:            url,
 		Method:         "GET",
 		Headers:        []Header{{Key: "Accept", Value: "application/json"}},
 		FollowRedirect: true,
+		Fail:           true,
 		Timeout:        5,
 		RetryAttempts:  3,
 		RetryDelay:     2,
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dag.Container().
+		From(curlImage).
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)).
+		WithExec(args), nil
 } 
\ No newline at end of file
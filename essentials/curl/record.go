@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/essentials/curl/internal/dagger"
+)
+
+// redacted replaces the values of sensitive headers in recordings
+const redacted = "[REDACTED]"
+
+// recordWriteOut makes curl print the transfer details and the response
+// headers as two JSON lines
+const recordWriteOut = "%{json}\n%{header_json}\n"
+
+// sensitiveHeaders are the headers whose values are never recorded
+var sensitiveHeaders = []string{"authorization", "proxy-authorization", "cookie", "set-cookie", "x-api-key"}
+
+// Exchange is a recorded request and its response
+type Exchange struct {
+	StartedAt       string   `json:"startedAt"`
+	Method          string   `json:"method"`
+	URL             string   `json:"url"`
+	RequestHeaders  []Header `json:"requestHeaders"`
+	Status          int      `json:"status"`
+	HTTPVersion     string   `json:"httpVersion"`
+	ResponseHeaders []Header `json:"responseHeaders"`
+	ContentType     string   `json:"contentType"`
+	Size            int      `json:"size"`
+	TimeMs          float64  `json:"timeMs"`
+	Error           string   `json:"error,omitempty"`
+}
+
+// transfer mirrors the fields of curl's %{json} write-out used in recordings
+type transfer struct {
+	Method      string  `json:"method"`
+	URL         string  `json:"url_effective"`
+	Status      int     `json:"response_code"`
+	HTTPVersion string  `json:"http_version"`
+	ContentType string  `json:"content_type"`
+	Size        float64 `json:"size_download"`
+	TimeTotal   float64 `json:"time_total"`
+}
+
+// Record makes an HTTP request and adds it to the recording of the run, with
+// sensitive header values redacted and without bodies. Failed requests are
+// recorded too; export the recording with Log or Har.
+func (c *Curl) Record(ctx context.Context, config RequestConfig) (*Curl, error) {
+	config.Output = ""
+	args, err := requestArgs(config)
+	if err != nil {
+		return nil, err
+	}
+	args = append([]string{"curl", "-sS", "-o", "/dev/null", "-w", recordWriteOut}, args[1:]...)
+
+	method := strings.ToUpper(config.Method)
+	if method == "" {
+		method = "GET"
+	}
+	exchange := Exchange{
+		StartedAt:      time.Now().UTC().Format(time.RFC3339Nano),
+		Method:         method,
+		URL:            config.URL,
+		RequestHeaders: sanitizeHeaders(config.Headers),
+	}
+
+	ctr := dag.Container().
+		From(curlImage).
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)).
+		WithExec(args, dagger.ContainerWithExecOpts{Expect: dagger.ReturnTypeAny})
+
+	exitCode, err := ctr.ExitCode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run request to %s: %w", config.URL, err)
+	}
+	output, err := ctr.Stdout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if exitCode != 0 {
+		stderr, _ := ctr.Stderr(ctx)
+		exchange.Error = fmt.Sprintf("curl exited with %d: %s", exitCode, strings.TrimSpace(stderr))
+	}
+
+	lines := strings.SplitN(strings.TrimSpace(output), "\n", 2)
+	var details transfer
+	if err := json.Unmarshal([]byte(lines[0]), &details); err == nil {
+		exchange.URL = details.URL
+		exchange.Status = details.Status
+		exchange.HTTPVersion = details.HTTPVersion
+		exchange.ContentType = details.ContentType
+		exchange.Size = int(details.Size)
+		exchange.TimeMs = details.TimeTotal * 1000
+		if details.Method != "" {
+			exchange.Method = details.Method
+		}
+	}
+	if len(lines) == 2 {
+		var headers map[string][]string
+		if err := json.Unmarshal([]byte(lines[1]), &headers); err == nil {
+			exchange.ResponseHeaders = flattenHeaders(headers)
+		}
+	}
+
+	fmt.Printf("📼 %s %s -> %d (%.0fms)\n", exchange.Method, exchange.URL, exchange.Status, exchange.TimeMs)
+	c.Exchanges = append(c.Exchanges, exchange)
+	return c, nil
+}
+
+// Log returns the recording as JSON Lines, one exchange per line
+func (c *Curl) Log(
+	// +optional
+	// +default="requests.jsonl"
+	name string,
+) (*dagger.File, error) {
+	if name == "" {
+		name = "requests.jsonl"
+	}
+
+	var lines strings.Builder
+	for _, exchange := range c.Exchanges {
+		line, err := json.Marshal(exchange)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode exchange: %w", err)
+		}
+		lines.Write(line)
+		lines.WriteString("\n")
+	}
+
+	return dag.Directory().WithNewFile(name, lines.String()).File(name), nil
+}
+
+// Har returns the recording as a HAR 1.2 file, for browser dev tools and HAR viewers
+func (c *Curl) Har(
+	// +optional
+	// +default="requests.har"
+	name string,
+) (*dagger.File, error) {
+	if name == "" {
+		name = "requests.har"
+	}
+
+	entries := make([]map[string]any, 0, len(c.Exchanges))
+	for _, exchange := range c.Exchanges {
+		entries = append(entries, map[string]any{
+			"startedDateTime": exchange.StartedAt,
+			"time":            exchange.TimeMs,
+			"request": map[string]any{
+				"method":      exchange.Method,
+				"url":         exchange.URL,
+				"httpVersion": harHTTPVersion(exchange.HTTPVersion),
+				"headers":     harHeaders(exchange.RequestHeaders),
+				"queryString": []any{},
+				"cookies":     []any{},
+				"headersSize": -1,
+				"bodySize":    -1,
+			},
+			"response": map[string]any{
+				"status":      exchange.Status,
+				"statusText":  "",
+				"httpVersion": harHTTPVersion(exchange.HTTPVersion),
+				"headers":     harHeaders(exchange.ResponseHeaders),
+				"cookies":     []any{},
+				"content":     map[string]any{"size": exchange.Size, "mimeType": exchange.ContentType},
+				"redirectURL": "",
+				"headersSize": -1,
+				"bodySize":    exchange.Size,
+				"_error":      exchange.Error,
+			},
+			"cache":   map[string]any{},
+			"timings": map[string]any{"send": 0, "wait": exchange.TimeMs, "receive": 0},
+		})
+	}
+
+	har, err := json.MarshalIndent(map[string]any{
+		"log": map[string]any{
+			"version": "1.2",
+			"creator": map[string]any{"name": "daggerverse-curl", "version": "1.0"},
+			"entries": entries,
+		},
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode HAR: %w", err)
+	}
+
+	return dag.Directory().WithNewFile(name, string(har)+"\n").File(name), nil
+}
+
+// sanitizeHeaders returns a copy of the headers with sensitive values redacted
+func sanitizeHeaders(headers []Header) []Header {
+	sanitized := make([]Header, 0, len(headers))
+	for _, header := range headers {
+		if isSensitive(header.Key) {
+			header.Value = redacted
+		}
+		sanitized = append(sanitized, header)
+	}
+	return sanitized
+}
+
+// flattenHeaders converts curl's header JSON to sanitized headers sorted by name
+func flattenHeaders(headers map[string][]string) []Header {
+	var flat []Header
+	for key, values := range headers {
+		for _, value := range values {
+			flat = append(flat, Header{Key: key, Value: value})
+		}
+	}
+	sort.SliceStable(flat, func(i, j int) bool { return flat[i].Key < flat[j].Key })
+	return sanitizeHeaders(flat)
+}
+
+// isSensitive reports whether a header may carry credentials
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, name := range sensitiveHeaders {
+		if key == name {
+			return true
+		}
+	}
+	return strings.Contains(key, "token") || strings.Contains(key, "secret")
+}
+
+// harHeaders converts headers to HAR name/value pairs
+func harHeaders(headers []Header) []map[string]string {
+	pairs := make([]map[string]string, 0, len(headers))
+	for _, header := range headers {
+		pairs = append(pairs, map[string]string{"name": header.Key, "value": header.Value})
+	}
+	return pairs
+}
+
+// harHTTPVersion formats curl's HTTP version (e.g. 1.1 or 2) as in HAR
+func harHTTPVersion(version string) string {
+	if version == "" {
+		return ""
+	}
+	return "HTTP/" + version
+}
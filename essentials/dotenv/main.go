@@ -0,0 +1,162 @@
+// A module to inject environment variables from .env files and secrets into
+// containers.
+//
+// Values loaded as secrets are set with WithSecretVariable, so they stay out of
+// the layer cache and are redacted from Dagger's logs.
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/felipepimentel/daggerverse/essentials/dotenv/internal/dagger"
+)
+
+// Dotenv holds environment variables to inject into containers
+type Dotenv struct {
+	// +private
+	Variables []Variable
+}
+
+// Variable is a single environment variable, set from either Value or Secret
+type Variable struct {
+	Name   string
+	Value  string
+	Secret *dagger.Secret
+}
+
+func New() *Dotenv {
+	return &Dotenv{}
+}
+
+// WithVariable adds a plain variable, replacing one with the same name
+func (m *Dotenv) WithVariable(name string, value string) *Dotenv {
+	return m.with(Variable{Name: name, Value: value})
+}
+
+// WithSecretVariable adds a variable from a secret, replacing one with the
+// same name
+func (m *Dotenv) WithSecretVariable(name string, secret *dagger.Secret) *Dotenv {
+	return m.with(Variable{Name: name, Secret: secret})
+}
+
+// WithEnvFile adds the variables of a .env file. Later files override
+// variables of earlier ones.
+func (m *Dotenv) WithEnvFile(
+	ctx context.Context,
+	file *dagger.File,
+	// Load every value as a secret
+	// +optional
+	secret bool,
+) (*Dotenv, error) {
+	contents, err := file.Contents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+
+	prefix := ""
+	if secret {
+		// Secret names are scoped to the file, so the same variable loaded
+		// from two files does not share one secret
+		digest, err := file.Digest(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read env file: %w", err)
+		}
+		prefix = strings.TrimPrefix(digest, "sha256:")[:12]
+	}
+
+	return m.withEnv(contents, secret, prefix)
+}
+
+// WithSecretEnvFile adds the variables of a .env file stored as a secret;
+// every value is loaded as a secret
+func (m *Dotenv) WithSecretEnvFile(ctx context.Context, file *dagger.Secret) (*Dotenv, error) {
+	name, err := file.Name(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+	contents, err := file.Plaintext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+
+	return m.withEnv(contents, true, name)
+}
+
+// Names returns the names of the variables, sorted
+func (m *Dotenv) Names() []string {
+	names := make([]string, 0, len(m.Variables))
+	for _, v := range m.Variables {
+		names = append(names, v.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Redacted returns the variables in .env format with secret values masked,
+// safe to print for debugging
+func (m *Dotenv) Redacted() string {
+	var b strings.Builder
+	for _, v := range m.sorted() {
+		value := v.Value
+		if v.Secret != nil {
+			value = "***"
+		}
+		fmt.Fprintf(&b, "%s=%s\n", v.Name, value)
+	}
+	return b.String()
+}
+
+// Apply sets the variables on a container
+func (m *Dotenv) Apply(container *dagger.Container) *dagger.Container {
+	for _, v := range m.sorted() {
+		if v.Secret != nil {
+			container = container.WithSecretVariable(v.Name, v.Secret)
+		} else {
+			container = container.WithEnvVariable(v.Name, v.Value)
+		}
+	}
+	return container
+}
+
+// with adds a variable, replacing one with the same name
+func (m *Dotenv) with(variable Variable) *Dotenv {
+	for i := range m.Variables {
+		if m.Variables[i].Name == variable.Name {
+			m.Variables[i] = variable
+			return m
+		}
+	}
+	m.Variables = append(m.Variables, variable)
+	return m
+}
+
+// withEnv adds the variables of .env contents; secret values are stored as
+// secrets named after the source and the variable
+func (m *Dotenv) withEnv(contents string, secret bool, source string) (*Dotenv, error) {
+	variables, err := parse(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range variables {
+		if secret {
+			m.with(Variable{Name: v.Name, Secret: dag.SetSecret(fmt.Sprintf("dotenv-%s-%s", source, v.Name), v.Value)})
+		} else {
+			m.with(v)
+		}
+	}
+	return m, nil
+}
+
+// sorted returns the variables sorted by name, so containers get the same
+// environment, and cache keys, regardless of insertion order
+func (m *Dotenv) sorted() []Variable {
+	variables := append([]Variable(nil), m.Variables...)
+	sort.Slice(variables, func(i, j int) bool {
+		return variables[i].Name < variables[j].Name
+	})
+	return variables
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/essentials/ssh/internal/dagger"
+)
+
+// auditNamespace is the ssh-keygen signature namespace of audit logs; verify with
+// ssh-keygen -Y verify -n daggerverse-ssh-audit -f allowed_signers -I <identity> -s audit.jsonl.sig < audit.jsonl
+const auditNamespace = "daggerverse-ssh-audit"
+
+// maxAuditOutput caps the stdout and stderr recorded per command
+const maxAuditOutput = 64 * 1024
+
+// AuditEntry records a command run on a remote host
+type AuditEntry struct {
+	Destination string   `json:"destination"`
+	Command     []string `json:"command"`
+	StartedAt   string   `json:"startedAt"`
+	FinishedAt  string   `json:"finishedAt"`
+	ExitCode    int      `json:"exitCode"`
+	Stdout      string   `json:"stdout"`
+	Stderr      string   `json:"stderr"`
+}
+
+// Record runs a command on the remote host and adds it, with its timestamps
+// and output, to the audit log of the session. A failing command returns an
+// error unless allowFailure is set, in which case it is recorded like any
+// other and the session continues.
+func (m *Ssh) Record(
+	ctx context.Context,
+	// Command to run on the remote host
+	args []string,
+	// Record a failing command without returning an error
+	// +optional
+	allowFailure bool,
+) (*Ssh, error) {
+	ctr := m.command(
+		m.BaseCtr.WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)),
+		dagger.ContainerWithExecOpts{Expect: dagger.ReturnTypeAny},
+		args...,
+	)
+
+	entry := AuditEntry{
+		Destination: m.Destination,
+		Command:     args,
+		StartedAt:   time.Now().UTC().Format(time.RFC3339Nano),
+	}
+
+	exitCode, err := ctr.ExitCode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run command on %s: %w", m.Destination, err)
+	}
+	entry.FinishedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	entry.ExitCode = exitCode
+
+	stdout, err := ctr.Stdout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := ctr.Stderr(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entry.Stdout = truncateOutput(stdout)
+	entry.Stderr = truncateOutput(stderr)
+
+	recorded := *m
+	recorded.Audit = append(append([]AuditEntry{}, m.Audit...), entry)
+
+	fmt.Printf("📝 %s: %s (exit %d)\n", m.Destination, strings.Join(args, " "), exitCode)
+	if exitCode != 0 && !allowFailure {
+		return nil, fmt.Errorf("command %q failed on %s with exit code %d: %s",
+			strings.Join(args, " "), m.Destination, exitCode, strings.TrimSpace(stderr))
+	}
+
+	return &recorded, nil
+}
+
+// AuditLog returns the audit log of the session as audit.jsonl, one command
+// per line, with its SHA-256 checksum. With a signing key the log is also
+// signed with ssh-keygen into audit.jsonl.sig, and the public key of the
+// signer is written to signer.pub.
+func (m *Ssh) AuditLog(
+	ctx context.Context,
+	// Private SSH key signing the log
+	// +optional
+	signingKey *dagger.Secret,
+) (*dagger.Directory, error) {
+	var log strings.Builder
+	for _, entry := range m.Audit {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode audit entry: %w", err)
+		}
+		log.Write(line)
+		log.WriteString("\n")
+	}
+
+	ctr := m.BaseCtr.
+		WithWorkdir("/audit").
+		WithNewFile("/audit/audit.jsonl", log.String()).
+		WithExec([]string{"sh", "-c", "sha256sum audit.jsonl > audit.jsonl.sha256"})
+
+	if signingKey != nil {
+		fmt.Println("🔏 Signing audit log...")
+		ctr = ctr.
+			WithExec([]string{"apk", "add", "--no-cache", "openssh-keygen"}).
+			WithMountedSecret("/run/secrets/signing-key", signingKey).
+			WithExec([]string{"ssh-keygen", "-Y", "sign", "-f", "/run/secrets/signing-key", "-n", auditNamespace, "audit.jsonl"}).
+			WithExec([]string{"sh", "-c", "ssh-keygen -y -f /run/secrets/signing-key > signer.pub"})
+	}
+
+	ctr, err := ctr.Sync(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	fmt.Printf("✅ Audit log has %d command(s)\n", len(m.Audit))
+	return ctr.Directory("/audit"), nil
+}
+
+// truncateOutput caps recorded output at maxAuditOutput bytes
+func truncateOutput(output string) string {
+	if len(output) <= maxAuditOutput {
+		return output
+	}
+	return output[:maxAuditOutput] + fmt.Sprintf("\n[truncated %d bytes]", len(output)-maxAuditOutput)
+}
@@ -14,6 +14,8 @@ type Ssh struct {
 	BaseCtr     *dagger.Container
 	Destination string
 	Opts        []SshOpts
+	// Commands run with Record
+	Audit []AuditEntry
 }
 
 type SshOpts struct {
@@ -89,8 +91,11 @@ func (m *Ssh) GenerateKeys(ctx context.Context) (*SSHKeys, error) {
 
 // example usage: "dagger call --destination USER@HOST --identity-file file:${HOME}/.ssh/id_ed25519 command --args whoami stdout"
 func (m *Ssh) Command(args ...string) *dagger.Container {
-	ctr := m.BaseCtr
+	return m.command(m.BaseCtr, dagger.ContainerWithExecOpts{}, args...)
+}
 
+// command runs ssh with the session options in ctr
+func (m *Ssh) command(ctr *dagger.Container, opts dagger.ContainerWithExecOpts, args ...string) *dagger.Container {
 	execArgs := []string{"/usr/bin/ssh", "-o", "StrictHostKeyChecking=no"}
 	for i, o := range m.Opts {
 		if o.IdentityFile != nil {
@@ -112,5 +117,5 @@ func (m *Ssh) Command(args ...string) *dagger.Container {
 	// add the command args
 	execArgs = append(execArgs, args...)
 
-	return ctr.WithExec(execArgs)
+	return ctr.WithExec(execArgs, opts)
 }
@@ -8,11 +8,32 @@ import (
 	"github.com/felipepimentel/daggerverse/essentials/versioner/internal/dagger"
 )
 
+// Bump strategies supported by BumpVersion
+const (
+	// StrategyConventional bumps major, minor or patch from the conventional
+	// commit type of the latest commit
+	StrategyConventional = "conventional"
+	// StrategyPatch always bumps the patch version
+	StrategyPatch = "patch"
+)
+
 // Versioner implements version management for repositories
 type Versioner struct {
 	// GitHub token for authentication
 	// +private
 	GitHubToken *dagger.Secret
+
+	// Remote repository URL, used when the source has no origin configured
+	// +private
+	Remote string
+
+	// Branch to sync with and push to
+	// +private
+	Branch string
+
+	// How the next version is derived: conventional or patch
+	// +private
+	Strategy string
 }
 
 // New creates a new Versioner instance
@@ -20,41 +41,77 @@ func New(
 	// GitHub token for authentication
 	// +optional
 	githubToken *dagger.Secret,
-) *Versioner {
-	return &Versioner{
+	// Remote repository URL (e.g. https://github.com/owner/repo.git).
+	// Required when the source is not a git repository.
+	// +optional
+	remote string,
+	// Branch to sync with and push to
+	// +optional
+	// +default="main"
+	branch string,
+	// How the next version is derived: "conventional" bumps from the commit
+	// type of the latest commit, "patch" always bumps the patch version
+	// +optional
+	// +default="conventional"
+	strategy string,
+) (*Versioner, error) {
+	m := &Versioner{
 		GitHubToken: githubToken,
+		Remote:      remote,
 	}
+	return m.WithBranch(branch).WithStrategy(strategy)
 }
 
-// BumpVersion creates a new version tag based on the latest tag and commit type
-func (m *Versioner) BumpVersion(ctx context.Context, source *dagger.Directory, outputVersion bool) (string, error) {
-	container := dag.Container().
-		From("alpine:latest").
-		WithDirectory("/src", source).
-		WithWorkdir("/src").
-		WithExec([]string{"apk", "add", "--no-cache", "git", "openssh"})
-
-	// Configure git
-	container = container.WithExec([]string{"git", "config", "--global", "user.email", "github-actions[bot]@users.noreply.github.com"}).
-		WithExec([]string{"git", "config", "--global", "user.name", "github-actions[bot]"})
+// WithRemote sets the remote repository URL
+func (m *Versioner) WithRemote(remote string) *Versioner {
+	m.Remote = remote
+	return m
+}
 
-	// Get repository URL from remote
-	repoUrl, err := container.WithExec([]string{
-		"sh", "-c",
-		"git remote get-url origin 2>/dev/null || echo ''",
-	}).Stdout(ctx)
-	if err != nil {
-		return "", fmt.Errorf("error getting repository URL: %w", err)
+// WithBranch sets the branch to sync with and push to, main when empty
+func (m *Versioner) WithBranch(branch string) *Versioner {
+	if branch == "" {
+		branch = "main"
 	}
+	m.Branch = branch
+	return m
+}
 
-	// If GitHub token is provided, use it to authenticate
-	if m.GitHubToken != nil {
-		repoUrl = strings.TrimSpace(repoUrl)
-		if strings.HasPrefix(repoUrl, "https://github.com/") {
-			repoUrl = strings.Replace(repoUrl, "https://", "https://x-access-token:$GITHUB_TOKEN@", 1)
-			container = container.WithSecretVariable("GITHUB_TOKEN", m.GitHubToken)
-		}
+// WithStrategy sets how the next version is derived: conventional or patch,
+// conventional when empty
+func (m *Versioner) WithStrategy(strategy string) (*Versioner, error) {
+	if strategy == "" {
+		strategy = StrategyConventional
+	}
+	if strategy != StrategyConventional && strategy != StrategyPatch {
+		return nil, fmt.Errorf("unsupported bump strategy %q, expected %s or %s", strategy, StrategyConventional, StrategyPatch)
 	}
+	m.Strategy = strategy
+	return m, nil
+}
+
+// WithGitHubToken sets the token used to authenticate against the remote
+func (m *Versioner) WithGitHubToken(token *dagger.Secret) *Versioner {
+	m.GitHubToken = token
+	return m
+}
+
+// BumpVersion creates a new version tag based on the latest tag and, with the
+// conventional strategy, the commit type of the latest commit
+func (m *Versioner) BumpVersion(
+	ctx context.Context,
+	source *dagger.Directory,
+	outputVersion bool,
+	// Compute the next version without creating or pushing any tag
+	// +optional
+	dryRun bool,
+) (string, error) {
+	// New and the With functions default and validate the branch and strategy
+	branch := m.Branch
+
+	// The git module configures the author and the token credentials
+	git := dag.GitRepo(dagger.GitRepoOpts{Source: source, Token: m.GitHubToken})
+	container := git.Container()
 
 	// Ensure repository is initialized
 	gitStatus, err := container.WithExec([]string{"sh", "-c", "[ -d .git ] && echo 'true' || echo 'false'"}).Stdout(ctx)
@@ -63,19 +120,31 @@ func (m *Versioner) BumpVersion(ctx context.Context, source *dagger.Directory, o
 	}
 
 	if strings.TrimSpace(gitStatus) == "false" {
+		if m.Remote == "" {
+			return "", fmt.Errorf("source is not a git repository and no remote was provided")
+		}
+
 		container = container.
 			WithExec([]string{"git", "init"}).
-			WithExec([]string{"git", "remote", "add", "origin", repoUrl}).
+			WithExec([]string{"git", "remote", "add", "origin", m.Remote}).
 			WithExec([]string{"git", "fetch", "--tags", "origin"}).
-			WithExec([]string{"git", "checkout", "-b", "main"}).
-			WithExec([]string{"git", "pull", "--rebase", "origin", "main"}).
+			WithExec([]string{"git", "checkout", "-b", branch}).
+			WithExec([]string{"git", "pull", "--rebase", "origin", branch}).
 			WithExec([]string{"git", "add", "."}).
 			WithExec([]string{"git", "commit", "-m", "Initial commit"})
 	} else {
+		if m.Remote != "" {
+			container = container.WithExec([]string{
+				"sh", "-c",
+				`git remote set-url origin "$0" 2>/dev/null || git remote add origin "$0"`,
+				m.Remote,
+			})
+		}
+
 		// Sync with remote branch and tags
 		container = container.
 			WithExec([]string{"git", "fetch", "--tags", "origin"}).
-			WithExec([]string{"git", "pull", "--rebase", "origin", "main"})
+			WithExec([]string{"git", "pull", "--rebase", "origin", branch})
 	}
 
 	// Get all tags sorted by version
@@ -87,20 +156,15 @@ func (m *Versioner) BumpVersion(ctx context.Context, source *dagger.Directory, o
 		return "", fmt.Errorf("error getting tags: %w", err)
 	}
 
-	// Get the latest commit message and hash
+	// Get the latest commit message
 	commitMsg, err := container.WithExec([]string{
 		"sh", "-c",
-		"git log -1 --pretty=%B%n%H",
+		"git log -1 --pretty=%B",
 	}).Stdout(ctx)
 	if err != nil {
 		return "", fmt.Errorf("error getting commit message: %w", err)
 	}
 
-	// Split commit message and hash
-	parts := strings.Split(strings.TrimSpace(commitMsg), "\n")
-	commitHash := parts[len(parts)-1]
-	commitMsg = strings.Join(parts[:len(parts)-1], "\n")
-
 	// Find the highest version among all tags
 	var major, minor, patch int
 	tags := strings.Split(strings.TrimSpace(output), "\n")
@@ -119,21 +183,12 @@ func (m *Versioner) BumpVersion(ctx context.Context, source *dagger.Directory, o
 			}
 		}
 
-		// Check if the latest tag points to the current commit
-		for _, tag := range tags {
-			tagHash, err := container.WithExec([]string{
-				"git", "rev-parse", tag,
-			}).Stdout(ctx)
-			if err == nil && strings.TrimSpace(tagHash) == commitHash {
-				// This commit already has a tag, increment patch version
-				patch++
-				break
-			}
-		}
-
-		// Determine version bump based on commit message
+		// Determine version bump based on commit message; a commit that is
+		// already tagged gets a new version from the single bump below
 		commitMsg = strings.ToLower(strings.TrimSpace(commitMsg))
-		if strings.Contains(commitMsg, "breaking change") || strings.Contains(commitMsg, "!:") {
+		if m.Strategy == StrategyPatch {
+			patch++
+		} else if strings.Contains(commitMsg, "breaking change") || strings.Contains(commitMsg, "!:") {
 			major++
 			minor = 0
 			patch = 0
@@ -146,26 +201,26 @@ func (m *Versioner) BumpVersion(ctx context.Context, source *dagger.Directory, o
 		}
 	}
 
-	// Create new tag
 	newTag := fmt.Sprintf("v%d.%d.%d", major, minor, patch)
-	_, err = container.WithExec([]string{
-		"git", "tag", "-a", newTag, "-m", fmt.Sprintf("Release %s", newTag),
-	}).Stdout(ctx)
-	if err != nil {
+
+	if dryRun {
+		fmt.Printf("Dry run: next version would be %s\n", newTag)
+		return strings.TrimPrefix(newTag, "v"), nil
+	}
+
+	// Create new tag
+	tagged := git.
+		WithSource(container.Directory("/src")).
+		Tag(newTag, dagger.GitRepoTagOpts{Message: fmt.Sprintf("Release %s", newTag)})
+	if _, err := tagged.Directory().Sync(ctx); err != nil {
 		return "", fmt.Errorf("error creating tag: %w", err)
 	}
 
-	// Push branch and ensure sync with remote
+	// Push branch and tags to remote
 	if m.GitHubToken != nil {
-		// Push branch and tags to remote
-		_, err = container.WithExec([]string{"git", "push", "--set-upstream", "origin", "main"}).Stdout(ctx)
-		if err != nil {
-			return "", fmt.Errorf("error pushing branch to remote: %w", err)
-		}
-
-		_, err = container.WithExec([]string{"git", "push", "origin", "--tags"}).Stdout(ctx)
+		_, err = tagged.Push(ctx, dagger.GitRepoPushOpts{Refs: []string{branch}, Tags: true})
 		if err != nil {
-			return "", fmt.Errorf("error pushing tags to remote: %w", err)
+			return "", fmt.Errorf("error pushing to remote: %w", err)
 		}
 	}
 
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/essentials/versioner/internal/dagger"
+)
+
+// tagFormat prints the name, object, peeled commit (annotated tags only) and
+// creation date of a tag, tab separated
+const tagFormat = "%(refname:strip=2)%09%(objectname)%09%(*objectname)%09%(creatordate:unix)"
+
+// Tag is a tag of the repository
+type Tag struct {
+	// Name of the tag, e.g. v1.2.3-rc.1
+	Name string
+	// Commit the tag points to
+	Commit string
+	// When the tag (or, for lightweight tags, its commit) was created, in RFC 3339
+	Date string
+	// Whether the tag is a pre-release version, e.g. v1.2.3-rc.1
+	Prerelease bool
+}
+
+// ListTags returns the tags of the remote matching a pattern, oldest first
+func (m *Versioner) ListTags(
+	ctx context.Context,
+	// Repository to read the tags of (cloned from the remote when omitted)
+	// +optional
+	source *dagger.Directory,
+	// Glob the tag names match, e.g. v* or mymodule/v*
+	// +optional
+	// +default="v*"
+	pattern string,
+) ([]Tag, error) {
+	git, err := m.repository(source)
+	if err != nil {
+		return nil, err
+	}
+	return listTags(ctx, git, pattern)
+}
+
+// listTags returns the tags of a repository matching a pattern, oldest first
+func listTags(ctx context.Context, git *dagger.GitRepo, pattern string) ([]Tag, error) {
+	if pattern == "" {
+		pattern = "v*"
+	}
+
+	output, err := git.Container().
+		WithExec([]string{"git", "for-each-ref", "--sort=creatordate", "--format=" + tagFormat, "refs/tags/" + pattern}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing tags: %w", err)
+	}
+
+	var tags []Tag
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 {
+			continue
+		}
+		commit := fields[1]
+		if fields[2] != "" {
+			commit = fields[2]
+		}
+		created, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("error reading date of tag %s: %w", fields[0], err)
+		}
+		tags = append(tags, Tag{
+			Name:       fields[0],
+			Commit:     commit,
+			Date:       time.Unix(created, 0).UTC().Format(time.RFC3339),
+			Prerelease: isPrerelease(fields[0]),
+		})
+	}
+	return tags, nil
+}
+
+// CleanupPrereleases deletes the pre-release tags older than a number of days
+// from the remote, returning their names. Releases are never deleted.
+func (m *Versioner) CleanupPrereleases(
+	ctx context.Context,
+	// Repository to clean up the tags of (cloned from the remote when omitted)
+	// +optional
+	source *dagger.Directory,
+	// Glob the tag names match, e.g. v* or mymodule/v*
+	// +optional
+	// +default="v*"
+	pattern string,
+	// Age in days from which pre-release tags are deleted
+	// +optional
+	// +default=30
+	olderThan int,
+	// List the tags that would be deleted without deleting them
+	// +optional
+	dryRun bool,
+) ([]string, error) {
+	if olderThan <= 0 {
+		olderThan = 30
+	}
+
+	git, err := m.repository(source)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := listTags(ctx, git, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -olderThan)
+	var stale, refs []string
+	for _, tag := range tags {
+		created, err := time.Parse(time.RFC3339, tag.Date)
+		if err != nil {
+			return nil, fmt.Errorf("error reading date of tag %s: %w", tag.Name, err)
+		}
+		if tag.Prerelease && created.Before(cutoff) {
+			stale = append(stale, tag.Name)
+			refs = append(refs, ":refs/tags/"+tag.Name)
+		}
+	}
+
+	if len(stale) == 0 {
+		fmt.Printf("No pre-release tags older than %d days\n", olderThan)
+		return stale, nil
+	}
+	if dryRun {
+		fmt.Printf("Dry run: would delete %d pre-release tags: %s\n", len(stale), strings.Join(stale, ", "))
+		return stale, nil
+	}
+	if m.GitHubToken == nil {
+		return nil, fmt.Errorf("a GitHub token is required to delete tags")
+	}
+
+	fmt.Printf("🧹 Deleting %d pre-release tags older than %d days...\n", len(stale), olderThan)
+	if _, err := git.Push(ctx, dagger.GitRepoPushOpts{Refs: refs}); err != nil {
+		return nil, fmt.Errorf("error deleting tags: %w", err)
+	}
+	return stale, nil
+}
+
+// UnreachableTags returns the tags matching a pattern whose commit is not
+// reachable from the branch, e.g. tags of rebased or deleted work
+func (m *Versioner) UnreachableTags(
+	ctx context.Context,
+	// Repository to check the tags of (cloned from the remote when omitted)
+	// +optional
+	source *dagger.Directory,
+	// Glob the tag names match, e.g. v* or mymodule/v*
+	// +optional
+	// +default="v*"
+	pattern string,
+) ([]string, error) {
+	branch := m.Branch
+
+	git, err := m.repository(source)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := listTags(ctx, git, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	// merge-base exits 1 when the commit is not an ancestor of the branch
+	// and with another code when it is missing
+	script := `for commit in "$@"; do
+  git merge-base --is-ancestor "$commit" "origin/$BRANCH"
+  echo "$commit $?"
+done`
+	args := []string{"sh", "-c", script, "sh"}
+	for _, tag := range tags {
+		args = append(args, tag.Commit)
+	}
+
+	output, err := git.Container().
+		WithEnvVariable("BRANCH", branch).
+		WithExec(args).
+		Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error checking tag reachability: %w", err)
+	}
+
+	reachable := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		commit, code, ok := strings.Cut(line, " ")
+		if ok && code == "0" {
+			reachable[commit] = true
+		}
+	}
+
+	var unreachable []string
+	for _, tag := range tags {
+		if !reachable[tag.Commit] {
+			unreachable = append(unreachable, tag.Name)
+		}
+	}
+	if len(unreachable) > 0 {
+		fmt.Printf("⚠️ %d tags are not reachable from %s: %s\n", len(unreachable), branch, strings.Join(unreachable, ", "))
+	}
+	return unreachable, nil
+}
+
+// repository returns the source, or a clone of the remote without one, with
+// the tags and full history of the remote fetched
+func (m *Versioner) repository(source *dagger.Directory) (*dagger.GitRepo, error) {
+	git := dag.GitRepo(dagger.GitRepoOpts{Source: source, Token: m.GitHubToken})
+	if source == nil {
+		if m.Remote == "" {
+			return nil, fmt.Errorf("a source or a remote is required")
+		}
+		return git.Clone(m.Remote).Fetch(), nil
+	}
+
+	if m.Remote != "" {
+		ctr := git.Container().WithExec([]string{
+			"sh", "-c",
+			`git remote set-url origin "$0" 2>/dev/null || git remote add origin "$0"`,
+			m.Remote,
+		})
+		git = git.WithSource(ctr.Directory("/src"))
+	}
+	return git.Fetch(), nil
+}
+
+// isPrerelease reports whether a tag is a pre-release version, which has a
+// suffix after its patch number, e.g. v1.2.3-rc.1 or mymodule/v1.2.3-beta
+func isPrerelease(tag string) bool {
+	version := tag[strings.LastIndex(tag, "/")+1:]
+	return strings.Contains(version, "-")
+}
@@ -0,0 +1,362 @@
+// A module to deploy Docker Compose stacks to a remote host over SSH
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/libraries/compose-deploy/internal/dagger"
+)
+
+const (
+	// keyPath is where the private key is mounted in SSH containers
+	keyPath = "/root/.ssh/id_deploy"
+	// stagePath is where files are staged before upload
+	stagePath = "/deploy"
+	// downloadPath is where downloaded files are written
+	downloadPath = "/tmp/download"
+	// secretsPath is where secret files are mounted for upload
+	secretsPath = "/run/secrets/deploy"
+	// sopsImage provides the sops binary used to decrypt files on upload
+	sopsImage = "ghcr.io/getsops/sops:v3.9.4-alpine"
+)
+
+// ComposeDeploy deploys a Docker Compose stack to a host over SSH
+type ComposeDeploy struct {
+	Host string
+	User string
+	Port int
+	// Directory on the host holding the stack
+	Dir string
+
+	// +private
+	PrivateKey *dagger.Secret
+	// +private
+	Files *dagger.Directory
+	// +private
+	SecretFiles []SecretFile
+	// +private
+	SecretVariables []SecretVariable
+}
+
+// SecretFile is a file uploaded from a secret, or from a sops-encrypted file
+// decrypted with an age key
+type SecretFile struct {
+	// Path relative to Dir
+	Path   string
+	Secret *dagger.Secret
+	// sops-encrypted file, used instead of Secret
+	Encrypted *dagger.File
+	AgeKey    *dagger.Secret
+}
+
+// SecretVariable is a variable appended to the .env file on upload
+type SecretVariable struct {
+	Name   string
+	Secret *dagger.Secret
+}
+
+func New(
+	// Hostname or IP address of the target
+	host string,
+	// Private key authorized for the user on the target
+	privateKey *dagger.Secret,
+	// +optional
+	// +default="root"
+	user string,
+	// +optional
+	// +default=22
+	port int,
+	// Directory on the host holding the stack
+	// +optional
+	// +default="/opt/app"
+	dir string,
+) *ComposeDeploy {
+	if user == "" {
+		user = "root"
+	}
+	if port <= 0 {
+		port = 22
+	}
+	if dir == "" {
+		dir = "/opt/app"
+	}
+
+	return &ComposeDeploy{
+		Host:       host,
+		User:       user,
+		Port:       port,
+		Dir:        dir,
+		PrivateKey: privateKey,
+		Files:      dag.Directory(),
+	}
+}
+
+// WithDirectory adds the contents of a directory (compose file, .env, extra
+// configuration) to the files uploaded to Dir
+func (c *ComposeDeploy) WithDirectory(source *dagger.Directory) *ComposeDeploy {
+	c.Files = c.Files.WithDirectory(".", source)
+	return c
+}
+
+// WithFile adds a file to the files uploaded, at a path relative to Dir
+func (c *ComposeDeploy) WithFile(path string, file *dagger.File) *ComposeDeploy {
+	c.Files = c.Files.WithFile(path, file)
+	return c
+}
+
+// WithSecretFile adds a secret to the files uploaded, at a path relative to
+// Dir, readable by its owner only. Its contents are streamed to the host from
+// a mounted secret, so they never land in a file or layer.
+func (c *ComposeDeploy) WithSecretFile(path string, secret *dagger.Secret) *ComposeDeploy {
+	c.SecretFiles = append(c.SecretFiles, SecretFile{Path: path, Secret: secret})
+	return c
+}
+
+// WithSopsFile adds a sops-encrypted file to the files uploaded, at a path
+// relative to Dir, readable by its owner only. It is decrypted with the age
+// key while it is streamed to the host, so the plaintext never lands in a file
+// or layer.
+func (c *ComposeDeploy) WithSopsFile(path string, file *dagger.File, ageKey *dagger.Secret) *ComposeDeploy {
+	c.SecretFiles = append(c.SecretFiles, SecretFile{Path: path, Encrypted: file, AgeKey: ageKey})
+	return c
+}
+
+// WithSecretVariable appends NAME=value to the .env file uploaded with the
+// files. The value is streamed to the host from a secret variable, so it
+// never lands in a file or layer.
+func (c *ComposeDeploy) WithSecretVariable(name string, secret *dagger.Secret) *ComposeDeploy {
+	c.SecretVariables = append(c.SecretVariables, SecretVariable{Name: name, Secret: secret})
+	return c
+}
+
+// Upload copies the files to Dir on the host, keeping their permissions and
+// leaving other files in place, then writes the secret files and appends the
+// secret variables to .env
+func (c *ComposeDeploy) Upload(ctx context.Context) error {
+	fmt.Printf("📤 Uploading files to %s:%s...\n", c.Host, c.Dir)
+	remote := fmt.Sprintf("mkdir -p %s && tar -C %s --no-overwrite-dir -xf -", shellQuote(c.Dir), shellQuote(c.Dir))
+	ctr := c.ssh().
+		WithDirectory(stagePath, c.Files).
+		WithExec([]string{"sh", "-c", fmt.Sprintf(
+			"tar -C %s -cf - . | %s",
+			stagePath, strings.Join(quoteAll(c.sshArgs(remote)), " "),
+		)})
+
+	for i, file := range c.SecretFiles {
+		// Secrets are piped from their mount, or from sops, straight into ssh;
+		// the extension of the path tells sops the format, e.g. .env or .yaml
+		source := fmt.Sprintf("%s/%d%s", secretsPath, i, path.Ext(file.Path))
+		read := "cat " + source
+		if file.Encrypted != nil {
+			ctr = ctr.
+				WithFile("/usr/local/bin/sops", dag.Container().From(sopsImage).File("/usr/local/bin/sops")).
+				WithMountedFile(source, file.Encrypted).
+				WithSecretVariable("SOPS_AGE_KEY", file.AgeKey)
+			read = "sops --decrypt " + source
+		} else {
+			ctr = ctr.WithMountedSecret(source, file.Secret)
+		}
+
+		target := fmt.Sprintf("cd %s && umask 077 && mkdir -p %s && cat > %s",
+			shellQuote(c.Dir), shellQuote(path.Dir(file.Path)), shellQuote(file.Path))
+		ctr = ctr.WithExec([]string{"sh", "-c", fmt.Sprintf(
+			"set -o pipefail; %s | %s",
+			read, strings.Join(quoteAll(c.sshArgs(target)), " "),
+		)})
+	}
+
+	if len(c.SecretVariables) > 0 {
+		var lines []string
+		for i, variable := range c.SecretVariables {
+			env := fmt.Sprintf("DEPLOY_SECRET_%d", i)
+			ctr = ctr.WithSecretVariable(env, variable.Secret)
+			lines = append(lines, fmt.Sprintf(`printf '%%s=%%s\n' %s "$%s"`, shellQuote(variable.Name), env))
+		}
+
+		target := fmt.Sprintf("cd %s && umask 077 && cat >> .env", shellQuote(c.Dir))
+		ctr = ctr.WithExec([]string{"sh", "-c", fmt.Sprintf(
+			"{ %s; } | %s",
+			strings.Join(lines, "; "), strings.Join(quoteAll(c.sshArgs(target)), " "),
+		)})
+	}
+
+	if _, err := ctr.Sync(ctx); err != nil {
+		return fmt.Errorf("failed to upload files to %s: %w", c.Host, err)
+	}
+
+	return nil
+}
+
+// Up uploads the files and starts the stack with docker compose up -d
+func (c *ComposeDeploy) Up(
+	ctx context.Context,
+	// Services to start (defaults to all)
+	// +optional
+	services []string,
+	// Pull images before starting
+	// +optional
+	// +default=true
+	pull bool,
+) error {
+	if err := c.Upload(ctx); err != nil {
+		return err
+	}
+
+	args := strings.Join(quoteAll(services), " ")
+	command := fmt.Sprintf("docker compose up -d --remove-orphans %s", args)
+	if pull {
+		command = fmt.Sprintf("docker compose pull %s && %s", args, command)
+	}
+
+	fmt.Printf("🐳 Starting services on %s...\n", c.Host)
+	if _, err := c.Exec(ctx, command); err != nil {
+		return fmt.Errorf("failed to start services on %s: %w", c.Host, err)
+	}
+
+	return nil
+}
+
+// Exec runs a shell command on the host from Dir and returns its output
+func (c *ComposeDeploy) Exec(ctx context.Context, command string) (string, error) {
+	return c.ssh().
+		WithExec(c.sshArgs(fmt.Sprintf("cd %s && %s", shellQuote(c.Dir), command))).
+		Stdout(ctx)
+}
+
+// Download returns a file from the host; relative paths are resolved from Dir
+func (c *ComposeDeploy) Download(filePath string) *dagger.File {
+	if !path.IsAbs(filePath) {
+		filePath = path.Join(c.Dir, filePath)
+	}
+
+	return c.ssh().
+		WithExec([]string{
+			"scp", "-i", keyPath, "-P", fmt.Sprint(c.Port),
+			fmt.Sprintf("%s:%s", c.target(), filePath),
+			downloadPath,
+		}).
+		File(downloadPath)
+}
+
+// WaitForHealthy runs a command on the host until it succeeds, returning the
+// recent compose logs in the error when the timeout expires
+func (c *ComposeDeploy) WaitForHealthy(
+	ctx context.Context,
+	// Command that succeeds once the stack is healthy, e.g. curl -fsS http://127.0.0.1:8080/health
+	command string,
+	// Timeout in seconds
+	// +optional
+	// +default=300
+	timeout int,
+	// Seconds between attempts
+	// +optional
+	// +default=10
+	interval int,
+) error {
+	if timeout <= 0 {
+		timeout = 300
+	}
+	if interval <= 0 {
+		interval = 10
+	}
+
+	fmt.Printf("⏳ Waiting for services on %s to become healthy (timeout: %ds)...\n", c.Host, timeout)
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := c.Exec(ctx, command); err == nil {
+			fmt.Println("✅ Services are healthy")
+			return nil
+		}
+
+		time.Sleep(time.Duration(interval) * time.Second)
+	}
+
+	logs, _ := c.Logs(ctx, "", 50)
+	return fmt.Errorf("timeout waiting for services on %s to become healthy:\n%s", c.Host, logs)
+}
+
+// Logs returns the compose logs of the stack
+func (c *ComposeDeploy) Logs(
+	ctx context.Context,
+	// Service to return logs for (defaults to all)
+	// +optional
+	service string,
+	// +optional
+	// +default=100
+	tail int,
+) (string, error) {
+	if tail <= 0 {
+		tail = 100
+	}
+
+	command := fmt.Sprintf("docker compose logs --no-color --tail %d", tail)
+	if service != "" {
+		command += " " + shellQuote(service)
+	}
+
+	return c.Exec(ctx, command)
+}
+
+// Down stops and removes the stack
+func (c *ComposeDeploy) Down(
+	ctx context.Context,
+	// Also remove named volumes
+	// +optional
+	volumes bool,
+) error {
+	command := "docker compose down --remove-orphans"
+	if volumes {
+		command += " --volumes"
+	}
+
+	fmt.Printf("🧹 Stopping services on %s...\n", c.Host)
+	if _, err := c.Exec(ctx, command); err != nil {
+		return fmt.Errorf("failed to stop services on %s: %w", c.Host, err)
+	}
+
+	return nil
+}
+
+// ssh returns an uncached container ready to connect to the host
+func (c *ComposeDeploy) ssh() *dagger.Container {
+	return dag.Container().
+		From("alpine:3").
+		WithExec([]string{"apk", "add", "--no-cache", "openssh-client"}).
+		WithMountedSecret(keyPath, c.PrivateKey, dagger.ContainerWithMountedSecretOpts{Mode: 0600}).
+		WithNewFile("/root/.ssh/config", "Host *\n\tStrictHostKeyChecking no\n\tUserKnownHostsFile /dev/null\n\tLogLevel ERROR\n").
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano))
+}
+
+// sshArgs returns the ssh command line running command on the host
+func (c *ComposeDeploy) sshArgs(command string) []string {
+	return []string{
+		"ssh", "-i", keyPath,
+		"-p", fmt.Sprint(c.Port),
+		"-o", "ConnectTimeout=10",
+		c.target(),
+		command,
+	}
+}
+
+// target returns the user@host SSH destination
+func (c *ComposeDeploy) target() string {
+	return fmt.Sprintf("%s@%s", c.User, c.Host)
+}
+
+// shellQuote quotes a value for a POSIX shell
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// quoteAll quotes every value for a POSIX shell
+func quoteAll(values []string) []string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, shellQuote(value))
+	}
+	return quoted
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// volumePricePerGB is the monthly price of block storage per GiB in USD
+const volumePricePerGB = 0.10
+
+// Account holds the account limits and status
+type Account struct {
+	Email           string `json:"email"`
+	Status          string `json:"status"`
+	DropletLimit    int    `json:"droplet_limit"`
+	FloatingIPLimit int    `json:"floating_ip_limit"`
+	VolumeLimit     int    `json:"volume_limit"`
+}
+
+// QuotaReport compares current resource usage with the account limits
+type QuotaReport struct {
+	DropletCount int
+	DropletLimit int
+	VolumeCount  int
+	VolumeLimit  int
+}
+
+// CostItem is the estimated monthly cost of a single resource
+type CostItem struct {
+	Kind    string
+	Name    string
+	Monthly float64
+}
+
+// CostReport is the estimated monthly cost of resources with a tag
+type CostReport struct {
+	Tag   string
+	Items []CostItem
+	Total float64
+}
+
+// volume mirrors the doctl JSON output for block storage volumes
+type volume struct {
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	SizeGigaBytes int      `json:"size_gigabytes"`
+	CreatedAt     string   `json:"created_at"`
+	DropletIDs    []int    `json:"droplet_ids"`
+	Tags          []string `json:"tags"`
+}
+
+// size mirrors the doctl JSON output for droplet sizes
+type size struct {
+	Slug         string  `json:"slug"`
+	PriceMonthly float64 `json:"price_monthly"`
+}
+
+// Account and Billing
+
+// GetAccount returns the account status and resource limits
+func (do *DigitalOcean) GetAccount(ctx context.Context) (*Account, error) {
+	fmt.Println("🔍 Getting account details...")
+	var account Account
+	if err := do.doctlJSON(ctx, do.doctl(), &account, "account", "get"); err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+
+	return &account, nil
+}
+
+// listVolumes lists all block storage volumes
+func (do *DigitalOcean) listVolumes(ctx context.Context) ([]volume, error) {
+	var volumes []volume
+	if err := do.doctlJSON(ctx, do.doctl(), &volumes, "compute", "volume", "list"); err != nil {
+		return nil, fmt.Errorf("failed to list volumes: %w", err)
+	}
+
+	return volumes, nil
+}
+
+// GetQuota returns droplet and volume counts against the account limits
+func (do *DigitalOcean) GetQuota(ctx context.Context) (*QuotaReport, error) {
+	account, err := do.GetAccount(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	droplets, err := do.ListDroplets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	volumes, err := do.listVolumes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &QuotaReport{
+		DropletCount: len(droplets),
+		DropletLimit: account.DropletLimit,
+		VolumeCount:  len(volumes),
+		VolumeLimit:  account.VolumeLimit,
+	}
+
+	fmt.Printf("📊 Droplets: %d/%d, Volumes: %d/%d\n",
+		report.DropletCount, report.DropletLimit, report.VolumeCount, report.VolumeLimit)
+
+	return report, nil
+}
+
+// CheckQuota fails when creating the given number of droplets and volumes would exceed the account limits
+func (do *DigitalOcean) CheckQuota(
+	ctx context.Context,
+	// Droplets about to be created
+	// +optional
+	// +default=1
+	droplets int,
+	// Volumes about to be created
+	// +optional
+	volumes int,
+) error {
+	report, err := do.GetQuota(ctx)
+	if err != nil {
+		return err
+	}
+
+	if report.DropletLimit > 0 && report.DropletCount+droplets > report.DropletLimit {
+		return fmt.Errorf("droplet limit exceeded: %d in use, %d requested, limit is %d",
+			report.DropletCount, droplets, report.DropletLimit)
+	}
+
+	if report.VolumeLimit > 0 && report.VolumeCount+volumes > report.VolumeLimit {
+		return fmt.Errorf("volume limit exceeded: %d in use, %d requested, limit is %d",
+			report.VolumeCount, volumes, report.VolumeLimit)
+	}
+
+	fmt.Println("✅ Quota check passed")
+	return nil
+}
+
+// EstimateCost returns the estimated monthly cost of droplets and volumes with the given tag
+func (do *DigitalOcean) EstimateCost(ctx context.Context, tag string) (*CostReport, error) {
+	fmt.Printf("💰 Estimating monthly cost for tag: %s\n", tag)
+
+	var sizes []size
+	if err := do.doctlJSON(ctx, do.doctl(), &sizes, "compute", "size", "list"); err != nil {
+		return nil, fmt.Errorf("failed to list sizes: %w", err)
+	}
+	prices := make(map[string]float64, len(sizes))
+	for _, s := range sizes {
+		prices[s.Slug] = s.PriceMonthly
+	}
+
+	droplets, err := do.ListDroplets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	volumes, err := do.listVolumes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CostReport{Tag: tag}
+	for _, d := range droplets {
+		if !slices.Contains(d.Tags, tag) {
+			continue
+		}
+		report.Items = append(report.Items, CostItem{
+			Kind:    "droplet",
+			Name:    d.Name,
+			Monthly: prices[d.Size],
+		})
+	}
+	for _, v := range volumes {
+		if !slices.Contains(v.Tags, tag) {
+			continue
+		}
+		report.Items = append(report.Items, CostItem{
+			Kind:    "volume",
+			Name:    v.Name,
+			Monthly: float64(v.SizeGigaBytes) * volumePricePerGB,
+		})
+	}
+
+	for _, item := range report.Items {
+		report.Total += item.Monthly
+	}
+
+	fmt.Printf("💰 Estimated monthly cost for %s: $%.2f (%d resources)\n", tag, report.Total, len(report.Items))
+	return report, nil
+}
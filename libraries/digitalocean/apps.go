@@ -0,0 +1,453 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/libraries/digitalocean/internal/dagger"
+)
+
+// appSpecPath is where app specs are mounted for doctl
+const appSpecPath = "/run/secrets/app-spec.json"
+
+// Deploy and manage App Platform applications.
+func (do *DigitalOcean) AppPlatform() *AppPlatform {
+	return &AppPlatform{DigitalOcean: do}
+}
+
+// AppPlatform manages App Platform applications
+type AppPlatform struct {
+	// +private
+	DigitalOcean *DigitalOcean
+}
+
+// AppSpec describes an App Platform application
+type AppSpec struct {
+	Name     string
+	Region   string
+	Services []AppService
+	Domains  []string
+	Envs     []AppEnv
+}
+
+// AppService is a service component of an application
+type AppService struct {
+	Name             string
+	Image            AppImage
+	InstanceSizeSlug string
+	InstanceCount    int
+	HTTPPort         int
+	HealthCheckPath  string
+	Envs             []AppEnv
+}
+
+// AppImage is a container image deployed by a service
+type AppImage struct {
+	// DOCR, DOCKER_HUB or GHCR
+	RegistryType string
+	Registry     string
+	Repository   string
+	Tag          string
+}
+
+// AppEnv is an environment variable of an application or service.
+// Exactly one of Value or Secret must be set; secrets are encrypted by App Platform.
+type AppEnv struct {
+	Key    string
+	Value  string
+	Secret *dagger.Secret
+	// RUN_TIME, BUILD_TIME or RUN_AND_BUILD_TIME
+	Scope string
+}
+
+// App is an App Platform application
+type App struct {
+	ID      string
+	Name    string
+	LiveURL string
+}
+
+// AppDeployment is a deployment of an application
+type AppDeployment struct {
+	ID        string
+	Phase     string
+	Cause     string
+	CreatedAt string
+	Error     string
+}
+
+// AppStatus summarizes the state of an application
+type AppStatus struct {
+	ID      string
+	Name    string
+	LiveURL string
+	// Phase of the latest deployment (e.g. ACTIVE, DEPLOYING, ERROR)
+	Phase        string
+	DeploymentID string
+	// Error of the latest deployment, if it failed
+	Error string
+}
+
+// app mirrors the doctl JSON output for apps
+type app struct {
+	ID      string `json:"id"`
+	LiveURL string `json:"live_url"`
+	Spec    struct {
+		Name string `json:"name"`
+	} `json:"spec"`
+	ActiveDeployment     *deployment `json:"active_deployment"`
+	InProgressDeployment *deployment `json:"in_progress_deployment"`
+}
+
+// deployment mirrors the doctl JSON output for app deployments
+type deployment struct {
+	ID        string `json:"id"`
+	Phase     string `json:"phase"`
+	Cause     string `json:"cause"`
+	CreatedAt string `json:"created_at"`
+	Progress  struct {
+		Steps []struct {
+			Name   string `json:"name"`
+			Status string `json:"status"`
+			Reason struct {
+				Message string `json:"message"`
+			} `json:"reason"`
+		} `json:"steps"`
+	} `json:"progress"`
+}
+
+// toDeployment converts the doctl representation into an AppDeployment
+func (d deployment) toDeployment() *AppDeployment {
+	result := &AppDeployment{
+		ID:        d.ID,
+		Phase:     d.Phase,
+		Cause:     d.Cause,
+		CreatedAt: d.CreatedAt,
+	}
+	for _, step := range d.Progress.Steps {
+		if step.Status == "ERROR" {
+			result.Error = fmt.Sprintf("%s: %s", step.Name, step.Reason.Message)
+			break
+		}
+	}
+	return result
+}
+
+// Validate checks that the spec has everything App Platform requires
+func (s *AppSpec) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("app name is required")
+	}
+	if len(s.Services) == 0 {
+		return fmt.Errorf("app %s needs at least one service", s.Name)
+	}
+	for _, svc := range s.Services {
+		if svc.Name == "" {
+			return fmt.Errorf("app %s has a service without a name", s.Name)
+		}
+		if svc.Image.Repository == "" {
+			return fmt.Errorf("service %s needs an image repository", svc.Name)
+		}
+		switch svc.Image.RegistryType {
+		case "DOCR", "GHCR", "DOCKER_HUB":
+		default:
+			return fmt.Errorf("service %s has unsupported registry type %q", svc.Name, svc.Image.RegistryType)
+		}
+		if err := validateEnvs(svc.Envs); err != nil {
+			return fmt.Errorf("service %s: %w", svc.Name, err)
+		}
+	}
+	return validateEnvs(s.Envs)
+}
+
+// validateEnvs checks that every variable has exactly one value source
+func validateEnvs(envs []AppEnv) error {
+	for _, env := range envs {
+		if env.Key == "" {
+			return fmt.Errorf("environment variable without a key")
+		}
+		if (env.Value != "") == (env.Secret != nil) {
+			return fmt.Errorf("environment variable %s needs exactly one of value or secret", env.Key)
+		}
+	}
+	return nil
+}
+
+// render marshals the spec into the App Platform JSON format.
+// The result is returned as a secret because it embeds secret values.
+func (s *AppSpec) render(ctx context.Context) (*dagger.Secret, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	envs, err := renderEnvs(ctx, s.Envs)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := map[string]any{"name": s.Name}
+	if s.Region != "" {
+		spec["region"] = s.Region
+	}
+	if len(envs) > 0 {
+		spec["envs"] = envs
+	}
+	if len(s.Domains) > 0 {
+		domains := make([]map[string]any, 0, len(s.Domains))
+		for i, domain := range s.Domains {
+			kind := "ALIAS"
+			if i == 0 {
+				kind = "PRIMARY"
+			}
+			domains = append(domains, map[string]any{"domain": domain, "type": kind})
+		}
+		spec["domains"] = domains
+	}
+
+	services := make([]map[string]any, 0, len(s.Services))
+	for _, svc := range s.Services {
+		image := map[string]any{
+			"registry_type": svc.Image.RegistryType,
+			"repository":    svc.Image.Repository,
+		}
+		if svc.Image.Registry != "" {
+			image["registry"] = svc.Image.Registry
+		}
+		if svc.Image.Tag != "" {
+			image["tag"] = svc.Image.Tag
+		}
+
+		service := map[string]any{
+			"name":               svc.Name,
+			"image":              image,
+			"instance_size_slug": defaultString(svc.InstanceSizeSlug, "basic-xxs"),
+			"instance_count":     max(svc.InstanceCount, 1),
+		}
+		if svc.HTTPPort > 0 {
+			service["http_port"] = svc.HTTPPort
+		}
+		if svc.HealthCheckPath != "" {
+			service["health_check"] = map[string]any{"http_path": svc.HealthCheckPath}
+		}
+
+		serviceEnvs, err := renderEnvs(ctx, svc.Envs)
+		if err != nil {
+			return nil, err
+		}
+		if len(serviceEnvs) > 0 {
+			service["envs"] = serviceEnvs
+		}
+
+		services = append(services, service)
+	}
+	spec["services"] = services
+
+	out, err := json.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal app spec: %w", err)
+	}
+
+	return dag.SetSecret(fmt.Sprintf("app-spec-%s-%d", s.Name, time.Now().UnixNano()), string(out)), nil
+}
+
+// renderEnvs converts environment variables to the App Platform format
+func renderEnvs(ctx context.Context, envs []AppEnv) ([]map[string]any, error) {
+	result := make([]map[string]any, 0, len(envs))
+	for _, env := range envs {
+		item := map[string]any{
+			"key":   env.Key,
+			"scope": defaultString(env.Scope, "RUN_TIME"),
+			"type":  "GENERAL",
+			"value": env.Value,
+		}
+		if env.Secret != nil {
+			value, err := env.Secret.Plaintext(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read secret for %s: %w", env.Key, err)
+			}
+			item["type"] = "SECRET"
+			item["value"] = value
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// defaultString returns value, or fallback when value is empty
+func defaultString(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// CreateApp creates an application from a typed spec
+func (a *AppPlatform) CreateApp(ctx context.Context, spec AppSpec) (*App, error) {
+	rendered, err := spec.render(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("🚀 Creating app: %s\n", spec.Name)
+	return a.applySpec(ctx, rendered, "create")
+}
+
+// CreateAppFromFile creates an application from an App Platform spec file (YAML or JSON)
+func (a *AppPlatform) CreateAppFromFile(ctx context.Context, spec *dagger.File) (*App, error) {
+	contents, err := spec.Contents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read app spec: %w", err)
+	}
+
+	fmt.Println("🚀 Creating app from spec file")
+	return a.applySpec(ctx, dag.SetSecret(fmt.Sprintf("app-spec-%d", time.Now().UnixNano()), contents), "create")
+}
+
+// UpdateApp replaces the spec of an existing application, triggering a new deployment
+func (a *AppPlatform) UpdateApp(ctx context.Context, appID string, spec AppSpec) (*App, error) {
+	rendered, err := spec.render(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("🔄 Updating app: %s\n", appID)
+	return a.applySpec(ctx, rendered, "update", appID)
+}
+
+// applySpec runs "doctl apps create|update" with a mounted spec
+func (a *AppPlatform) applySpec(ctx context.Context, spec *dagger.Secret, args ...string) (*App, error) {
+	container := a.DigitalOcean.doctl().WithMountedSecret(appSpecPath, spec)
+
+	cmd := append([]string{"apps"}, args...)
+	cmd = append(cmd, "--spec", appSpecPath)
+
+	var apps []app
+	if err := a.DigitalOcean.doctlJSON(ctx, container, &apps, cmd...); err != nil {
+		return nil, fmt.Errorf("failed to %s app: %w", args[0], err)
+	}
+	if len(apps) == 0 {
+		return nil, fmt.Errorf("no app returned")
+	}
+
+	return &App{
+		ID:      apps[0].ID,
+		Name:    apps[0].Spec.Name,
+		LiveURL: apps[0].LiveURL,
+	}, nil
+}
+
+// FindApp returns the ID of the application with the given name, or an empty string
+func (a *AppPlatform) FindApp(ctx context.Context, name string) (string, error) {
+	var apps []app
+	if err := a.DigitalOcean.doctlJSON(ctx, a.DigitalOcean.doctl(), &apps, "apps", "list"); err != nil {
+		return "", fmt.Errorf("failed to list apps: %w", err)
+	}
+
+	for _, item := range apps {
+		if item.Spec.Name == name {
+			return item.ID, nil
+		}
+	}
+
+	return "", nil
+}
+
+// GetAppStatus returns the live URL and latest deployment state of an application
+func (a *AppPlatform) GetAppStatus(ctx context.Context, appID string) (*AppStatus, error) {
+	var apps []app
+	if err := a.DigitalOcean.doctlJSON(ctx, a.DigitalOcean.doctl(), &apps, "apps", "get", appID); err != nil {
+		return nil, fmt.Errorf("failed to get app %s: %w", appID, err)
+	}
+	if len(apps) == 0 {
+		return nil, fmt.Errorf("app %s not found", appID)
+	}
+
+	status := &AppStatus{
+		ID:      apps[0].ID,
+		Name:    apps[0].Spec.Name,
+		LiveURL: apps[0].LiveURL,
+	}
+
+	deployments, err := a.ListDeployments(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	if len(deployments) > 0 {
+		latest := deployments[0]
+		status.Phase = latest.Phase
+		status.DeploymentID = latest.ID
+		status.Error = latest.Error
+	}
+
+	return status, nil
+}
+
+// ListDeployments lists the deployments of an application, newest first
+func (a *AppPlatform) ListDeployments(ctx context.Context, appID string) ([]AppDeployment, error) {
+	var deployments []deployment
+	if err := a.DigitalOcean.doctlJSON(ctx, a.DigitalOcean.doctl(), &deployments, "apps", "list-deployments", appID); err != nil {
+		return nil, fmt.Errorf("failed to list deployments for %s: %w", appID, err)
+	}
+
+	result := make([]AppDeployment, 0, len(deployments))
+	for _, d := range deployments {
+		result = append(result, *d.toDeployment())
+	}
+
+	return result, nil
+}
+
+// WaitForDeployment waits for the latest deployment of an application to become
+// active, failing if it errors, is canceled or the timeout expires
+func (a *AppPlatform) WaitForDeployment(
+	ctx context.Context,
+	appID string,
+	// Timeout in seconds
+	// +optional
+	// +default=900
+	timeout int,
+) (*AppStatus, error) {
+	if timeout <= 0 {
+		timeout = 900
+	}
+
+	fmt.Printf("⏳ Waiting for deployment of app %s (timeout: %ds)\n", appID, timeout)
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+	for time.Now().Before(deadline) {
+		status, err := a.GetAppStatus(ctx, appID)
+		if err != nil {
+			return nil, err
+		}
+
+		switch status.Phase {
+		case "ACTIVE":
+			fmt.Printf("✅ App %s is live at %s\n", status.Name, status.LiveURL)
+			return status, nil
+		case "ERROR", "CANCELED":
+			return status, fmt.Errorf("deployment %s of app %s ended in phase %s: %s",
+				status.DeploymentID, status.Name, status.Phase, status.Error)
+		}
+
+		fmt.Printf("  Phase: %s\n", status.Phase)
+		time.Sleep(15 * time.Second)
+	}
+
+	return nil, fmt.Errorf("timeout waiting for deployment of app %s", appID)
+}
+
+// DeleteApp deletes an application
+func (a *AppPlatform) DeleteApp(ctx context.Context, appID string) ([]PlannedAction, error) {
+	plan := a.DigitalOcean.plan("delete", "app", appID)
+	if a.DigitalOcean.DryRun {
+		return plan, nil
+	}
+
+	fmt.Printf("🗑️ Deleting app: %s\n", appID)
+	_, err := a.DigitalOcean.doctlExec(a.DigitalOcean.doctl(), "apps", "delete", appID, "--force").Sync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
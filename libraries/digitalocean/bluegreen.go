@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/libraries/digitalocean/internal/dagger"
+)
+
+// blueGreenTagPrefix prefixes the tag marking the droplets of a blue-green deployment
+const blueGreenTagPrefix = "blue-green-"
+
+// BlueGreenConfig holds configuration for a blue-green droplet deployment
+type BlueGreenConfig struct {
+	// Droplet to create; its name is the deployment name, and each
+	// deployment creates <name>-<timestamp>
+	Droplet DropletConfig
+
+	// Private SSH key authorized on the droplets, used for readiness checks
+	PrivateKey *dagger.Secret
+	// SSH user for readiness checks (default: root)
+	User string
+	// Wait for cloud-init to finish before the health check
+	WaitForCloudInit bool
+	// Shell command run on the new droplet until it exits 0, e.g.
+	// curl -fsS http://127.0.0.1:8080/health
+	HealthCheck string
+	// Seconds to wait for the new droplet to become ready (default: 600)
+	Timeout int
+
+	// Reserved IP moved to the new droplet
+	ReservedIP string
+	// A record pointed at the new droplet; ignored when Domain is empty
+	DNS DNSConfig
+
+	// Keep the previous droplets instead of destroying them
+	KeepPrevious bool
+}
+
+// BlueGreenResult describes the outcome of a blue-green deployment
+type BlueGreenResult struct {
+	Active *Droplet
+	// Names of the droplets that served before the switch
+	Previous []string
+	// Whether the previous droplets were destroyed
+	Destroyed bool
+}
+
+// BlueGreenDeploy creates a new droplet from the config, waits until it is
+// ready, moves the reserved IP and/or DNS record to it and destroys the
+// droplets it replaces. When the new droplet does not become ready it is
+// destroyed and the current droplets keep serving.
+func (do *DigitalOcean) BlueGreenDeploy(ctx context.Context, config BlueGreenConfig) (*BlueGreenResult, error) {
+	name := config.Droplet.Name
+	if name == "" {
+		return nil, fmt.Errorf("missing droplet name")
+	}
+	if config.ReservedIP == "" && config.DNS.Domain == "" {
+		return nil, fmt.Errorf("a reserved IP or DNS record is required to switch traffic")
+	}
+	if (config.HealthCheck != "" || config.WaitForCloudInit) && config.PrivateKey == nil {
+		return nil, fmt.Errorf("a private key is required for readiness checks")
+	}
+	if config.User == "" {
+		config.User = "root"
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 600
+	}
+
+	previous, err := do.blueGreenDroplets(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	tag := blueGreenTagPrefix + name
+	droplet := config.Droplet
+	droplet.Name = fmt.Sprintf("%s-%s", name, time.Now().UTC().Format("20060102150405"))
+	droplet.Tags = append(append([]string{}, droplet.Tags...), tag)
+
+	fmt.Printf("🔵 Deploying %s alongside %d current droplet(s)\n", droplet.Name, len(previous))
+	created, err := do.CreateDroplet(ctx, droplet)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := do.waitForReady(ctx, created, config); err != nil {
+		fmt.Printf("❌ %s is not ready, destroying it: %v\n", created.Name, err)
+		if _, deleteErr := do.DeleteDroplet(ctx, created.ID); deleteErr != nil {
+			return nil, fmt.Errorf("%w (and failed to destroy %s: %v)", err, created.Name, deleteErr)
+		}
+		return nil, err
+	}
+
+	fmt.Printf("🟢 Switching traffic to %s (%s)\n", created.Name, created.IP)
+	if config.ReservedIP != "" {
+		_, err := do.doctlExec(do.doctl(),
+			"compute", "reserved-ip-action", "assign", config.ReservedIP, created.ID,
+		).Sync(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to assign reserved IP %s to %s: %w", config.ReservedIP, created.Name, err)
+		}
+	}
+	if config.DNS.Domain != "" {
+		record := config.DNS
+		record.Type = "A"
+		record.Value = created.IP
+		if _, err := do.EnsureDNSRecord(ctx, record); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &BlueGreenResult{Active: created}
+	for _, old := range previous {
+		result.Previous = append(result.Previous, old.Name)
+	}
+	if config.KeepPrevious || len(previous) == 0 {
+		return result, nil
+	}
+
+	for _, old := range previous {
+		if _, err := do.DeleteDroplet(ctx, old.ID); err != nil {
+			return result, fmt.Errorf("traffic switched to %s but failed to destroy %s: %w", created.Name, old.Name, err)
+		}
+	}
+	result.Destroyed = true
+
+	fmt.Printf("✅ %s is live\n", created.Name)
+	return result, nil
+}
+
+// blueGreenDroplets returns the droplets of a deployment: those tagged by
+// earlier blue-green deployments and a droplet with the deployment name
+// itself, so existing single-droplet deployments can be migrated
+func (do *DigitalOcean) blueGreenDroplets(ctx context.Context, name string) ([]Droplet, error) {
+	droplets, err := do.ListDroplets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tag := blueGreenTagPrefix + name
+	var matches []Droplet
+	for _, droplet := range droplets {
+		if droplet.Name == name || slices.Contains(droplet.Tags, tag) {
+			matches = append(matches, droplet)
+		}
+	}
+	return matches, nil
+}
+
+// waitForReady waits for cloud-init and the health check on a new droplet
+func (do *DigitalOcean) waitForReady(ctx context.Context, droplet *Droplet, config BlueGreenConfig) error {
+	if droplet.IP == "" {
+		return fmt.Errorf("droplet %s has no public IP", droplet.Name)
+	}
+
+	deadline := time.Now().Add(time.Duration(config.Timeout) * time.Second)
+	if config.WaitForCloudInit {
+		if err := do.WaitForCloudInit(ctx, droplet.IP, config.PrivateKey, config.User, config.Timeout); err != nil {
+			return err
+		}
+	}
+	if config.HealthCheck == "" {
+		return nil
+	}
+
+	fmt.Printf("🩺 Waiting for %s to pass its health check\n", droplet.Name)
+	var stderr string
+	for time.Now().Before(deadline) {
+		check := sshExec(droplet.IP, config.User, config.PrivateKey, config.HealthCheck)
+		exitCode, err := check.ExitCode(ctx)
+		if err == nil && exitCode == 0 {
+			fmt.Printf("✅ %s is healthy\n", droplet.Name)
+			return nil
+		}
+		if err == nil {
+			stderr, _ = check.Stderr(ctx)
+		}
+
+		time.Sleep(10 * time.Second)
+	}
+
+	return fmt.Errorf("timeout waiting for %s to pass its health check: %s", droplet.Name, strings.TrimSpace(stderr))
+}
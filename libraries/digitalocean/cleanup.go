@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CleanupItem is a resource selected for deletion
+type CleanupItem struct {
+	Kind   string
+	ID     string
+	Name   string
+	Reason string
+}
+
+// CleanupReport lists the resources deleted, or that would be deleted in dry-run mode
+type CleanupReport struct {
+	DryRun  bool
+	Items   []CleanupItem
+	Deleted int
+	Errors  []string
+}
+
+// snapshot mirrors the doctl JSON output for snapshots
+type snapshot struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	CreatedAt string   `json:"created_at"`
+	Tags      []string `json:"tags"`
+}
+
+// Garbage Collection
+
+// Cleanup deletes droplets, volumes, snapshots, SSH keys and DNS records left
+// behind by pipelines. Droplets, volumes and snapshots are matched by a tag
+// starting with tagPrefix; SSH keys by a name starting with tagPrefix and ending
+// with a unix timestamp (e.g. "n8n-deploy-1700000000"); DNS records by pointing
+// to a droplet being deleted. Nothing is deleted in dry-run mode, set here or
+// on the module.
+func (do *DigitalOcean) Cleanup(
+	ctx context.Context,
+	// Only delete resources older than this duration (e.g. "24h")
+	// +optional
+	// +default="24h"
+	olderThan string,
+	// Tag or name prefix identifying pipeline resources
+	tagPrefix string,
+	// Domain whose records pointing to deleted droplets are removed
+	// +optional
+	domain string,
+	// Report what would be deleted without deleting anything
+	// +optional
+	dryRun bool,
+) (*CleanupReport, error) {
+	if tagPrefix == "" {
+		return nil, fmt.Errorf("tag prefix is required")
+	}
+	if olderThan == "" {
+		olderThan = "24h"
+	}
+	age, err := time.ParseDuration(olderThan)
+	if err != nil {
+		return nil, fmt.Errorf("invalid duration %q: %w", olderThan, err)
+	}
+	cutoff := time.Now().Add(-age)
+	dryRun = dryRun || do.DryRun
+
+	fmt.Printf("🧹 Looking for resources tagged %s* older than %s\n", tagPrefix, olderThan)
+	report := &CleanupReport{DryRun: dryRun}
+
+	droplets, err := do.ListDroplets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deletedIPs := map[string]bool{}
+	for _, d := range droplets {
+		if hasTagPrefix(d.Tags, tagPrefix) && createdBefore(d.CreatedAt, cutoff) {
+			report.Items = append(report.Items, CleanupItem{Kind: "droplet", ID: d.ID, Name: d.Name, Reason: "tagged and expired"})
+			if d.IP != "" {
+				deletedIPs[d.IP] = true
+			}
+		}
+	}
+
+	if domain != "" {
+		records, err := do.ListDNSRecords(ctx, domain)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range records {
+			if (r.Type == "A" || r.Type == "AAAA") && deletedIPs[r.Data] {
+				report.Items = append(report.Items, CleanupItem{Kind: "dns", ID: strconv.Itoa(r.ID), Name: r.Name + "." + domain, Reason: "points to a deleted droplet"})
+			}
+		}
+	}
+
+	volumes, err := do.listVolumes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range volumes {
+		if hasTagPrefix(v.Tags, tagPrefix) && createdBefore(v.CreatedAt, cutoff) {
+			report.Items = append(report.Items, CleanupItem{Kind: "volume", ID: v.ID, Name: v.Name, Reason: "tagged and expired"})
+		}
+	}
+
+	var snapshots []snapshot
+	if err := do.doctlJSON(ctx, do.doctl(), &snapshots, "compute", "snapshot", "list"); err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	for _, s := range snapshots {
+		if hasTagPrefix(s.Tags, tagPrefix) && createdBefore(s.CreatedAt, cutoff) {
+			report.Items = append(report.Items, CleanupItem{Kind: "snapshot", ID: s.ID, Name: s.Name, Reason: "tagged and expired"})
+		}
+	}
+
+	keys, err := do.ListSSHKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		if !strings.HasPrefix(k.Name, tagPrefix) {
+			continue
+		}
+		idx := strings.LastIndex(k.Name, "-")
+		if idx < 0 {
+			continue
+		}
+		ts, err := strconv.ParseInt(k.Name[idx+1:], 10, 64)
+		if err != nil || !time.Unix(ts, 0).Before(cutoff) {
+			continue
+		}
+		report.Items = append(report.Items, CleanupItem{Kind: "ssh-key", ID: strconv.Itoa(k.ID), Name: k.Name, Reason: "name prefix and expired"})
+	}
+
+	for _, item := range report.Items {
+		if dryRun {
+			fmt.Printf("📝 Would delete %s %s (%s)\n", item.Kind, item.Name, item.Reason)
+			continue
+		}
+
+		if err := do.deleteCleanupItem(ctx, item, domain); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s %s: %v", item.Kind, item.Name, err))
+			continue
+		}
+		report.Deleted++
+	}
+
+	fmt.Printf("🧹 Cleanup finished: %d selected, %d deleted, %d errors\n", len(report.Items), report.Deleted, len(report.Errors))
+	return report, nil
+}
+
+// deleteCleanupItem deletes a single resource selected by Cleanup
+func (do *DigitalOcean) deleteCleanupItem(ctx context.Context, item CleanupItem, domain string) error {
+	switch item.Kind {
+	case "droplet":
+		_, err := do.DeleteDroplet(ctx, item.ID)
+		return err
+	case "dns":
+		_, err := do.DeleteDNSRecord(ctx, domain, item.ID)
+		return err
+	case "ssh-key":
+		_, err := do.DeleteSSHKey(ctx, item.ID)
+		return err
+	case "volume":
+		_, err := do.doctlExec(do.doctl(), "compute", "volume", "delete", item.ID, "--force").Sync(ctx)
+		return err
+	case "snapshot":
+		_, err := do.doctlExec(do.doctl(), "compute", "snapshot", "delete", item.ID, "--force").Sync(ctx)
+		return err
+	default:
+		return fmt.Errorf("unknown resource kind %s", item.Kind)
+	}
+}
+
+// hasTagPrefix reports whether any tag starts with prefix
+func hasTagPrefix(tags []string, prefix string) bool {
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// createdBefore reports whether an RFC 3339 creation time is before cutoff
+func createdBefore(createdAt string, cutoff time.Time) bool {
+	created, err := time.Parse(time.RFC3339, createdAt)
+	if err != nil {
+		return false
+	}
+	return created.Before(cutoff)
+}
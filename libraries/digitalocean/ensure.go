@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Idempotent Operations
+
+// EnsureSSHKey returns the SSH key with the same public key, renaming it if
+// needed, and registers the key only when it does not exist yet
+func (do *DigitalOcean) EnsureSSHKey(ctx context.Context, config SSHKeyConfig) (*SSHKey, error) {
+	keys, err := do.ListSSHKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := normalizePublicKey(config.PublicKey)
+	for i := range keys {
+		key := &keys[i]
+		if normalizePublicKey(key.PublicKey) != wanted {
+			if key.Name == config.Name {
+				return nil, fmt.Errorf("SSH key %s already exists with a different public key", config.Name)
+			}
+			continue
+		}
+
+		if key.Name == config.Name {
+			fmt.Printf("✅ SSH key %s already exists (ID: %d)\n", key.Name, key.ID)
+			return key, nil
+		}
+
+		fmt.Printf("✏️ Renaming SSH key %s to %s\n", key.Name, config.Name)
+		var updated []SSHKey
+		err := do.doctlJSON(ctx, do.doctl(), &updated,
+			"compute",
+			"ssh-key",
+			"update",
+			strconv.Itoa(key.ID),
+			"--key-name", config.Name,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to rename SSH key %s: %w", key.Name, err)
+		}
+		if len(updated) == 0 {
+			return nil, fmt.Errorf("no SSH key returned for %s", config.Name)
+		}
+		return &updated[0], nil
+	}
+
+	return do.CreateSSHKey(ctx, config)
+}
+
+// EnsureDroplet returns the droplet with the configured name, creating it only
+// when it does not exist. Droplets are never recreated; configuration drift
+// is reported but left untouched.
+func (do *DigitalOcean) EnsureDroplet(ctx context.Context, config DropletConfig) (*Droplet, error) {
+	existing, err := do.findDroplet(ctx, config.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return do.CreateDroplet(ctx, config)
+	}
+
+	fmt.Printf("✅ Droplet %s already exists (ID: %s)\n", existing.Name, existing.ID)
+	if config.Region != "" && existing.Region != config.Region {
+		fmt.Printf("⚠️ Droplet %s is in region %s, expected %s\n", existing.Name, existing.Region, config.Region)
+	}
+	if config.Size != "" && existing.Size != config.Size {
+		fmt.Printf("⚠️ Droplet %s has size %s, expected %s\n", existing.Name, existing.Size, config.Size)
+	}
+
+	return existing, nil
+}
+
+// EnsureDNSRecord returns the record with the configured type and name,
+// creating it when missing and updating its value or TTL when they differ,
+// with the create or update it made
+func (do *DigitalOcean) EnsureDNSRecord(ctx context.Context, config DNSConfig) (*DNSChange, error) {
+	records, err := do.ListDNSRecords(ctx, config.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range records {
+		record := &records[i]
+		if !strings.EqualFold(record.Type, config.Type) || record.Name != config.Name {
+			continue
+		}
+
+		sameValue := strings.TrimSuffix(record.Data, ".") == strings.TrimSuffix(config.Value, ".")
+		sameTTL := config.TTL <= 0 || record.TTL == config.TTL
+		if sameValue && sameTTL {
+			fmt.Printf("✅ DNS record %s.%s already points to %s\n", config.Name, config.Domain, config.Value)
+			return &DNSChange{Record: *record}, nil
+		}
+
+		plan := do.plan("update", "DNS record", fmt.Sprintf("%s.%s: %s -> %s", config.Name, config.Domain, record.Data, config.Value))
+		if do.DryRun {
+			planned := *record
+			planned.Data = config.Value
+			if config.TTL > 0 {
+				planned.TTL = config.TTL
+			}
+			return &DNSChange{Record: planned, Actions: plan}, nil
+		}
+
+		fmt.Printf("✏️ Updating DNS record %s.%s: %s -> %s\n", config.Name, config.Domain, record.Data, config.Value)
+		args := []string{
+			"compute",
+			"domain",
+			"records",
+			"update",
+			config.Domain,
+			"--record-id", strconv.Itoa(record.ID),
+			"--record-data", config.Value,
+		}
+		if config.TTL > 0 {
+			args = append(args, "--record-ttl", fmt.Sprintf("%d", config.TTL))
+		}
+
+		var updated []DNSRecord
+		if err := do.doctlJSON(ctx, do.doctl(), &updated, args...); err != nil {
+			return nil, fmt.Errorf("failed to update DNS record %s.%s: %w", config.Name, config.Domain, err)
+		}
+		if len(updated) == 0 {
+			return nil, fmt.Errorf("no DNS record returned for %s.%s", config.Name, config.Domain)
+		}
+		return &DNSChange{Record: updated[0], Actions: plan}, nil
+	}
+
+	return do.CreateDNSRecord(ctx, config)
+}
+
+// normalizePublicKey strips the comment from an authorized_keys entry so keys
+// can be compared by type and key material only
+func normalizePublicKey(key string) string {
+	fields := strings.Fields(key)
+	if len(fields) < 2 {
+		return strings.TrimSpace(key)
+	}
+	return fields[0] + " " + fields[1]
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/libraries/digitalocean/internal/dagger"
+)
+
+// Manage DigitalOcean Kubernetes (DOKS) clusters.
+func (do *DigitalOcean) Kubernetes() *Kubernetes {
+	return &Kubernetes{DigitalOcean: do}
+}
+
+// Kubernetes manages DOKS clusters
+type Kubernetes struct {
+	// +private
+	DigitalOcean *DigitalOcean
+}
+
+// Cluster is a DOKS cluster
+type Cluster struct {
+	ID        string
+	Name      string
+	Region    string
+	Version   string
+	State     string
+	Endpoint  string
+	NodePools []NodePool
+}
+
+// NodePool is a node pool of a DOKS cluster
+type NodePool struct {
+	ID    string
+	Name  string
+	Size  string
+	Count int
+}
+
+// cluster mirrors the doctl JSON output for clusters
+type cluster struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Region   string `json:"region"`
+	Version  string `json:"version"`
+	Endpoint string `json:"endpoint"`
+	Status   struct {
+		State string `json:"state"`
+	} `json:"status"`
+	NodePools []struct {
+		ID    string `json:"id"`
+		Name  string `json:"name"`
+		Size  string `json:"size"`
+		Count int    `json:"count"`
+	} `json:"node_pools"`
+}
+
+// toCluster converts the doctl representation into a Cluster
+func (c cluster) toCluster() *Cluster {
+	result := &Cluster{
+		ID:       c.ID,
+		Name:     c.Name,
+		Region:   c.Region,
+		Version:  c.Version,
+		State:    c.Status.State,
+		Endpoint: c.Endpoint,
+	}
+	for _, pool := range c.NodePools {
+		result.NodePools = append(result.NodePools, NodePool{
+			ID:    pool.ID,
+			Name:  pool.Name,
+			Size:  pool.Size,
+			Count: pool.Count,
+		})
+	}
+	return result
+}
+
+// CreateCluster creates a Kubernetes cluster with a single node pool
+func (k *Kubernetes) CreateCluster(
+	ctx context.Context,
+	name string,
+	// +optional
+	// +default="nyc1"
+	region string,
+	// Kubernetes version slug (e.g. "1.29"), defaults to the latest
+	// +optional
+	// +default="latest"
+	version string,
+	// +optional
+	// +default="s-2vcpu-4gb"
+	nodeSize string,
+	// +optional
+	// +default=3
+	nodeCount int,
+	// +optional
+	tags []string,
+) (*Cluster, error) {
+	if region == "" {
+		region = "nyc1"
+	}
+	if version == "" {
+		version = "latest"
+	}
+	if nodeSize == "" {
+		nodeSize = "s-2vcpu-4gb"
+	}
+	if nodeCount <= 0 {
+		nodeCount = 3
+	}
+
+	fmt.Printf("☸️ Creating Kubernetes cluster: %s (%s, %d x %s)\n", name, region, nodeCount, nodeSize)
+	args := []string{
+		"kubernetes", "cluster", "create", name,
+		"--region", region,
+		"--version", version,
+		"--node-pool", fmt.Sprintf("name=%s-default;size=%s;count=%d", name, nodeSize, nodeCount),
+		"--update-kubeconfig=false",
+		"--wait",
+	}
+	if len(tags) > 0 {
+		args = append(args, "--tag", strings.Join(tags, ","))
+	}
+
+	var clusters []cluster
+	if err := k.DigitalOcean.doctlJSON(ctx, k.DigitalOcean.doctl(), &clusters, args...); err != nil {
+		return nil, fmt.Errorf("failed to create cluster %s: %w", name, err)
+	}
+	if len(clusters) == 0 {
+		return nil, fmt.Errorf("no cluster returned for %s", name)
+	}
+
+	return clusters[0].toCluster(), nil
+}
+
+// GetCluster returns a Kubernetes cluster by name or ID
+func (k *Kubernetes) GetCluster(ctx context.Context, name string) (*Cluster, error) {
+	var clusters []cluster
+	if err := k.DigitalOcean.doctlJSON(ctx, k.DigitalOcean.doctl(), &clusters, "kubernetes", "cluster", "get", name); err != nil {
+		return nil, fmt.Errorf("failed to get cluster %s: %w", name, err)
+	}
+	if len(clusters) == 0 {
+		return nil, fmt.Errorf("cluster %s not found", name)
+	}
+
+	return clusters[0].toCluster(), nil
+}
+
+// ListClusters lists all Kubernetes clusters
+func (k *Kubernetes) ListClusters(ctx context.Context) ([]Cluster, error) {
+	var clusters []cluster
+	if err := k.DigitalOcean.doctlJSON(ctx, k.DigitalOcean.doctl(), &clusters, "kubernetes", "cluster", "list"); err != nil {
+		return nil, fmt.Errorf("failed to list clusters: %w", err)
+	}
+
+	result := make([]Cluster, 0, len(clusters))
+	for _, c := range clusters {
+		result = append(result, *c.toCluster())
+	}
+
+	return result, nil
+}
+
+// GetKubeconfig returns the kubeconfig of a cluster as a secret
+func (k *Kubernetes) GetKubeconfig(ctx context.Context, name string) (*dagger.Secret, error) {
+	fmt.Printf("🔑 Getting kubeconfig for cluster: %s\n", name)
+	kubeconfig, err := k.DigitalOcean.doctlExec(k.DigitalOcean.doctl(),
+		"kubernetes", "cluster", "kubeconfig", "show", name,
+	).Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubeconfig for %s: %w", name, err)
+	}
+
+	return dag.SetSecret(fmt.Sprintf("kubeconfig-%s", name), kubeconfig), nil
+}
+
+// ScaleNodePool sets the number of nodes of a node pool
+func (k *Kubernetes) ScaleNodePool(ctx context.Context, cluster string, pool string, count int) error {
+	fmt.Printf("📏 Scaling node pool %s of cluster %s to %d nodes\n", pool, cluster, count)
+	_, err := k.DigitalOcean.doctlExec(k.DigitalOcean.doctl(),
+		"kubernetes", "cluster", "node-pool", "update", cluster, pool,
+		"--count", fmt.Sprintf("%d", count),
+	).Sync(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to scale node pool %s: %w", pool, err)
+	}
+
+	return nil
+}
+
+// DeleteCluster deletes a Kubernetes cluster and its associated load balancers and volumes
+func (k *Kubernetes) DeleteCluster(ctx context.Context, name string) ([]PlannedAction, error) {
+	plan := k.DigitalOcean.plan("delete", "Kubernetes cluster", name)
+	if k.DigitalOcean.DryRun {
+		return plan, nil
+	}
+
+	fmt.Printf("🗑️ Deleting Kubernetes cluster: %s\n", name)
+	_, err := k.DigitalOcean.doctlExec(k.DigitalOcean.doctl(),
+		"kubernetes", "cluster", "delete", name,
+		"--dangerous",
+		"--force",
+	).Sync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// WaitForClusterReady waits for a cluster to reach the running state
+func (k *Kubernetes) WaitForClusterReady(
+	ctx context.Context,
+	name string,
+	// Timeout in seconds
+	// +optional
+	// +default=900
+	timeout int,
+) (*Cluster, error) {
+	if timeout <= 0 {
+		timeout = 900
+	}
+
+	fmt.Printf("⏳ Waiting for cluster %s to be running (timeout: %ds)\n", name, timeout)
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+	for time.Now().Before(deadline) {
+		c, err := k.GetCluster(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+
+		switch c.State {
+		case "running":
+			fmt.Printf("✅ Cluster %s is running\n", name)
+			return c, nil
+		case "errored", "invalid":
+			return nil, fmt.Errorf("cluster %s is in state %s", name, c.State)
+		}
+
+		time.Sleep(15 * time.Second)
+	}
+
+	return nil, fmt.Errorf("timeout waiting for cluster %s to be running", name)
+}
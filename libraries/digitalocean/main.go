@@ -3,15 +3,24 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/felipepimentel/daggerverse/libraries/digitalocean/internal/dagger"
 )
 
+// doctlImage is the doctl image used for all API operations
+const doctlImage = "digitalocean/doctl:1.101.0"
+
 // DigitalOcean provides functionality for managing DigitalOcean resources
 type DigitalOcean struct {
-	token *dagger.Secret
+	// +private
+	Token *dagger.Secret
+	// Log deletes and DNS changes instead of making them
+	// +private
+	DryRun bool
 }
 
 // SSHKeyConfig holds configuration for SSH key operations
@@ -35,6 +44,13 @@ type DropletConfig struct {
 	Monitoring bool
 	IPv6       bool
 	Tags       []string
+	VPCID      string
+
+	// Cloud-init user data template; ${KEY} placeholders are replaced
+	// with UserDataVars and UserDataSecrets before the droplet is created
+	UserData        *dagger.File
+	UserDataVars    []KeyValue
+	UserDataSecrets []SecretKeyValue
 }
 
 // DNSConfig holds configuration for managing DNS records
@@ -54,44 +70,78 @@ type DNSConfig struct {
 // New creates a new instance of the DigitalOcean module
 func New(token *dagger.Secret) *DigitalOcean {
 	return &DigitalOcean{
-		token: token,
+		Token: token,
 	}
 }
 
+// WithDryRun makes deletes, DNS changes and uploads log and return the
+// actions they would take instead of taking them
+func (do *DigitalOcean) WithDryRun() *DigitalOcean {
+	do.DryRun = true
+	return do
+}
+
+// doctl returns a container running doctl authenticated with the module token
+func (do *DigitalOcean) doctl() *dagger.Container {
+	return dag.Container().
+		From(doctlImage).
+		WithSecretVariable("DIGITALOCEAN_ACCESS_TOKEN", do.Token)
+}
+
+// doctlExec runs a doctl command, bypassing the cache so results reflect the current account state
+func (do *DigitalOcean) doctlExec(container *dagger.Container, args ...string) *dagger.Container {
+	return container.
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)).
+		WithExec(args, dagger.ContainerWithExecOpts{UseEntrypoint: true})
+}
+
+// doctlJSON runs a doctl command with JSON output and decodes the result into out
+func (do *DigitalOcean) doctlJSON(ctx context.Context, container *dagger.Container, out any, args ...string) error {
+	args = append(args, "--output", "json")
+	output, err := do.doctlExec(container, args...).Stdout(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal([]byte(output), out); err != nil {
+		return fmt.Errorf("failed to parse doctl output: %w", err)
+	}
+
+	return nil
+}
+
 // SSH Key Management
 
 // CreateSSHKey creates a new SSH key
-func (do *DigitalOcean) CreateSSHKey(ctx context.Context, config SSHKeyConfig) (*dagger.Container, error) {
+func (do *DigitalOcean) CreateSSHKey(ctx context.Context, config SSHKeyConfig) (*SSHKey, error) {
 	fmt.Printf("🔑 Creating SSH key: %s\n", config.Name)
-	return dag.Container().
-		From("digitalocean/doctl:1.101.0").
-		WithSecretVariable("DIGITALOCEAN_ACCESS_TOKEN", do.token).
-		WithExec([]string{
-			"compute",
-			"ssh-key",
-			"create",
-			config.Name,
-			"--public-key", config.PublicKey,
-			"--format", "ID",
-			"--no-header",
-		}), nil
+	var keys []SSHKey
+	err := do.doctlJSON(ctx, do.doctl(), &keys,
+		"compute",
+		"ssh-key",
+		"create",
+		config.Name,
+		"--public-key", config.PublicKey,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SSH key %s: %w", config.Name, err)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no SSH key returned for %s", config.Name)
+	}
+
+	return &keys[0], nil
 }
 
 // ListSSHKeys lists all SSH keys
-func (do *DigitalOcean) ListSSHKeys(ctx context.Context, format string) (*dagger.Container, error) {
+func (do *DigitalOcean) ListSSHKeys(ctx context.Context) ([]SSHKey, error) {
 	fmt.Println("🔍 Listing SSH keys...")
-	args := []string{"compute", "ssh-key", "list"}
-	if format != "" {
-		args = append(args, "--format", format)
-	}
-	if format == "ID" {
-		args = append(args, "--no-header")
+	var keys []SSHKey
+	if err := do.doctlJSON(ctx, do.doctl(), &keys, "compute", "ssh-key", "list"); err != nil {
+		return nil, fmt.Errorf("failed to list SSH keys: %w", err)
 	}
 
-	return dag.Container().
-		From("digitalocean/doctl:1.101.0").
-		WithSecretVariable("DIGITALOCEAN_ACCESS_TOKEN", do.token).
-		WithExec(args), nil
+	return keys, nil
 }
 
 // Registry Management
@@ -101,7 +151,7 @@ func (do *DigitalOcean) CreateRegistry(ctx context.Context, config RegistryConfi
 	fmt.Printf("🔧 Creating registry: %s\n", config.Name)
 	return dag.Container().
 		From("digitalocean/doctl:1.101.0").
-		WithSecretVariable("DIGITALOCEAN_ACCESS_TOKEN", do.token).
+		WithSecretVariable("DIGITALOCEAN_ACCESS_TOKEN", do.Token).
 		WithExec([]string{
 			"registry",
 			"create",
@@ -114,7 +164,7 @@ func (do *DigitalOcean) GetRegistry(ctx context.Context) (*dagger.Container, err
 	fmt.Println("🔍 Getting registry details...")
 	return dag.Container().
 		From("digitalocean/doctl:1.101.0").
-		WithSecretVariable("DIGITALOCEAN_ACCESS_TOKEN", do.token).
+		WithSecretVariable("DIGITALOCEAN_ACCESS_TOKEN", do.Token).
 		WithExec([]string{
 			"registry",
 			"get",
@@ -126,7 +176,7 @@ func (do *DigitalOcean) ListRegistryTags(ctx context.Context, registry string) (
 	fmt.Printf("🔍 Listing tags for registry: %s\n", registry)
 	return dag.Container().
 		From("digitalocean/doctl:1.101.0").
-		WithSecretVariable("DIGITALOCEAN_ACCESS_TOKEN", do.token).
+		WithSecretVariable("DIGITALOCEAN_ACCESS_TOKEN", do.Token).
 		WithExec([]string{
 			"registry",
 			"repository",
@@ -136,25 +186,29 @@ func (do *DigitalOcean) ListRegistryTags(ctx context.Context, registry string) (
 }
 
 // DeleteRegistry deletes a container registry
-func (do *DigitalOcean) DeleteRegistry(ctx context.Context, name string) error {
+func (do *DigitalOcean) DeleteRegistry(ctx context.Context, name string) ([]PlannedAction, error) {
+	plan := do.plan("delete", "registry", name)
+	if do.DryRun {
+		return plan, nil
+	}
+
 	fmt.Printf("🗑️ Deleting registry: %s\n", name)
-	_, err := dag.Container().
-		From("digitalocean/doctl:1.101.0").
-		WithSecretVariable("DIGITALOCEAN_ACCESS_TOKEN", do.token).
-		WithExec([]string{
-			"registry",
-			"delete",
-			name,
-			"--force",
-		}).
-		Stdout(ctx)
-	return err
+	_, err := do.doctlExec(do.doctl(),
+		"registry",
+		"delete",
+		name,
+		"--force",
+	).Sync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
 }
 
 // Droplet Management
 
 // CreateDroplet creates a new droplet with the given configuration
-func (do *DigitalOcean) CreateDroplet(ctx context.Context, config DropletConfig) (*dagger.Container, error) {
+func (do *DigitalOcean) CreateDroplet(ctx context.Context, config DropletConfig) (*Droplet, error) {
 	if config.Name == "" || config.Region == "" || config.Size == "" || config.Image == "" {
 		return nil, fmt.Errorf("missing required droplet configuration")
 	}
@@ -175,8 +229,6 @@ func (do *DigitalOcean) CreateDroplet(ctx context.Context, config DropletConfig)
 		"--image", config.Image,
 		"--ssh-keys", config.SSHKeyID,
 		"--wait",
-		"--format", "ID,Name,PublicIPv4",
-		"--no-header",
 	}
 
 	if config.Monitoring {
@@ -188,62 +240,98 @@ func (do *DigitalOcean) CreateDroplet(ctx context.Context, config DropletConfig)
 	}
 
 	if len(config.Tags) > 0 {
-		args = append(args, "--tag-names", fmt.Sprintf("[%s]", config.Tags[0]))
-		for _, tag := range config.Tags[1:] {
-			args[len(args)-1] = fmt.Sprintf("%s,%s", args[len(args)-1], tag)
+		args = append(args, "--tag-names", strings.Join(config.Tags, ","))
+	}
+
+	if config.VPCID != "" {
+		args = append(args, "--vpc-uuid", config.VPCID)
+	}
+
+	container := do.doctl()
+
+	if config.UserData != nil {
+		userData, err := do.RenderUserData(ctx, config.UserData, config.UserDataVars, config.UserDataSecrets)
+		if err != nil {
+			return nil, err
 		}
+		container = container.WithMountedSecret(userDataPath, userData)
+		args = append(args, "--user-data-file", userDataPath)
 	}
 
-	return dag.Container().
-		From("digitalocean/doctl:1.101.0").
-		WithSecretVariable("DIGITALOCEAN_ACCESS_TOKEN", do.token).
-		WithExec(args), nil
+	var droplets []droplet
+	if err := do.doctlJSON(ctx, container, &droplets, args...); err != nil {
+		return nil, fmt.Errorf("failed to create droplet %s: %w", config.Name, err)
+	}
+	if len(droplets) == 0 {
+		return nil, fmt.Errorf("no droplet returned for %s", config.Name)
+	}
+
+	return droplets[0].toDroplet(), nil
 }
 
 // GetDroplet retrieves information about a droplet by name
-func (do *DigitalOcean) GetDroplet(ctx context.Context, name string, format string) (*dagger.Container, error) {
+func (do *DigitalOcean) GetDroplet(ctx context.Context, name string) (*Droplet, error) {
 	fmt.Printf("🔍 Getting droplet: %s\n", name)
-	args := []string{
-		"compute",
-		"droplet",
-		"get",
-		name,
+	found, err := do.findDroplet(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, fmt.Errorf("droplet %s not found", name)
+	}
+
+	return found, nil
+}
+
+// findDroplet returns the droplet with the given name, or nil if it does not exist
+func (do *DigitalOcean) findDroplet(ctx context.Context, name string) (*Droplet, error) {
+	droplets, err := do.ListDroplets(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	if format != "" {
-		args = append(args, "--format", format)
-		if format == "PublicIPv4" {
-			args = append(args, "--no-header")
+	for i := range droplets {
+		if droplets[i].Name == name {
+			return &droplets[i], nil
 		}
 	}
 
-	return dag.Container().
-		From("digitalocean/doctl:1.101.0").
-		WithSecretVariable("DIGITALOCEAN_ACCESS_TOKEN", do.token).
-		WithExec(args), nil
+	return nil, nil
 }
 
 // DeleteDroplet deletes a droplet by name
-func (do *DigitalOcean) DeleteDroplet(ctx context.Context, name string) error {
+func (do *DigitalOcean) DeleteDroplet(ctx context.Context, name string) ([]PlannedAction, error) {
+	plan := do.plan("delete", "droplet", name)
+	if do.DryRun {
+		return plan, nil
+	}
+
 	fmt.Printf("🗑️ Deleting droplet: %s\n", name)
-	_, err := dag.Container().
-		From("digitalocean/doctl:1.101.0").
-		WithSecretVariable("DIGITALOCEAN_ACCESS_TOKEN", do.token).
-		WithExec([]string{
-			"compute",
-			"droplet",
-			"delete",
-			name,
-			"--force",
-		}).
-		Stdout(ctx)
-	return err
+	_, err := do.doctlExec(do.doctl(),
+		"compute",
+		"droplet",
+		"delete",
+		name,
+		"--force",
+	).Sync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
 }
 
 // DNS Management
 
 // CreateDNSRecord creates a new DNS record
-func (do *DigitalOcean) CreateDNSRecord(ctx context.Context, config DNSConfig) error {
+func (do *DigitalOcean) CreateDNSRecord(ctx context.Context, config DNSConfig) (*DNSChange, error) {
+	plan := do.plan("create", "DNS record", fmt.Sprintf("%s.%s -> %s", config.Name, config.Domain, config.Value))
+	if do.DryRun {
+		return &DNSChange{
+			Record:  DNSRecord{Type: config.Type, Name: config.Name, Data: config.Value, TTL: config.TTL, Priority: config.Priority},
+			Actions: plan,
+		}, nil
+	}
+
 	fmt.Printf("🌐 Creating DNS record: %s.%s -> %s\n", config.Name, config.Domain, config.Value)
 	args := []string{
 		"compute",
@@ -264,47 +352,56 @@ func (do *DigitalOcean) CreateDNSRecord(ctx context.Context, config DNSConfig) e
 		args = append(args, "--record-priority", fmt.Sprintf("%d", config.Priority))
 	}
 
-	_, err := dag.Container().
-		From("digitalocean/doctl:1.101.0").
-		WithSecretVariable("DIGITALOCEAN_ACCESS_TOKEN", do.token).
-		WithExec(args).
-		Stdout(ctx)
-	return err
+	var records []DNSRecord
+	if err := do.doctlJSON(ctx, do.doctl(), &records, args...); err != nil {
+		return nil, fmt.Errorf("failed to create DNS record %s.%s: %w", config.Name, config.Domain, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no DNS record returned for %s.%s", config.Name, config.Domain)
+	}
+
+	return &DNSChange{Record: records[0], Actions: plan}, nil
 }
 
 // ListDNSRecords lists all DNS records for a domain
-func (do *DigitalOcean) ListDNSRecords(ctx context.Context, domain string) (*dagger.Container, error) {
+func (do *DigitalOcean) ListDNSRecords(ctx context.Context, domain string) ([]DNSRecord, error) {
 	fmt.Printf("🔍 Listing DNS records for domain: %s\n", domain)
-	return dag.Container().
-		From("digitalocean/doctl:1.101.0").
-		WithSecretVariable("DIGITALOCEAN_ACCESS_TOKEN", do.token).
-		WithExec([]string{
-			"compute",
-			"domain",
-			"records",
-			"list",
-			domain,
-			"--format", "ID,Type,Name,Data",
-		}), nil
+	var records []DNSRecord
+	err := do.doctlJSON(ctx, do.doctl(), &records,
+		"compute",
+		"domain",
+		"records",
+		"list",
+		domain,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list DNS records for %s: %w", domain, err)
+	}
+
+	return records, nil
 }
 
 // DeleteDNSRecord deletes a DNS record
-func (do *DigitalOcean) DeleteDNSRecord(ctx context.Context, domain string, recordID string) error {
+func (do *DigitalOcean) DeleteDNSRecord(ctx context.Context, domain string, recordID string) ([]PlannedAction, error) {
+	plan := do.plan("delete", "DNS record", recordID)
+	if do.DryRun {
+		return plan, nil
+	}
+
 	fmt.Printf("🗑️ Deleting DNS record: %s (ID: %s)\n", domain, recordID)
-	_, err := dag.Container().
-		From("digitalocean/doctl:1.101.0").
-		WithSecretVariable("DIGITALOCEAN_ACCESS_TOKEN", do.token).
-		WithExec([]string{
-			"compute",
-			"domain",
-			"records",
-			"delete",
-			domain,
-			recordID,
-			"--force",
-		}).
-		Stdout(ctx)
-	return err
+	_, err := do.doctlExec(do.doctl(),
+		"compute",
+		"domain",
+		"records",
+		"delete",
+		domain,
+		recordID,
+		"--force",
+	).Sync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
 }
 
 // Utility Functions
@@ -314,17 +411,12 @@ func (do *DigitalOcean) WaitForDroplet(ctx context.Context, name string, status
 	fmt.Printf("⏳ Waiting for droplet %s to reach status %s (timeout: %s)\n", name, status, timeout)
 	deadline := time.Now().Add(timeout)
 	for time.Now().Before(deadline) {
-		container, err := do.GetDroplet(ctx, name, "Status")
-		if err != nil {
-			return err
-		}
-
-		output, err := container.Stdout(ctx)
+		droplet, err := do.GetDroplet(ctx, name)
 		if err != nil {
 			return err
 		}
 
-		if output == status {
+		if droplet.Status == status {
 			fmt.Printf("✅ Droplet %s reached status %s\n", name, status)
 			return nil
 		}
@@ -336,51 +428,56 @@ func (do *DigitalOcean) WaitForDroplet(ctx context.Context, name string, status
 }
 
 // ListDroplets lists all droplets in the account
-func (do *DigitalOcean) ListDroplets(ctx context.Context) (*dagger.Container, error) {
+func (do *DigitalOcean) ListDroplets(ctx context.Context) ([]Droplet, error) {
 	fmt.Println("🔍 Listing all droplets...")
-	return dag.Container().
-		From("digitalocean/doctl:1.101.0").
-		WithSecretVariable("DIGITALOCEAN_ACCESS_TOKEN", do.token).
-		WithExec([]string{
-			"compute",
-			"droplet",
-			"list",
-			"--format", "ID,Name,PublicIPv4,Status",
-		}), nil
+	var droplets []droplet
+	if err := do.doctlJSON(ctx, do.doctl(), &droplets, "compute", "droplet", "list"); err != nil {
+		return nil, fmt.Errorf("failed to list droplets: %w", err)
+	}
+
+	result := make([]Droplet, 0, len(droplets))
+	for _, d := range droplets {
+		result = append(result, *d.toDroplet())
+	}
+
+	return result, nil
 }
 
 // DeleteSSHKey deletes an SSH key by ID
-func (do *DigitalOcean) DeleteSSHKey(ctx context.Context, keyID string) error {
+func (do *DigitalOcean) DeleteSSHKey(ctx context.Context, keyID string) ([]PlannedAction, error) {
+	plan := do.plan("delete", "SSH key", keyID)
+	if do.DryRun {
+		return plan, nil
+	}
+
 	fmt.Printf("🗑️ Deleting SSH key: %s\n", keyID)
-	_, err := dag.Container().
-		From("digitalocean/doctl:1.101.0").
-		WithSecretVariable("DIGITALOCEAN_ACCESS_TOKEN", do.token).
-		WithExec([]string{
-			"compute",
-			"ssh-key",
-			"delete",
-			keyID,
-			"--force",
-		}).
-		Stdout(ctx)
-	return err
+	_, err := do.doctlExec(do.doctl(),
+		"compute",
+		"ssh-key",
+		"delete",
+		keyID,
+		"--force",
+	).Sync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
 }
 
 // RegisterSSHKey registers an SSH key with DigitalOcean
-func (do *DigitalOcean) RegisterSSHKey(ctx context.Context, name string, publicKey string) error {
+func (do *DigitalOcean) RegisterSSHKey(ctx context.Context, name string, publicKey string) (*SSHKey, error) {
 	fmt.Printf("📝 Registering SSH key: %s\n", name)
-	_, err := dag.Container().
-		From("digitalocean/doctl:1.101.0").
-		WithSecretVariable("DIGITALOCEAN_ACCESS_TOKEN", do.token).
-		WithExec([]string{
-			"compute",
-			"ssh-key",
-			"create",
-			name,
-			"--public-key", publicKey,
-			"--format", "ID",
-			"--no-header",
-		}).
-		Stdout(ctx)
-	return err
+	return do.CreateSSHKey(ctx, SSHKeyConfig{
+		Name:      name,
+		PublicKey: publicKey,
+	})
+}
+
+// plan returns the action of a destructive operation, logging it in dry-run
+// mode, where the operation stops there
+func (do *DigitalOcean) plan(action, resource, id string) []PlannedAction {
+	if do.DryRun {
+		fmt.Printf("📝 Would %s %s %s\n", action, resource, id)
+	}
+	return []PlannedAction{{Action: action, Resource: resource, ID: id}}
 }
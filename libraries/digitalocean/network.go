@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FirewallRule is an inbound or outbound firewall rule. For inbound rules
+// the addresses, tags and droplets are sources; for outbound rules they are
+// destinations.
+type FirewallRule struct {
+	// tcp, udp or icmp
+	Protocol string
+	// Port, range ("8000-9000") or "all"; ignored for icmp
+	Ports      string
+	Addresses  []string
+	Tags       []string
+	DropletIDs []string
+}
+
+// FirewallConfig holds configuration for creating a firewall
+type FirewallConfig struct {
+	Name          string
+	InboundRules  []FirewallRule
+	OutboundRules []FirewallRule
+	DropletIDs    []string
+	Tags          []string
+}
+
+// Firewall is a cloud firewall as reported by the DigitalOcean API
+type Firewall struct {
+	ID         string
+	Name       string
+	Status     string
+	DropletIDs []string
+	Tags       []string
+}
+
+// VPCConfig holds configuration for creating a VPC
+type VPCConfig struct {
+	Name        string
+	Region      string
+	IPRange     string
+	Description string
+}
+
+// VPC is a virtual private cloud network
+type VPC struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Region  string `json:"region"`
+	IPRange string `json:"ip_range"`
+	Default bool   `json:"default"`
+}
+
+// firewall mirrors the doctl JSON output for firewalls
+type firewall struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Status     string   `json:"status"`
+	DropletIDs []int    `json:"droplet_ids"`
+	Tags       []string `json:"tags"`
+}
+
+// toFirewall converts the doctl representation into a Firewall
+func (f firewall) toFirewall() *Firewall {
+	result := &Firewall{
+		ID:     f.ID,
+		Name:   f.Name,
+		Status: f.Status,
+		Tags:   f.Tags,
+	}
+	for _, id := range f.DropletIDs {
+		result.DropletIDs = append(result.DropletIDs, strconv.Itoa(id))
+	}
+	return result
+}
+
+// format renders the rule in the doctl rule syntax
+func (r FirewallRule) format() (string, error) {
+	protocol := strings.ToLower(r.Protocol)
+	switch protocol {
+	case "tcp", "udp":
+		if r.Ports == "" {
+			return "", fmt.Errorf("ports are required for %s rules", protocol)
+		}
+	case "icmp":
+	default:
+		return "", fmt.Errorf("unsupported firewall protocol %q", r.Protocol)
+	}
+	if len(r.Addresses) == 0 && len(r.Tags) == 0 && len(r.DropletIDs) == 0 {
+		return "", fmt.Errorf("%s rule on ports %s has no addresses, tags or droplets", protocol, r.Ports)
+	}
+
+	parts := []string{"protocol:" + protocol}
+	if protocol != "icmp" {
+		parts = append(parts, "ports:"+r.Ports)
+	}
+	for _, address := range r.Addresses {
+		parts = append(parts, "address:"+address)
+	}
+	for _, tag := range r.Tags {
+		parts = append(parts, "tag:"+tag)
+	}
+	for _, id := range r.DropletIDs {
+		parts = append(parts, "droplet_id:"+id)
+	}
+
+	return strings.Join(parts, ","), nil
+}
+
+// formatRules renders a list of rules in the doctl rule syntax
+func formatRules(rules []FirewallRule) (string, error) {
+	formatted := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		f, err := rule.format()
+		if err != nil {
+			return "", err
+		}
+		formatted = append(formatted, f)
+	}
+	return strings.Join(formatted, " "), nil
+}
+
+// Firewall Management
+
+// CreateFirewall creates a cloud firewall
+func (do *DigitalOcean) CreateFirewall(ctx context.Context, config FirewallConfig) (*Firewall, error) {
+	if config.Name == "" {
+		return nil, fmt.Errorf("firewall name is required")
+	}
+	if len(config.InboundRules) == 0 && len(config.OutboundRules) == 0 {
+		return nil, fmt.Errorf("firewall %s has no rules", config.Name)
+	}
+
+	args := []string{"compute", "firewall", "create", "--name", config.Name}
+
+	if len(config.InboundRules) > 0 {
+		inbound, err := formatRules(config.InboundRules)
+		if err != nil {
+			return nil, fmt.Errorf("invalid inbound rule: %w", err)
+		}
+		args = append(args, "--inbound-rules", inbound)
+	}
+
+	if len(config.OutboundRules) > 0 {
+		outbound, err := formatRules(config.OutboundRules)
+		if err != nil {
+			return nil, fmt.Errorf("invalid outbound rule: %w", err)
+		}
+		args = append(args, "--outbound-rules", outbound)
+	}
+
+	if len(config.DropletIDs) > 0 {
+		args = append(args, "--droplet-ids", strings.Join(config.DropletIDs, ","))
+	}
+
+	if len(config.Tags) > 0 {
+		args = append(args, "--tag-names", strings.Join(config.Tags, ","))
+	}
+
+	fmt.Printf("🧱 Creating firewall: %s\n", config.Name)
+	var firewalls []firewall
+	if err := do.doctlJSON(ctx, do.doctl(), &firewalls, args...); err != nil {
+		return nil, fmt.Errorf("failed to create firewall %s: %w", config.Name, err)
+	}
+	if len(firewalls) == 0 {
+		return nil, fmt.Errorf("no firewall returned for %s", config.Name)
+	}
+
+	return firewalls[0].toFirewall(), nil
+}
+
+// AttachFirewall applies a firewall to droplets and tagged droplets
+func (do *DigitalOcean) AttachFirewall(
+	ctx context.Context,
+	firewallID string,
+	// +optional
+	dropletIDs []string,
+	// +optional
+	tags []string,
+) error {
+	if len(dropletIDs) == 0 && len(tags) == 0 {
+		return fmt.Errorf("droplet IDs or tags are required to attach firewall %s", firewallID)
+	}
+
+	fmt.Printf("🧱 Attaching firewall: %s\n", firewallID)
+	if len(dropletIDs) > 0 {
+		_, err := do.doctlExec(do.doctl(),
+			"compute", "firewall", "add-droplets", firewallID,
+			"--droplet-ids", strings.Join(dropletIDs, ","),
+		).Sync(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to attach firewall %s to droplets: %w", firewallID, err)
+		}
+	}
+
+	if len(tags) > 0 {
+		_, err := do.doctlExec(do.doctl(),
+			"compute", "firewall", "add-tags", firewallID,
+			"--tag-names", strings.Join(tags, ","),
+		).Sync(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to attach firewall %s to tags: %w", firewallID, err)
+		}
+	}
+
+	return nil
+}
+
+// ListFirewalls lists all firewalls
+func (do *DigitalOcean) ListFirewalls(ctx context.Context) ([]Firewall, error) {
+	var firewalls []firewall
+	if err := do.doctlJSON(ctx, do.doctl(), &firewalls, "compute", "firewall", "list"); err != nil {
+		return nil, fmt.Errorf("failed to list firewalls: %w", err)
+	}
+
+	result := make([]Firewall, 0, len(firewalls))
+	for _, f := range firewalls {
+		result = append(result, *f.toFirewall())
+	}
+
+	return result, nil
+}
+
+// DeleteFirewall deletes a firewall
+func (do *DigitalOcean) DeleteFirewall(ctx context.Context, firewallID string) ([]PlannedAction, error) {
+	plan := do.plan("delete", "firewall", firewallID)
+	if do.DryRun {
+		return plan, nil
+	}
+
+	fmt.Printf("🗑️ Deleting firewall: %s\n", firewallID)
+	_, err := do.doctlExec(do.doctl(), "compute", "firewall", "delete", firewallID, "--force").Sync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// VPC Management
+
+// CreateVPC creates a VPC network
+func (do *DigitalOcean) CreateVPC(ctx context.Context, config VPCConfig) (*VPC, error) {
+	if config.Name == "" || config.Region == "" {
+		return nil, fmt.Errorf("VPC name and region are required")
+	}
+
+	args := []string{"vpcs", "create", "--name", config.Name, "--region", config.Region}
+	if config.IPRange != "" {
+		args = append(args, "--ip-range", config.IPRange)
+	}
+	if config.Description != "" {
+		args = append(args, "--description", config.Description)
+	}
+
+	fmt.Printf("🕸️ Creating VPC: %s (%s)\n", config.Name, config.Region)
+	var vpcs []VPC
+	if err := do.doctlJSON(ctx, do.doctl(), &vpcs, args...); err != nil {
+		return nil, fmt.Errorf("failed to create VPC %s: %w", config.Name, err)
+	}
+	if len(vpcs) == 0 {
+		return nil, fmt.Errorf("no VPC returned for %s", config.Name)
+	}
+
+	return &vpcs[0], nil
+}
+
+// ListVPCs lists all VPC networks
+func (do *DigitalOcean) ListVPCs(ctx context.Context) ([]VPC, error) {
+	var vpcs []VPC
+	if err := do.doctlJSON(ctx, do.doctl(), &vpcs, "vpcs", "list"); err != nil {
+		return nil, fmt.Errorf("failed to list VPCs: %w", err)
+	}
+
+	return vpcs, nil
+}
+
+// DeleteVPC deletes a VPC network; it must not contain any resources
+func (do *DigitalOcean) DeleteVPC(ctx context.Context, vpcID string) ([]PlannedAction, error) {
+	plan := do.plan("delete", "VPC", vpcID)
+	if do.DryRun {
+		return plan, nil
+	}
+
+	fmt.Printf("🗑️ Deleting VPC: %s\n", vpcID)
+	_, err := do.doctlExec(do.doctl(), "vpcs", "delete", vpcID, "--force").Sync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
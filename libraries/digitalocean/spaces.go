@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/libraries/digitalocean/internal/dagger"
+)
+
+const (
+	// awsCliImage is used to talk to the S3-compatible Spaces API
+	awsCliImage = "amazon/aws-cli:2.15.0"
+	// spacesSourcePath is where uploaded files and directories are mounted
+	spacesSourcePath = "/spaces"
+)
+
+// Manage Spaces object storage, authenticated with Spaces access keys.
+func (do *DigitalOcean) Spaces(
+	accessKey *dagger.Secret,
+	secretKey *dagger.Secret,
+	// +optional
+	// +default="nyc3"
+	region string,
+) *Spaces {
+	if region == "" {
+		region = "nyc3"
+	}
+	return &Spaces{
+		DigitalOcean: do,
+		AccessKey:    accessKey,
+		SecretKey:    secretKey,
+		Region:       region,
+	}
+}
+
+// Spaces manages S3-compatible object storage buckets
+type Spaces struct {
+	// +private
+	DigitalOcean *DigitalOcean
+	// +private
+	AccessKey *dagger.Secret
+	// +private
+	SecretKey *dagger.Secret
+
+	Region string
+}
+
+// CDNEndpoint is a CDN endpoint in front of a Spaces bucket
+type CDNEndpoint struct {
+	ID            string `json:"id"`
+	Origin        string `json:"origin"`
+	Endpoint      string `json:"endpoint"`
+	CustomDomain  string `json:"custom_domain"`
+	CertificateID string `json:"certificate_id"`
+	TTL           int    `json:"ttl"`
+	CreatedAt     string `json:"created_at"`
+}
+
+// Endpoint returns the S3 endpoint of the Spaces region
+func (s *Spaces) Endpoint() string {
+	return fmt.Sprintf("https://%s.digitaloceanspaces.com", s.Region)
+}
+
+// BucketURL returns the public URL of a bucket
+func (s *Spaces) BucketURL(bucket string) string {
+	return fmt.Sprintf("https://%s.%s.digitaloceanspaces.com", bucket, s.Region)
+}
+
+// awsCli returns an aws-cli container authenticated with the Spaces keys
+func (s *Spaces) awsCli() *dagger.Container {
+	return dag.Container().
+		From(awsCliImage).
+		WithSecretVariable("AWS_ACCESS_KEY_ID", s.AccessKey).
+		WithSecretVariable("AWS_SECRET_ACCESS_KEY", s.SecretKey).
+		// Spaces ignores the signing region, but the CLI requires one
+		WithEnvVariable("AWS_DEFAULT_REGION", "us-east-1").
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano))
+}
+
+// s3 runs an aws-cli command against the Spaces endpoint
+func (s *Spaces) s3(container *dagger.Container, args ...string) *dagger.Container {
+	cmd := append([]string{"aws", "--endpoint-url", s.Endpoint()}, args...)
+	return container.WithExec(cmd)
+}
+
+// validateACL ensures the canned ACL is one supported by Spaces
+func validateACL(acl string) error {
+	switch acl {
+	case "private", "public-read":
+		return nil
+	default:
+		return fmt.Errorf("unsupported ACL %q: must be private or public-read", acl)
+	}
+}
+
+// CreateBucket creates a bucket, doing nothing if it already exists
+func (s *Spaces) CreateBucket(
+	ctx context.Context,
+	name string,
+	// +optional
+	// +default="private"
+	acl string,
+) error {
+	if acl == "" {
+		acl = "private"
+	}
+	if err := validateACL(acl); err != nil {
+		return err
+	}
+
+	exitCode, err := s.awsCli().
+		WithExec(
+			[]string{"aws", "--endpoint-url", s.Endpoint(), "s3api", "head-bucket", "--bucket", name},
+			dagger.ContainerWithExecOpts{Expect: dagger.ReturnTypeAny},
+		).
+		ExitCode(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check bucket %s: %w", name, err)
+	}
+	if exitCode == 0 {
+		fmt.Printf("✅ Bucket %s already exists\n", name)
+		return nil
+	}
+
+	fmt.Printf("🪣 Creating bucket: %s (%s)\n", name, s.Region)
+	_, err = s.s3(s.awsCli(), "s3api", "create-bucket", "--bucket", name, "--acl", acl).Sync(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create bucket %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// UploadDirectory syncs a directory to a bucket, returning the objects
+// uploaded and, with prune, deleted. In dry-run mode the sync is only
+// planned and the objects it would change are returned.
+func (s *Spaces) UploadDirectory(
+	ctx context.Context,
+	bucket string,
+	source *dagger.Directory,
+	// Key prefix to upload under
+	// +optional
+	prefix string,
+	// +optional
+	// +default="private"
+	acl string,
+	// Delete objects under the prefix that are not in the source
+	// +optional
+	prune bool,
+	// Cache-Control header for uploaded objects
+	// +optional
+	cacheControl string,
+) ([]PlannedAction, error) {
+	if acl == "" {
+		acl = "private"
+	}
+	if err := validateACL(acl); err != nil {
+		return nil, err
+	}
+
+	destination := fmt.Sprintf("s3://%s/%s", bucket, strings.Trim(prefix, "/"))
+	args := []string{"s3", "sync", spacesSourcePath, destination, "--acl", acl, "--no-progress"}
+	if prune {
+		args = append(args, "--delete")
+	}
+	if cacheControl != "" {
+		args = append(args, "--cache-control", cacheControl)
+	}
+	if s.DigitalOcean.DryRun {
+		args = append(args, "--dryrun")
+		fmt.Printf("📝 Would upload directory to %s\n", destination)
+	} else {
+		fmt.Printf("📤 Uploading directory to %s\n", destination)
+	}
+
+	output, err := s.s3(s.awsCli().WithMountedDirectory(spacesSourcePath, source), args...).Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload directory to %s: %w", destination, err)
+	}
+
+	return syncActions(output), nil
+}
+
+// syncActions parses the objects changed by aws s3 sync from its output, e.g.
+// "upload: ./index.html to s3://bucket/index.html" or, with --dryrun,
+// "(dryrun) delete: s3://bucket/old.html"
+func syncActions(output string) []PlannedAction {
+	var actions []PlannedAction
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimPrefix(strings.TrimSpace(line), "(dryrun) ")
+		action, target, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		switch action {
+		case "upload", "copy":
+			if _, to, ok := strings.Cut(target, " to "); ok {
+				target = to
+			}
+		case "delete":
+		default:
+			continue
+		}
+		actions = append(actions, PlannedAction{Action: action, Resource: "object", ID: target})
+	}
+	return actions
+}
+
+// UploadFile uploads a single file to a bucket
+func (s *Spaces) UploadFile(
+	ctx context.Context,
+	bucket string,
+	file *dagger.File,
+	key string,
+	// +optional
+	// +default="private"
+	acl string,
+	// +optional
+	contentType string,
+	// +optional
+	cacheControl string,
+) error {
+	if acl == "" {
+		acl = "private"
+	}
+	if err := validateACL(acl); err != nil {
+		return err
+	}
+
+	source := spacesSourcePath + "/" + strings.TrimPrefix(key, "/")
+	destination := fmt.Sprintf("s3://%s/%s", bucket, strings.TrimPrefix(key, "/"))
+	args := []string{"s3", "cp", source, destination, "--acl", acl, "--no-progress"}
+	if contentType != "" {
+		args = append(args, "--content-type", contentType)
+	}
+	if cacheControl != "" {
+		args = append(args, "--cache-control", cacheControl)
+	}
+
+	fmt.Printf("📤 Uploading file to %s\n", destination)
+	_, err := s.s3(s.awsCli().WithMountedFile(source, file), args...).Sync(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to upload file to %s: %w", destination, err)
+	}
+
+	return nil
+}
+
+// SetACL sets the canned ACL of a bucket, or of a single object when key is set
+func (s *Spaces) SetACL(
+	ctx context.Context,
+	bucket string,
+	acl string,
+	// +optional
+	key string,
+) error {
+	if err := validateACL(acl); err != nil {
+		return err
+	}
+
+	args := []string{"s3api", "put-bucket-acl", "--bucket", bucket, "--acl", acl}
+	target := bucket
+	if key != "" {
+		args = []string{"s3api", "put-object-acl", "--bucket", bucket, "--key", key, "--acl", acl}
+		target = bucket + "/" + key
+	}
+
+	fmt.Printf("🔒 Setting ACL %s on %s\n", acl, target)
+	if _, err := s.s3(s.awsCli(), args...).Sync(ctx); err != nil {
+		return fmt.Errorf("failed to set ACL on %s: %w", target, err)
+	}
+
+	return nil
+}
+
+// CreateCDN enables the CDN for a bucket
+func (s *Spaces) CreateCDN(
+	ctx context.Context,
+	bucket string,
+	// Cache TTL in seconds
+	// +optional
+	// +default=3600
+	ttl int,
+	// +optional
+	customDomain string,
+	// Certificate ID, required with a custom domain
+	// +optional
+	certificateID string,
+) (*CDNEndpoint, error) {
+	if ttl <= 0 {
+		ttl = 3600
+	}
+	if customDomain != "" && certificateID == "" {
+		return nil, fmt.Errorf("a certificate ID is required for custom domain %s", customDomain)
+	}
+
+	origin := fmt.Sprintf("%s.%s.digitaloceanspaces.com", bucket, s.Region)
+	args := []string{"compute", "cdn", "create", origin, "--ttl", fmt.Sprintf("%d", ttl)}
+	if customDomain != "" {
+		args = append(args, "--domain", customDomain, "--certificate-id", certificateID)
+	}
+
+	fmt.Printf("🌐 Creating CDN endpoint for %s\n", origin)
+	var endpoints []CDNEndpoint
+	if err := s.DigitalOcean.doctlJSON(ctx, s.DigitalOcean.doctl(), &endpoints, args...); err != nil {
+		return nil, fmt.Errorf("failed to create CDN endpoint for %s: %w", bucket, err)
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("no CDN endpoint returned for %s", bucket)
+	}
+
+	return &endpoints[0], nil
+}
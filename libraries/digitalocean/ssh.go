@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/libraries/digitalocean/internal/dagger"
+)
+
+// sshKeyPath is where the private key is mounted in SSH containers
+const sshKeyPath = "/root/.ssh/id_key"
+
+// CommandResult is the outcome of a command run on a droplet
+type CommandResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// sshContainer returns an uncached container ready to SSH into a droplet
+func sshContainer(ip, user string, privateKey *dagger.Secret) *dagger.Container {
+	return dag.Container().
+		From("alpine:3").
+		WithExec([]string{"apk", "add", "--no-cache", "openssh-client"}).
+		WithMountedSecret(sshKeyPath, privateKey).
+		WithNewFile("/root/.ssh/config", "Host *\n\tStrictHostKeyChecking no\n\tUserKnownHostsFile /dev/null\n\tLogLevel ERROR\n").
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano))
+}
+
+// sshExec runs a shell command on a droplet; the exec succeeds whatever the
+// exit code of the command, which is read with ExitCode
+func sshExec(ip, user string, privateKey *dagger.Secret, command string) *dagger.Container {
+	return sshContainer(ip, user, privateKey).
+		WithExec([]string{
+			"ssh", "-i", sshKeyPath,
+			"-o", "ConnectTimeout=10",
+			fmt.Sprintf("%s@%s", user, ip),
+			command,
+		}, dagger.ContainerWithExecOpts{Expect: dagger.ReturnTypeAny})
+}
+
+// RunCommand runs a shell command on a droplet over SSH and returns its
+// output and exit code. A non-zero exit code is not an error, so callers can
+// act on it; failing to connect is one (ssh exits with 255).
+func (do *DigitalOcean) RunCommand(
+	ctx context.Context,
+	// Droplet name
+	dropletName string,
+	// Shell command to run
+	command string,
+	// Private SSH key authorized on the droplet
+	privateKey *dagger.Secret,
+	// SSH user
+	// +optional
+	// +default="root"
+	user string,
+) (*CommandResult, error) {
+	if user == "" {
+		user = "root"
+	}
+
+	droplet, err := do.GetDroplet(ctx, dropletName)
+	if err != nil {
+		return nil, err
+	}
+	if droplet.IP == "" {
+		return nil, fmt.Errorf("droplet %s has no public IP", dropletName)
+	}
+
+	fmt.Printf("💻 Running command on %s (%s)\n", dropletName, droplet.IP)
+	ctr := sshExec(droplet.IP, user, privateKey, command)
+
+	exitCode, err := ctr.ExitCode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run command on %s: %w", dropletName, err)
+	}
+	stdout, err := ctr.Stdout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := ctr.Stderr(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if exitCode == 255 {
+		return nil, fmt.Errorf("failed to connect to %s: %s", dropletName, stderr)
+	}
+
+	return &CommandResult{Stdout: stdout, Stderr: stderr, ExitCode: exitCode}, nil
+}
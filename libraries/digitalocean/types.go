@@ -0,0 +1,106 @@
+package main
+
+import "strconv"
+
+// Droplet is a droplet as reported by the DigitalOcean API
+type Droplet struct {
+	ID        string
+	Name      string
+	IP        string
+	PrivateIP string
+	Status    string
+	Region    string
+	Size      string
+	Image     string
+	Tags      []string
+	CreatedAt string
+}
+
+// DNSRecord is a domain record as reported by the DigitalOcean API
+type DNSRecord struct {
+	ID       int    `json:"id"`
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Data     string `json:"data"`
+	TTL      int    `json:"ttl"`
+	Priority int    `json:"priority"`
+	Port     int    `json:"port"`
+	Weight   int    `json:"weight"`
+}
+
+// PlannedAction is a change a destructive operation makes, or only plans in
+// dry-run mode, e.g. {delete, droplet, web-1}
+type PlannedAction struct {
+	// delete, create, update or upload
+	Action   string
+	Resource string
+	// ID or name of the resource, e.g. a droplet name or an object URL
+	ID string
+}
+
+// DNSChange is the record a DNS operation wrote, or would write in dry-run
+// mode, with the actions it took; Actions is empty when the record was
+// already up to date
+type DNSChange struct {
+	Record  DNSRecord
+	Actions []PlannedAction
+}
+
+// SSHKey is an SSH key registered in the account
+type SSHKey struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Fingerprint string `json:"fingerprint"`
+	PublicKey   string `json:"public_key"`
+}
+
+// droplet mirrors the doctl JSON output for droplets
+type droplet struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	SizeSlug string `json:"size_slug"`
+	Created  string `json:"created_at"`
+	Region   struct {
+		Slug string `json:"slug"`
+	} `json:"region"`
+	Image struct {
+		Slug string `json:"slug"`
+		Name string `json:"name"`
+	} `json:"image"`
+	Networks struct {
+		V4 []struct {
+			IPAddress string `json:"ip_address"`
+			Type      string `json:"type"`
+		} `json:"v4"`
+	} `json:"networks"`
+	Tags []string `json:"tags"`
+}
+
+// toDroplet converts the doctl representation into a Droplet
+func (d droplet) toDroplet() *Droplet {
+	result := &Droplet{
+		ID:        strconv.Itoa(d.ID),
+		Name:      d.Name,
+		Status:    d.Status,
+		Region:    d.Region.Slug,
+		Size:      d.SizeSlug,
+		Image:     d.Image.Slug,
+		Tags:      d.Tags,
+		CreatedAt: d.Created,
+	}
+	if result.Image == "" {
+		result.Image = d.Image.Name
+	}
+
+	for _, network := range d.Networks.V4 {
+		switch network.Type {
+		case "public":
+			result.IP = network.IPAddress
+		case "private":
+			result.PrivateIP = network.IPAddress
+		}
+	}
+
+	return result
+}
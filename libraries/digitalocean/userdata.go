@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/libraries/digitalocean/internal/dagger"
+)
+
+// userDataPath is where rendered user data is mounted for doctl
+const userDataPath = "/run/secrets/user-data"
+
+// KeyValue is a plain template variable
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// SecretKeyValue is a template variable whose value is a secret
+type SecretKeyValue struct {
+	Key   string
+	Value *dagger.Secret
+}
+
+// Cloud-Init
+
+// RenderUserData replaces ${KEY} placeholders in a cloud-init template with the
+// given variables and secrets. Other $ expressions are left untouched, so shell
+// scripts keep working. The result is returned as a secret since it may embed
+// secret values.
+func (do *DigitalOcean) RenderUserData(
+	ctx context.Context,
+	// Cloud-init user data template
+	template *dagger.File,
+	// Plain variables
+	// +optional
+	vars []KeyValue,
+	// Secret variables
+	// +optional
+	secrets []SecretKeyValue,
+) (*dagger.Secret, error) {
+	contents, err := template.Contents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user data template: %w", err)
+	}
+
+	replacements := make([]string, 0, 2*(len(vars)+len(secrets)))
+	for _, kv := range vars {
+		replacements = append(replacements, "${"+kv.Key+"}", kv.Value)
+	}
+	for _, kv := range secrets {
+		value, err := kv.Value.Plaintext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secret for %s: %w", kv.Key, err)
+		}
+		replacements = append(replacements, "${"+kv.Key+"}", value)
+	}
+
+	rendered := strings.NewReplacer(replacements...).Replace(contents)
+
+	name, err := template.Name(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return dag.SetSecret(fmt.Sprintf("user-data-%s-%d", name, time.Now().UnixNano()), rendered), nil
+}
+
+// WaitForCloudInit waits until cloud-init has finished on a droplet, checking over SSH
+func (do *DigitalOcean) WaitForCloudInit(
+	ctx context.Context,
+	// Droplet public IP address
+	ip string,
+	// Private SSH key authorized on the droplet
+	privateKey *dagger.Secret,
+	// SSH user
+	// +optional
+	// +default="root"
+	user string,
+	// Timeout in seconds
+	// +optional
+	// +default=600
+	timeout int,
+) error {
+	if user == "" {
+		user = "root"
+	}
+	if timeout <= 0 {
+		timeout = 600
+	}
+
+	fmt.Printf("⏳ Waiting for cloud-init on %s (timeout: %ds)\n", ip, timeout)
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+	for time.Now().Before(deadline) {
+		output, err := sshExec(ip, user, privateKey,
+			"cloud-init status --wait >/dev/null; cloud-init status --format json",
+		).Stdout(ctx)
+		if err == nil {
+			if strings.Contains(output, `"status": "done"`) {
+				fmt.Printf("✅ cloud-init finished on %s\n", ip)
+				return nil
+			}
+			if strings.Contains(output, `"status": "error"`) {
+				return fmt.Errorf("cloud-init failed on %s: %s", ip, output)
+			}
+		}
+
+		time.Sleep(10 * time.Second)
+	}
+
+	return fmt.Errorf("timeout waiting for cloud-init on %s", ip)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/felipepimentel/daggerverse/libraries/docker/internal/dagger"
+)
+
+// diveImage is used to analyze image layers
+const diveImage = "wagoodman/dive:v0.12.0"
+
+// AnalyzeConfig represents configuration for image analysis
+type AnalyzeConfig struct {
+	SizeBudget    int     // Maximum total image size in bytes (0 disables the check)
+	WastedBudget  int     // Maximum wasted bytes (0 disables the check)
+	MinEfficiency float64 // Minimum efficiency score between 0 and 1 (0 disables the check)
+	TopWasted     int     // Number of wasted files to report (default 10)
+}
+
+// LayerInfo represents a single image layer
+type LayerInfo struct {
+	Index   int
+	Digest  string
+	Size    int
+	Command string
+}
+
+// WastedFile represents a file duplicated or removed across layers
+type WastedFile struct {
+	Path  string
+	Count int
+	Size  int
+}
+
+// ImageAnalysis represents the result of an image analysis
+type ImageAnalysis struct {
+	TotalSize   int
+	WastedSize  int
+	Efficiency  float64
+	Layers      []LayerInfo
+	WastedFiles []WastedFile
+}
+
+// diveReport mirrors the dive JSON report
+type diveReport struct {
+	Layer []struct {
+		Index     int    `json:"index"`
+		DigestID  string `json:"digestId"`
+		SizeBytes int    `json:"sizeBytes"`
+		Command   string `json:"command"`
+	} `json:"layer"`
+	Image struct {
+		SizeBytes        int     `json:"sizeBytes"`
+		InefficientBytes int     `json:"inefficientBytes"`
+		EfficiencyScore  float64 `json:"efficiencyScore"`
+		FileReference    []struct {
+			Count     int    `json:"count"`
+			SizeBytes int    `json:"sizeBytes"`
+			File      string `json:"file"`
+		} `json:"fileReference"`
+	} `json:"image"`
+}
+
+// Analyze reports the total size, per-layer sizes and wasted space of an
+// image. The analysis is returned together with an error when the image
+// exceeds any of the configured budgets.
+func (d *Docker) Analyze(ctx context.Context, container *dagger.Container, config AnalyzeConfig) (*ImageAnalysis, error) {
+	if config.TopWasted <= 0 {
+		config.TopWasted = 10
+	}
+
+	output, err := dag.Container().
+		From(diveImage).
+		WithMountedFile("/image.tar", container.AsTarball()).
+		WithExec([]string{
+			"dive", "docker-archive:///image.tar",
+			"--json", "/report.json",
+		}).
+		File("/report.json").
+		Contents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze image: %w", err)
+	}
+
+	var report diveReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		return nil, fmt.Errorf("failed to parse analysis report: %w", err)
+	}
+
+	analysis := &ImageAnalysis{
+		TotalSize:  report.Image.SizeBytes,
+		WastedSize: report.Image.InefficientBytes,
+		Efficiency: report.Image.EfficiencyScore,
+	}
+	for _, layer := range report.Layer {
+		analysis.Layers = append(analysis.Layers, LayerInfo{
+			Index:   layer.Index,
+			Digest:  layer.DigestID,
+			Size:    layer.SizeBytes,
+			Command: layer.Command,
+		})
+	}
+	// dive sorts file references by wasted size, largest first
+	for i, ref := range report.Image.FileReference {
+		if i >= config.TopWasted {
+			break
+		}
+		analysis.WastedFiles = append(analysis.WastedFiles, WastedFile{
+			Path:  ref.File,
+			Count: ref.Count,
+			Size:  ref.SizeBytes,
+		})
+	}
+
+	if config.SizeBudget > 0 && analysis.TotalSize > config.SizeBudget {
+		return analysis, fmt.Errorf("image size %d bytes exceeds budget of %d bytes", analysis.TotalSize, config.SizeBudget)
+	}
+	if config.WastedBudget > 0 && analysis.WastedSize > config.WastedBudget {
+		return analysis, fmt.Errorf("wasted space %d bytes exceeds budget of %d bytes", analysis.WastedSize, config.WastedBudget)
+	}
+	if config.MinEfficiency > 0 && analysis.Efficiency < config.MinEfficiency {
+		return analysis, fmt.Errorf("image efficiency %.4f is below minimum of %.4f", analysis.Efficiency, config.MinEfficiency)
+	}
+
+	return analysis, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/felipepimentel/daggerverse/libraries/docker/internal/dagger"
+)
+
+const (
+	// dindImage runs the Docker engine buildx builds are driven through
+	dindImage = "docker:27-dind"
+	// dockerCliImage ships the docker CLI with the buildx plugin
+	dockerCliImage = "docker:27-cli"
+	// buildxCachePath is where CacheVolume is mounted in the buildx client
+	buildxCachePath = "/buildx-cache"
+)
+
+// buildxScript logs in to the configured registry so registry caches can be
+// read and written, builds, then swaps the exported local cache in place so
+// the cache volume does not grow with every run
+const buildxScript = `set -e
+if [ -n "${REGISTRY_URL:-}" ]; then
+  printenv REGISTRY_PASSWORD | docker login "$REGISTRY_URL" -u "$REGISTRY_USERNAME" --password-stdin >/dev/null
+fi
+docker buildx create --name dagger --driver docker-container --use >/dev/null
+docker buildx build "$@"
+if [ -d ` + buildxCachePath + `-next ]; then
+  rm -rf ` + buildxCachePath + `/*
+  mv ` + buildxCachePath + `-next/* ` + buildxCachePath + `/
+fi`
+
+// usesBuildx reports whether a build needs options Container.Build lacks
+func (c ImageConfig) usesBuildx() bool {
+	return len(c.CacheFrom) > 0 || len(c.CacheTo) > 0 || c.CacheVolume != "" || c.SSH != nil
+}
+
+// buildx builds the image with docker buildx against a Docker engine service
+// and imports the result, for cache import/export and SSH forwarding
+func (d *Docker) buildx(ctx context.Context, config ImageConfig) (*dagger.Container, error) {
+	engine := dag.Container().
+		From(dindImage).
+		WithExposedPort(2375).
+		AsService(dagger.ContainerAsServiceOpts{
+			Args:                     []string{"dockerd", "--host", "tcp://0.0.0.0:2375", "--tls=false"},
+			UseEntrypoint:            true,
+			InsecureRootCapabilities: true,
+		})
+
+	platform := config.Platform
+	if platform == "" {
+		var err error
+		if platform, err = dag.DefaultPlatform(ctx); err != nil {
+			return nil, fmt.Errorf("failed to get default platform: %w", err)
+		}
+	}
+
+	args := []string{"--output", "type=docker,dest=/image.tar", "--platform", string(platform)}
+	if config.Dockerfile != "" {
+		args = append(args, "--file", "/src/"+config.Dockerfile)
+	}
+	if config.BuildTarget != "" {
+		args = append(args, "--target", config.BuildTarget)
+	}
+	for _, buildArg := range config.BuildArgs {
+		args = append(args, "--build-arg", fmt.Sprintf("%s=%s", buildArg.Key, buildArg.Value))
+	}
+	for _, label := range config.Labels {
+		name, err := label.Name(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get label name: %w", err)
+		}
+		value, err := label.Value(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get label value: %w", err)
+		}
+		args = append(args, "--label", fmt.Sprintf("%s=%s", name, value))
+	}
+	for _, cache := range config.CacheFrom {
+		args = append(args, "--cache-from", cache)
+	}
+	for _, cache := range config.CacheTo {
+		args = append(args, "--cache-to", cache)
+	}
+
+	client := dag.Container().
+		From(dockerCliImage).
+		WithServiceBinding("docker", engine).
+		WithEnvVariable("DOCKER_HOST", "tcp://docker:2375").
+		WithDirectory("/src", config.Context)
+
+	if config.CacheVolume != "" {
+		client = client.WithMountedCache(buildxCachePath, dag.CacheVolume(config.CacheVolume))
+		args = append(args,
+			"--cache-from", "type=local,src="+buildxCachePath,
+			"--cache-to", "type=local,dest="+buildxCachePath+"-next,mode=max",
+		)
+	}
+
+	// Secrets are exposed to RUN --mount=type=secret under their own name
+	for _, secret := range config.Secrets {
+		name, err := secret.Name(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get secret name: %w", err)
+		}
+		path := "/run/secrets/" + name
+		client = client.WithMountedSecret(path, secret)
+		args = append(args, "--secret", fmt.Sprintf("id=%s,src=%s", name, path))
+	}
+
+	if config.SSH != nil {
+		client = client.
+			WithUnixSocket("/run/ssh-agent.sock", config.SSH).
+			WithEnvVariable("SSH_AUTH_SOCK", "/run/ssh-agent.sock")
+		args = append(args, "--ssh", "default")
+	}
+
+	if d.Registry != nil {
+		client = client.
+			WithEnvVariable("REGISTRY_URL", d.Registry.URL).
+			WithEnvVariable("REGISTRY_USERNAME", d.Registry.Username).
+			WithSecretVariable("REGISTRY_PASSWORD", d.Registry.Password)
+	}
+
+	fmt.Println("🏗️ Building image with buildx...")
+	image := client.
+		WithExec(append([]string{"sh", "-c", buildxScript, "sh"}, append(args, "/src")...)).
+		File("/image.tar")
+
+	return dag.Container(dagger.ContainerOpts{Platform: platform}).Import(image), nil
+}
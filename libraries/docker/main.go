@@ -8,10 +8,16 @@ import (
 	"github.com/felipepimentel/daggerverse/libraries/docker/internal/dagger"
 )
 
-// Docker provides methods for Docker operations
+// Docker provides methods for Docker operations. Its settings are exported,
+// and hidden with +private, so they survive between calls.
 type Docker struct {
-	client   *dagger.Client
-	registry *RegistryConfig
+	// Credentials set with WithRegistry
+	// +private
+	Registry *RegistryConfig
+
+	// Log pushes, tags, promotions and signatures instead of writing them
+	// +private
+	DryRun bool
 }
 
 
@@ -21,6 +27,16 @@ type BuildArg struct {
 	Value string
 }
 
+// PlannedAction is a change a push or tag makes, or only plans in dry-run
+// mode, e.g. {push, image, ghcr.io/org/app:1.2.3}
+type PlannedAction struct {
+	// push or tag
+	Action   string
+	Resource string
+	// Reference the action writes
+	ID string
+}
+
 // DriverOpt represents a driver-specific option
 type DriverOpt struct {
 	Key   string
@@ -38,6 +54,14 @@ type ImageConfig struct {
 	Context     *dagger.Directory // Build context
 	PullPolicy  string           // Pull policy (always, never, if-not-present)
 	Registry    string           // Registry URL
+	TagMetadata *TagConfig        // Version metadata to derive tags from when pushing
+	BuildTarget string            // Build stage to target
+	Secrets     []*dagger.Secret  // Secrets for RUN --mount=type=secret,id=<secret name>
+	SSH         *dagger.Socket    // SSH agent socket for RUN --mount=type=ssh (builds with buildx)
+	CacheFrom   []string          // Build cache sources, e.g. type=registry,ref=ghcr.io/org/app:cache (builds with buildx)
+	CacheTo     []string          // Build cache exports, e.g. type=registry,ref=ghcr.io/org/app:cache,mode=max (builds with buildx)
+	CacheVolume string            // Cache volume keeping a local build cache across runs (builds with buildx)
+	Platform    dagger.Platform   // Platform to build or pull, e.g. linux/arm64 (defaults to the engine's platform)
 }
 
 // RegistryConfig represents configuration for Docker registry operations
@@ -55,32 +79,31 @@ type VolumeConfig struct {
 	DriverOpts []DriverOpt     // Driver-specific options
 }
 
-// NetworkConfig represents configuration for Docker network operations
-type NetworkConfig struct {
-	Name       string          // Network name
-	Driver     string          // Network driver
-	Labels     []*dagger.Label // Network labels
-	DriverOpts []DriverOpt     // Driver-specific options
-	Internal   bool            // Internal network
-	IPv6       bool            // Enable IPv6
-}
-
 // New creates a new Docker client
 func New() *Docker {
-	return &Docker{
-		client: dag,
-	}
+	return &Docker{}
 }
 
 // WithRegistry configures registry authentication
 func (d *Docker) WithRegistry(config RegistryConfig) *Docker {
-	d.registry = &config
+	d.Registry = &config
+	return d
+}
+
+// WithDryRun makes pushes, tags, promotions and signatures log the
+// references they would write instead of writing them
+func (d *Docker) WithDryRun() *Docker {
+	d.DryRun = true
 	return d
 }
 
 // PullImage pulls a Docker image
 func (d *Docker) PullImage(ctx context.Context, image, tag string, config *ImageConfig) (*dagger.Container, error) {
-	container := d.client.Container()
+	opts := dagger.ContainerOpts{}
+	if config != nil {
+		opts.Platform = config.Platform
+	}
+	container := dag.Container(opts)
 	
 	if config != nil {
 		if config.Registry != "" {
@@ -105,62 +128,82 @@ func (d *Docker) PullImage(ctx context.Context, image, tag string, config *Image
 	return container.From(fmt.Sprintf("%s:%s", image, tag)), nil
 }
 
-// PushImage pushes a Docker image to a registry
-func (d *Docker) PushImage(ctx context.Context, config ImageConfig) error {
-	container := d.client.Container().From(config.Source)
+// PushImage pushes a Docker image to a registry, returning the pushes. When
+// TagMetadata is set, Target is the repository and the image is pushed with
+// every derived tag.
+func (d *Docker) PushImage(ctx context.Context, config ImageConfig) ([]PlannedAction, error) {
+	container := dag.Container().From(config.Source)
 	
 	if config.Labels != nil {
 		for _, label := range config.Labels {
 			name, err := label.Name(ctx)
 			if err != nil {
-				return fmt.Errorf("failed to get label name: %w", err)
+				return nil, fmt.Errorf("failed to get label name: %w", err)
 			}
 			value, err := label.Value(ctx)
 			if err != nil {
-				return fmt.Errorf("failed to get label value: %w", err)
+				return nil, fmt.Errorf("failed to get label value: %w", err)
 			}
 			container = container.WithLabel(name, value)
 		}
 	}
 
-	if d.registry != nil {
+	if d.Registry != nil {
 		container = container.WithRegistryAuth(
-			d.registry.URL,
-			d.registry.Username,
-			d.registry.Password,
+			d.Registry.URL,
+			d.Registry.Username,
+			d.Registry.Password,
 		)
 	}
 
-	_, err := container.Publish(ctx, config.Target)
-	if err != nil {
-		return fmt.Errorf("failed to push image %s: %w", config.Target, err)
+	targets := []string{config.Target}
+	if config.TagMetadata != nil {
+		refs, err := d.ImageReferences(config.Target, *config.TagMetadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive tags for %s: %w", config.Target, err)
+		}
+		targets = refs
 	}
 
-	return nil
+	var plan []PlannedAction
+	for _, target := range targets {
+		plan = append(plan, PlannedAction{Action: "push", Resource: "image", ID: target})
+		if d.DryRun {
+			fmt.Printf("📝 Would push image %s\n", target)
+			continue
+		}
+		_, err := container.Publish(ctx, target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to push image %s: %w", target, err)
+		}
+	}
+
+	return plan, nil
 }
 
-// BuildImage builds a Docker image from a context
+// BuildImage builds a Docker image from a context. Builds that import or
+// export a cache, or forward an SSH agent, run with docker buildx.
 func (d *Docker) BuildImage(ctx context.Context, config ImageConfig) (*dagger.Container, error) {
 	if config.Context == nil {
 		return nil, fmt.Errorf("build context is required")
 	}
 
-	container := d.client.Container()
-	
-	if config.Dockerfile != "" {
-		container = container.Build(config.Context, dagger.ContainerBuildOpts{
-			Dockerfile: config.Dockerfile,
-		})
-	} else {
-		container = container.Build(config.Context)
+	if config.usesBuildx() {
+		return d.buildx(ctx, config)
 	}
-	
-	if config.BuildArgs != nil {
-		for _, buildArg := range config.BuildArgs {
-			container = container.WithEnvVariable(buildArg.Key, buildArg.Value)
-		}
+
+	var buildArgs []dagger.BuildArg
+	for _, buildArg := range config.BuildArgs {
+		buildArgs = append(buildArgs, dagger.BuildArg{Name: buildArg.Key, Value: buildArg.Value})
 	}
 
+	container := dag.Container(dagger.ContainerOpts{Platform: config.Platform}).Build(config.Context, dagger.ContainerBuildOpts{
+		Dockerfile: config.Dockerfile,
+		Target:     config.BuildTarget,
+		BuildArgs:  buildArgs,
+		Secrets:    config.Secrets,
+	})
+
 	if config.Labels != nil {
 		for _, label := range config.Labels {
 			name, err := label.Name(ctx)
@@ -178,35 +221,41 @@ func (d *Docker) BuildImage(ctx context.Context, config ImageConfig) (*dagger.Co
 	return container, nil
 }
 
-// TagImage tags a Docker image
-func (d *Docker) TagImage(ctx context.Context, source, target string) error {
-	container := d.client.Container().From(source)
+// TagImage tags a Docker image, returning the tag
+func (d *Docker) TagImage(ctx context.Context, source, target string) ([]PlannedAction, error) {
+	container := dag.Container().From(source)
 	
-	if d.registry != nil {
+	if d.Registry != nil {
 		container = container.WithRegistryAuth(
-			d.registry.URL,
-			d.registry.Username,
-			d.registry.Password,
+			d.Registry.URL,
+			d.Registry.Username,
+			d.Registry.Password,
 		)
 	}
 
+	plan := []PlannedAction{{Action: "tag", Resource: "image", ID: target}}
+	if d.DryRun {
+		fmt.Printf("📝 Would tag image %s as %s\n", source, target)
+		return plan, nil
+	}
+
 	_, err := container.Publish(ctx, target)
 	if err != nil {
-		return fmt.Errorf("failed to tag image %s as %s: %w", source, target, err)
+		return nil, fmt.Errorf("failed to tag image %s as %s: %w", source, target, err)
 	}
 
-	return nil
+	return plan, nil
 }
 
 // InspectImage returns information about a Docker image
 func (d *Docker) InspectImage(ctx context.Context, image string) (*dagger.Container, error) {
-	container := d.client.Container().From(image)
+	container := dag.Container().From(image)
 	
-	if d.registry != nil {
+	if d.Registry != nil {
 		container = container.WithRegistryAuth(
-			d.registry.URL,
-			d.registry.Username,
-			d.registry.Password,
+			d.Registry.URL,
+			d.Registry.Username,
+			d.Registry.Password,
 		)
 	}
 
@@ -215,13 +264,13 @@ func (d *Docker) InspectImage(ctx context.Context, image string) (*dagger.Contai
 
 // VerifyImageExists checks if an image exists in the registry
 func (d *Docker) VerifyImageExists(ctx context.Context, image string) (bool, error) {
-	container := d.client.Container().From(image)
+	container := dag.Container().From(image)
 	
-	if d.registry != nil {
+	if d.Registry != nil {
 		container = container.WithRegistryAuth(
-			d.registry.URL,
-			d.registry.Username,
-			d.registry.Password,
+			d.Registry.URL,
+			d.Registry.Username,
+			d.Registry.Password,
 		)
 	}
 
@@ -235,41 +284,6 @@ func (d *Docker) VerifyImageExists(ctx context.Context, image string) (bool, err
 
 // CreateVolume creates a new Docker volume
 func (d *Docker) CreateVolume(ctx context.Context, config VolumeConfig) (*dagger.CacheVolume, error) {
-	volume := d.client.CacheVolume(config.Name)
+	volume := dag.CacheVolume(config.Name)
 	return volume, nil
 }
-
-// RemoveVolume removes a Docker volume
-func (d *Docker) RemoveVolume(ctx context.Context, name string) error {
-	// Note: Currently, Dagger doesn't provide direct volume removal.
-	// This is a placeholder for future implementation.
-	return fmt.Errorf("volume removal not supported yet")
-}
-
-// CreateNetwork creates a new Docker network
-func (d *Docker) CreateNetwork(ctx context.Context, config NetworkConfig) error {
-	// Note: Currently, Dagger doesn't provide direct network management.
-	// This is a placeholder for future implementation.
-	return fmt.Errorf("network creation not supported yet")
-}
-
-// RemoveNetwork removes a Docker network
-func (d *Docker) RemoveNetwork(ctx context.Context, name string) error {
-	// Note: Currently, Dagger doesn't provide direct network management.
-	// This is a placeholder for future implementation.
-	return fmt.Errorf("network removal not supported yet")
-}
-
-// ListNetworks lists all Docker networks
-func (d *Docker) ListNetworks(ctx context.Context) error {
-	// Note: Currently, Dagger doesn't provide direct network management.
-	// This is a placeholder for future implementation.
-	return fmt.Errorf("network listing not supported yet")
-}
-
-// ListVolumes lists all Docker volumes
-func (d *Docker) ListVolumes(ctx context.Context) ([]*dagger.CacheVolume, error) {
-	// Note: Currently, Dagger doesn't provide volume listing.
-	// This is a placeholder for future implementation.
-	return nil, fmt.Errorf("volume listing not supported yet")
-}
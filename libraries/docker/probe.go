@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/libraries/docker/internal/dagger"
+)
+
+// probeHost is the hostname the probed service is bound to
+const probeHost = "app"
+
+// ProbeConfig represents configuration for runtime smoke tests
+type ProbeConfig struct {
+	Port           int      // Port the image listens on
+	HTTPPath       string   // HTTP path to probe (TCP probe only when empty)
+	ExpectedStatus int      // Expected HTTP status code (default 200)
+	Timeout        int      // Seconds to wait for the probe to succeed (default 60)
+	Args           []string // Arguments passed to the image entrypoint
+	Env            []BuildArg
+	EnvFile        *dagger.Secret // .env file injected as secret variables
+	Command        []string       // Command run against the service from a client container (service reachable as "app")
+	CommandImage   string         // Image for Command (default alpine)
+}
+
+// RunAndProbe starts an image as a service, waits until its port accepts
+// connections and the optional HTTP probe succeeds, runs an optional command
+// against it and stops it. It returns the probe and command output.
+func (d *Docker) RunAndProbe(ctx context.Context, container *dagger.Container, config ProbeConfig) (string, error) {
+	if config.Port <= 0 {
+		return "", fmt.Errorf("probe port is required")
+	}
+	if config.ExpectedStatus == 0 {
+		config.ExpectedStatus = 200
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 60
+	}
+	if config.CommandImage == "" {
+		config.CommandImage = "alpine:3"
+	}
+
+	env := dag.Dotenv()
+	if config.EnvFile != nil {
+		env = env.WithSecretEnvFile(config.EnvFile)
+	}
+	for _, variable := range config.Env {
+		env = env.WithVariable(variable.Key, variable.Value)
+	}
+	container = env.Apply(container)
+
+	svc := container.
+		WithExposedPort(config.Port).
+		AsService(dagger.ContainerAsServiceOpts{
+			Args:          config.Args,
+			UseEntrypoint: true,
+		})
+
+	// Start blocks until the exposed port accepts TCP connections
+	svc, err := svc.Start(ctx)
+	if err != nil {
+		return "", fmt.Errorf("image failed to start: %w", err)
+	}
+	defer svc.Stop(ctx)
+
+	var output strings.Builder
+	fmt.Fprintf(&output, "TCP probe on port %d succeeded\n", config.Port)
+
+	if config.HTTPPath != "" {
+		url := fmt.Sprintf("http://%s:%d/%s", probeHost, config.Port, strings.TrimPrefix(config.HTTPPath, "/"))
+		script := fmt.Sprintf(`deadline=$(($(date +%%s) + %d))
+while [ "$(date +%%s)" -lt "$deadline" ]; do
+  code=$(curl -s -o /dev/null -w '%%{http_code}' %q || true)
+  if [ "$code" = "%d" ]; then echo "HTTP probe $0 returned $code"; exit 0; fi
+  sleep 2
+done
+echo "HTTP probe $0 did not return %d (last: $code)" >&2
+exit 1`, config.Timeout, url, config.ExpectedStatus, config.ExpectedStatus)
+
+		out, err := dag.Container().
+			From("curlimages/curl:latest").
+			WithServiceBinding(probeHost, svc).
+			WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)).
+			WithExec([]string{"sh", "-c", script, url}).
+			Stdout(ctx)
+		if err != nil {
+			return "", fmt.Errorf("HTTP probe failed: %w", err)
+		}
+		output.WriteString(out)
+	}
+
+	if len(config.Command) > 0 {
+		out, err := dag.Container().
+			From(config.CommandImage).
+			WithServiceBinding(probeHost, svc).
+			WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)).
+			WithExec(config.Command).
+			Stdout(ctx)
+		if err != nil {
+			return "", fmt.Errorf("smoke test command failed: %w", err)
+		}
+		output.WriteString(out)
+	}
+
+	return output.String(), nil
+}
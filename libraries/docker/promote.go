@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// PromoteConfig represents configuration for copying an image between registries
+type PromoteConfig struct {
+	Source         string          // Image to promote, by tag or digest, e.g. staging.example.com/app:1.2.3
+	Target         string          // Reference to copy to, e.g. registry.example.com/app:1.2.3
+	SourceRegistry *RegistryConfig // Credentials for the source registry
+	TargetRegistry *RegistryConfig // Credentials for the target registry
+}
+
+// Promote copies an image by digest from one registry to another without
+// rebuilding it. The manifest, and with it labels and the digest, is copied
+// as is, along with the cosign signatures and attestations of the image.
+// Returns the digest reference of the promoted image, or the one it would have
+// in dry-run mode.
+func (d *Docker) Promote(ctx context.Context, config PromoteConfig) (string, error) {
+	if config.Source == "" || config.Target == "" {
+		return "", fmt.Errorf("source and target images are required")
+	}
+
+	source, err := d.resolveDigest(ctx, config.Source, config.SourceRegistry)
+	if err != nil {
+		return "", err
+	}
+	_, digest, _ := strings.Cut(source, "@")
+	promoted := fmt.Sprintf("%s@%s", repository(config.Target), digest)
+
+	if d.DryRun {
+		fmt.Printf("📝 Would promote %s to %s\n", source, config.Target)
+		return promoted, nil
+	}
+
+	fmt.Printf("🚚 Promoting %s to %s...\n", source, config.Target)
+	_, err = d.cosign(config.SourceRegistry, config.TargetRegistry).
+		WithExec([]string{"cosign", "copy", "--force", source, config.Target}).
+		Sync(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to promote %s to %s: %w", source, config.Target, err)
+	}
+
+	fmt.Printf("✅ Promoted %s\n", promoted)
+	return promoted, nil
+}
+
+// repository strips the tag or digest from an image reference
+func repository(ref string) string {
+	ref, _, _ = strings.Cut(ref, "@")
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref = ref[:i]
+	}
+	return ref
+}
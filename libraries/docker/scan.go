@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/felipepimentel/daggerverse/libraries/docker/internal/dagger"
+)
+
+const (
+	// trivyImage and grypeImage run the vulnerability scans
+	trivyImage = "aquasec/trivy:0.58.1"
+	grypeImage = "anchore/grype:v0.86.1"
+)
+
+// severityRank orders severities from least to most severe
+var severityRank = map[string]int{
+	"UNKNOWN":    0,
+	"NEGLIGIBLE": 0,
+	"LOW":        1,
+	"MEDIUM":     2,
+	"HIGH":       3,
+	"CRITICAL":   4,
+}
+
+// ScanConfig represents configuration for vulnerability scans
+type ScanConfig struct {
+	Scanner       string       // trivy (default) or grype
+	FailOn        string       // Minimum severity that fails the scan: LOW, MEDIUM, HIGH or CRITICAL (empty disables)
+	IgnoreFile    *dagger.File // .trivyignore for Trivy, or a .grype.yaml with ignore rules for Grype
+	IgnoreUnfixed bool         // Skip vulnerabilities without a fixed version
+	Format        string       // Report format: json (default) or sarif
+}
+
+// Vulnerability represents a single finding
+type Vulnerability struct {
+	ID           string
+	Package      string
+	Version      string
+	FixedVersion string
+	Severity     string
+}
+
+// ScanResult represents the result of a vulnerability scan
+type ScanResult struct {
+	Report   *dagger.File // Scanner report in the requested format
+	Critical int
+	High     int
+	Medium   int
+	Low      int
+	Unknown  int
+	// Findings at or above FailOn, or all findings when FailOn is empty
+	Vulnerabilities []Vulnerability
+}
+
+// trivyReport mirrors the Trivy JSON report
+type trivyReport struct {
+	Results []struct {
+		Vulnerabilities []struct {
+			VulnerabilityID  string `json:"VulnerabilityID"`
+			PkgName          string `json:"PkgName"`
+			InstalledVersion string `json:"InstalledVersion"`
+			FixedVersion     string `json:"FixedVersion"`
+			Severity         string `json:"Severity"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+// grypeReport mirrors the Grype JSON report
+type grypeReport struct {
+	Matches []struct {
+		Vulnerability struct {
+			ID       string `json:"id"`
+			Severity string `json:"severity"`
+			Fix      struct {
+				Versions []string `json:"versions"`
+			} `json:"fix"`
+		} `json:"vulnerability"`
+		Artifact struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"artifact"`
+	} `json:"matches"`
+}
+
+// Scan scans an image for known vulnerabilities with Trivy or Grype. The
+// result is returned together with an error when findings reach FailOn.
+func (d *Docker) Scan(ctx context.Context, container *dagger.Container, config ScanConfig) (*ScanResult, error) {
+	if config.Scanner == "" {
+		config.Scanner = "trivy"
+	}
+	if config.Format == "" {
+		config.Format = "json"
+	}
+	if config.Format != "json" && config.Format != "sarif" {
+		return nil, fmt.Errorf("unsupported report format %q, expected json or sarif", config.Format)
+	}
+	threshold := strings.ToUpper(config.FailOn)
+	// UNKNOWN and NEGLIGIBLE rank lowest, so as thresholds they would fail on
+	// every finding
+	if rank, ok := severityRank[threshold]; threshold != "" && (!ok || rank == 0) {
+		return nil, fmt.Errorf("unsupported severity %q, expected LOW, MEDIUM, HIGH or CRITICAL", config.FailOn)
+	}
+
+	var scanner *dagger.Container
+	switch config.Scanner {
+	case "trivy":
+		scanner = d.trivy(container, config)
+	case "grype":
+		scanner = d.grype(container, config)
+	default:
+		return nil, fmt.Errorf("unsupported scanner %q, expected trivy or grype", config.Scanner)
+	}
+
+	output, err := scanner.File("/report.json").Contents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan image: %w", err)
+	}
+
+	var findings []Vulnerability
+	if config.Scanner == "trivy" {
+		findings, err = parseTrivyReport(output)
+	} else {
+		findings, err = parseGrypeReport(output)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse scan report: %w", err)
+	}
+
+	result := &ScanResult{Report: scanner.File("/report." + config.Format)}
+	failing := 0
+	for _, finding := range findings {
+		switch finding.Severity {
+		case "CRITICAL":
+			result.Critical++
+		case "HIGH":
+			result.High++
+		case "MEDIUM":
+			result.Medium++
+		case "LOW":
+			result.Low++
+		default:
+			result.Unknown++
+		}
+
+		if threshold == "" || severityRank[finding.Severity] >= severityRank[threshold] {
+			result.Vulnerabilities = append(result.Vulnerabilities, finding)
+			if threshold != "" {
+				failing++
+			}
+		}
+	}
+
+	fmt.Printf("🔍 %s found %d critical, %d high, %d medium, %d low and %d unknown vulnerabilities\n",
+		config.Scanner, result.Critical, result.High, result.Medium, result.Low, result.Unknown)
+
+	if failing > 0 {
+		return result, fmt.Errorf("%d vulnerabilities at or above %s severity", failing, threshold)
+	}
+
+	return result, nil
+}
+
+// trivy returns a container that has scanned the image with Trivy, with the
+// JSON report in /report.json and the SARIF report in /report.sarif
+func (d *Docker) trivy(container *dagger.Container, config ScanConfig) *dagger.Container {
+	args := []string{
+		"trivy", "image",
+		"--input", "/image.tar",
+		"--scanners", "vuln",
+		"--format", "json",
+		"--output", "/report.json",
+		"--exit-code", "0",
+	}
+	if config.IgnoreUnfixed {
+		args = append(args, "--ignore-unfixed")
+	}
+
+	scanner := dag.Container().
+		From(trivyImage).
+		WithMountedCache("/root/.cache/trivy", dag.CacheVolume("trivy-cache")).
+		WithMountedFile("/image.tar", container.AsTarball())
+	if config.IgnoreFile != nil {
+		scanner = scanner.WithMountedFile("/.trivyignore", config.IgnoreFile)
+		args = append(args, "--ignorefile", "/.trivyignore")
+	}
+
+	return scanner.
+		WithExec(args).
+		WithExec([]string{"trivy", "convert", "--format", "sarif", "--output", "/report.sarif", "/report.json"})
+}
+
+// grype returns a container that has scanned the image with Grype, with the
+// JSON report in /report.json and the SARIF report in /report.sarif
+func (d *Docker) grype(container *dagger.Container, config ScanConfig) *dagger.Container {
+	args := []string{
+		"docker-archive:/image.tar",
+		"--output", "json=/report.json",
+		"--output", "sarif=/report.sarif",
+	}
+	if config.IgnoreUnfixed {
+		args = append(args, "--only-fixed")
+	}
+
+	scanner := dag.Container().
+		From(grypeImage).
+		WithMountedCache("/root/.cache/grype", dag.CacheVolume("grype-cache")).
+		WithMountedFile("/image.tar", container.AsTarball())
+	if config.IgnoreFile != nil {
+		scanner = scanner.WithMountedFile("/.grype.yaml", config.IgnoreFile)
+		args = append(args, "--config", "/.grype.yaml")
+	}
+
+	// The Grype image has no shell, so the binary runs as the entrypoint
+	return scanner.WithExec(args, dagger.ContainerWithExecOpts{UseEntrypoint: true})
+}
+
+// parseTrivyReport extracts the findings of a Trivy JSON report
+func parseTrivyReport(output string) ([]Vulnerability, error) {
+	var report trivyReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		return nil, err
+	}
+
+	var findings []Vulnerability
+	for _, result := range report.Results {
+		for _, v := range result.Vulnerabilities {
+			findings = append(findings, Vulnerability{
+				ID:           v.VulnerabilityID,
+				Package:      v.PkgName,
+				Version:      v.InstalledVersion,
+				FixedVersion: v.FixedVersion,
+				Severity:     strings.ToUpper(v.Severity),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// parseGrypeReport extracts the findings of a Grype JSON report
+func parseGrypeReport(output string) ([]Vulnerability, error) {
+	var report grypeReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		return nil, err
+	}
+
+	var findings []Vulnerability
+	for _, match := range report.Matches {
+		findings = append(findings, Vulnerability{
+			ID:           match.Vulnerability.ID,
+			Package:      match.Artifact.Name,
+			Version:      match.Artifact.Version,
+			FixedVersion: strings.Join(match.Vulnerability.Fix.Versions, ", "),
+			Severity:     strings.ToUpper(match.Vulnerability.Severity),
+		})
+	}
+	return findings, nil
+}
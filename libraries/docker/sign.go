@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/libraries/docker/internal/dagger"
+)
+
+// cosignImage is the base the cosign CLI is installed on; the upstream cosign
+// image has no shell to log in to the registry with
+const cosignImage = "alpine:3.20"
+
+// SignConfig represents configuration for signing images with cosign
+type SignConfig struct {
+	Key           *dagger.Secret // cosign private key; keyless signing is used when unset
+	KeyPassword   *dagger.Secret // Password of the private key
+	IdentityToken *dagger.Secret // OIDC token for keyless signing, e.g. a GitHub Actions ID token
+	SBOM          *dagger.File   // SBOM to attach as an attestation
+	SBOMType      string         // Predicate type of the SBOM: spdxjson (default) or cyclonedx
+	Provenance    *dagger.File   // SLSA provenance predicate to attach as an attestation
+}
+
+// VerifyConfig represents configuration for verifying image signatures
+type VerifyConfig struct {
+	PublicKey             *dagger.File // cosign public key; keyless verification is used when unset
+	CertificateIdentity   string       // Signer identity for keyless verification, e.g. https://github.com/org/repo/.github/workflows/release.yml@refs/heads/main
+	CertificateOIDCIssuer string       // OIDC issuer for keyless verification, e.g. https://token.actions.githubusercontent.com
+	AttestationTypes      []string     // Attestations that must be present and signed, e.g. spdxjson, slsaprovenance
+}
+
+// Sign signs a published image with cosign and attaches the SBOM and
+// provenance attestations of the config. Tags are resolved to their digest
+// first, and the signed digest reference is returned; in dry-run mode nothing
+// is pushed to the registry.
+func (d *Docker) Sign(ctx context.Context, image string, config SignConfig) (string, error) {
+	if config.Key == nil && config.IdentityToken == nil {
+		return "", fmt.Errorf("a private key or an OIDC identity token is required to sign %s", image)
+	}
+	if config.SBOMType == "" {
+		config.SBOMType = "spdxjson"
+	}
+	if config.SBOMType != "spdxjson" && config.SBOMType != "cyclonedx" {
+		return "", fmt.Errorf("unsupported SBOM type %q, expected spdxjson or cyclonedx", config.SBOMType)
+	}
+
+	ref, err := d.resolveDigest(ctx, image, d.Registry)
+	if err != nil {
+		return "", err
+	}
+
+	if d.DryRun {
+		fmt.Printf("📝 Would sign %s\n", ref)
+		if config.SBOM != nil {
+			fmt.Printf("📝 Would attach %s SBOM attestation to %s\n", config.SBOMType, ref)
+		}
+		if config.Provenance != nil {
+			fmt.Printf("📝 Would attach provenance attestation to %s\n", ref)
+		}
+		return ref, nil
+	}
+
+	signer := d.cosign(d.Registry)
+	keyArgs := []string{}
+	if config.Key != nil {
+		signer = signer.WithMountedSecret("/cosign.key", config.Key)
+		if config.KeyPassword != nil {
+			signer = signer.WithSecretVariable("COSIGN_PASSWORD", config.KeyPassword)
+		} else {
+			signer = signer.WithEnvVariable("COSIGN_PASSWORD", "")
+		}
+		keyArgs = append(keyArgs, "--key", "/cosign.key")
+	} else {
+		signer = signer.WithSecretVariable("SIGSTORE_ID_TOKEN", config.IdentityToken)
+	}
+
+	fmt.Printf("🔏 Signing %s...\n", ref)
+	signer = signer.WithExec(append(append([]string{"cosign", "sign", "--yes"}, keyArgs...), ref))
+
+	if config.SBOM != nil {
+		fmt.Printf("📎 Attaching %s SBOM attestation...\n", config.SBOMType)
+		signer = signer.
+			WithMountedFile("/sbom.json", config.SBOM).
+			WithExec(append(append([]string{"cosign", "attest", "--yes", "--type", config.SBOMType, "--predicate", "/sbom.json"}, keyArgs...), ref))
+	}
+	if config.Provenance != nil {
+		fmt.Println("📎 Attaching provenance attestation...")
+		signer = signer.
+			WithMountedFile("/provenance.json", config.Provenance).
+			WithExec(append(append([]string{"cosign", "attest", "--yes", "--type", "slsaprovenance", "--predicate", "/provenance.json"}, keyArgs...), ref))
+	}
+
+	if _, err := signer.Sync(ctx); err != nil {
+		return "", fmt.Errorf("failed to sign %s: %w", ref, err)
+	}
+
+	fmt.Printf("✅ Signed %s\n", ref)
+	return ref, nil
+}
+
+// Verify checks the cosign signature of an image, and the attestations listed
+// in the config, returning an error when any of them is missing or invalid
+func (d *Docker) Verify(ctx context.Context, image string, config VerifyConfig) error {
+	verifier := d.cosign(d.Registry)
+	keyArgs := []string{}
+	switch {
+	case config.PublicKey != nil:
+		verifier = verifier.WithMountedFile("/cosign.pub", config.PublicKey)
+		keyArgs = append(keyArgs, "--key", "/cosign.pub")
+	case config.CertificateIdentity != "" && config.CertificateOIDCIssuer != "":
+		keyArgs = append(keyArgs,
+			"--certificate-identity", config.CertificateIdentity,
+			"--certificate-oidc-issuer", config.CertificateOIDCIssuer,
+		)
+	default:
+		return fmt.Errorf("a public key, or a certificate identity and OIDC issuer, are required to verify %s", image)
+	}
+
+	fmt.Printf("🔍 Verifying signature of %s...\n", image)
+	verifier = verifier.WithExec(append(append([]string{"cosign", "verify", "--output", "text"}, keyArgs...), image))
+	for _, attestation := range config.AttestationTypes {
+		verifier = verifier.WithExec(append(append([]string{"cosign", "verify-attestation", "--output", "text", "--type", attestation}, keyArgs...), image))
+	}
+
+	if _, err := verifier.Sync(ctx); err != nil {
+		return fmt.Errorf("failed to verify %s: %w", image, err)
+	}
+
+	fmt.Printf("✅ Verified %s\n", image)
+	return nil
+}
+
+// cosign returns an uncached container with cosign installed, logged in to
+// each of the given registries
+func (d *Docker) cosign(registries ...*RegistryConfig) *dagger.Container {
+	container := dag.Container().
+		From(cosignImage).
+		WithExec([]string{"apk", "add", "--no-cache", "cosign"}).
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano))
+
+	for i, registry := range registries {
+		if registry == nil {
+			continue
+		}
+		passwordVar := fmt.Sprintf("REGISTRY_PASSWORD_%d", i)
+		container = container.
+			WithSecretVariable(passwordVar, registry.Password).
+			WithExec([]string{
+				"sh", "-c", `printenv "$0" | cosign login "$1" -u "$2" --password-stdin`,
+				passwordVar, registry.URL, registry.Username,
+			})
+	}
+
+	return container
+}
+
+// resolveDigest returns the digest reference of an image, so the signature
+// covers the exact image rather than whatever the tag points to later
+func (d *Docker) resolveDigest(ctx context.Context, image string, registry *RegistryConfig) (string, error) {
+	if strings.Contains(image, "@sha256:") {
+		return image, nil
+	}
+
+	container := dag.Container()
+	if registry != nil {
+		container = container.WithRegistryAuth(registry.URL, registry.Username, registry.Password)
+	}
+
+	ref, err := container.From(image).ImageRef(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve digest of %s: %w", image, err)
+	}
+	return ref, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/felipepimentel/daggerverse/libraries/docker/internal/dagger"
+)
+
+// StackVolume is a cache volume mounted in a stack service
+type StackVolume struct {
+	Path   string // Mount path in the container
+	Volume string // Volume name, scoped to the stack
+}
+
+// StackService is a container of a ServiceStack
+type StackService struct {
+	Name      string            // Hostname other services reach it by
+	Container *dagger.Container // Container run as the service, with its default args
+	Ports     []int             // Ports exposed to the other services
+	DependsOn []string          // Services bound to this one
+	Volumes   []StackVolume     // Cache volumes mounted in the container
+}
+
+// ServiceStack models containers that reach each other by name, like a
+// Compose project, run as Dagger services. Useful to bring up databases,
+// queues and the app under test for integration tests.
+type ServiceStack struct {
+	Name     string
+	Services []StackService
+}
+
+// Stack returns an empty service stack; its name scopes the cache volumes
+func (d *Docker) Stack(name string) *ServiceStack {
+	return &ServiceStack{Name: name}
+}
+
+// WithService adds a service to the stack, replacing one with the same name
+func (s *ServiceStack) WithService(
+	name string,
+	container *dagger.Container,
+	// Ports exposed to the other services
+	// +optional
+	ports []int,
+	// Services this one connects to; they are bound by name
+	// +optional
+	dependsOn []string,
+) *ServiceStack {
+	service := StackService{
+		Name:      name,
+		Container: container,
+		Ports:     ports,
+		DependsOn: dependsOn,
+	}
+
+	for i := range s.Services {
+		if s.Services[i].Name == name {
+			service.Volumes = s.Services[i].Volumes
+			s.Services[i] = service
+			return s
+		}
+	}
+	s.Services = append(s.Services, service)
+	return s
+}
+
+// WithVolume mounts a cache volume in a service, so data such as a database
+// directory persists between runs of the stack
+func (s *ServiceStack) WithVolume(service, path, volume string) (*ServiceStack, error) {
+	for i := range s.Services {
+		if s.Services[i].Name == service {
+			s.Services[i].Volumes = append(s.Services[i].Volumes, StackVolume{Path: path, Volume: volume})
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("service %s not found in stack %s", service, s.Name)
+}
+
+// Service returns a service of the stack, bound to the services it depends on
+func (s *ServiceStack) Service(name string) (*dagger.Service, error) {
+	return s.service(name, map[string]bool{})
+}
+
+// Bind binds every service of the stack to a container by name, e.g. the
+// container running the integration tests
+func (s *ServiceStack) Bind(container *dagger.Container) (*dagger.Container, error) {
+	for _, service := range s.Services {
+		svc, err := s.Service(service.Name)
+		if err != nil {
+			return nil, err
+		}
+		container = container.WithServiceBinding(service.Name, svc)
+	}
+	return container, nil
+}
+
+// service builds a service and, recursively, its dependencies; visiting holds
+// the services being built to detect dependency cycles
+func (s *ServiceStack) service(name string, visiting map[string]bool) (*dagger.Service, error) {
+	if visiting[name] {
+		return nil, fmt.Errorf("dependency cycle through service %s in stack %s", name, s.Name)
+	}
+
+	var spec *StackService
+	for i := range s.Services {
+		if s.Services[i].Name == name {
+			spec = &s.Services[i]
+			break
+		}
+	}
+	if spec == nil {
+		return nil, fmt.Errorf("service %s not found in stack %s", name, s.Name)
+	}
+
+	visiting[name] = true
+	defer delete(visiting, name)
+
+	container := spec.Container
+	for _, dependency := range spec.DependsOn {
+		svc, err := s.service(dependency, visiting)
+		if err != nil {
+			return nil, err
+		}
+		container = container.WithServiceBinding(dependency, svc)
+	}
+	for _, volume := range spec.Volumes {
+		container = container.WithMountedCache(volume.Path, dag.CacheVolume(fmt.Sprintf("%s-%s", s.Name, volume.Volume)))
+	}
+	for _, port := range spec.Ports {
+		container = container.WithExposedPort(port)
+	}
+
+	return container.AsService(), nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// semverPattern matches a semantic version with an optional leading "v"
+var semverPattern = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+
+// invalidTagChars matches characters not allowed in image tags
+var invalidTagChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// TagConfig represents the version metadata image tags are derived from
+type TagConfig struct {
+	Version       string // Semantic version (e.g. "v1.2.3"), derived from Ref when it is a tag
+	Ref           string // Git ref (e.g. "refs/heads/main", "refs/tags/v1.2.3")
+	SHA           string // Git commit SHA
+	DefaultBranch string // Branch that receives the "latest" tag (default main)
+}
+
+// ImageTags derives the canonical tags for an image from version metadata:
+// the full semver, major.minor and major aliases for stable releases, the
+// sanitized branch name, "sha-<short sha>" and "latest" on the default branch.
+func (d *Docker) ImageTags(config TagConfig) ([]string, error) {
+	if config.DefaultBranch == "" {
+		config.DefaultBranch = "main"
+	}
+
+	version := config.Version
+	branch := ""
+	switch {
+	case strings.HasPrefix(config.Ref, "refs/tags/"):
+		if version == "" {
+			version = strings.TrimPrefix(config.Ref, "refs/tags/")
+		}
+	case strings.HasPrefix(config.Ref, "refs/heads/"):
+		branch = strings.TrimPrefix(config.Ref, "refs/heads/")
+	}
+
+	var tags []string
+	if version != "" {
+		match := semverPattern.FindStringSubmatch(version)
+		if match == nil {
+			return nil, fmt.Errorf("invalid semantic version: %s", version)
+		}
+		major, minor, patch, prerelease := match[1], match[2], match[3], match[4]
+
+		full := fmt.Sprintf("%s.%s.%s", major, minor, patch)
+		if prerelease != "" {
+			tags = append(tags, sanitizeTag(full+"-"+prerelease))
+		} else {
+			tags = append(tags, full, major+"."+minor, major)
+		}
+	}
+
+	if branch != "" {
+		if branch == config.DefaultBranch {
+			tags = append(tags, "latest")
+		} else {
+			tags = append(tags, sanitizeTag(branch))
+		}
+	}
+
+	if config.SHA != "" {
+		sha := config.SHA
+		if len(sha) > 7 {
+			sha = sha[:7]
+		}
+		tags = append(tags, "sha-"+sha)
+	}
+
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("no tags could be derived: version, ref or SHA is required")
+	}
+
+	return tags, nil
+}
+
+// ImageReferences returns the full image references for a repository
+// (e.g. "ghcr.io/org/app") with the tags derived from version metadata
+func (d *Docker) ImageReferences(repository string, config TagConfig) ([]string, error) {
+	tags, err := d.ImageTags(config)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		refs = append(refs, fmt.Sprintf("%s:%s", repository, tag))
+	}
+
+	return refs, nil
+}
+
+// sanitizeTag replaces characters not allowed in image tags and truncates
+// the tag to the maximum length of 128 characters
+func sanitizeTag(tag string) string {
+	tag = invalidTagChars.ReplaceAllString(tag, "-")
+	tag = strings.TrimLeft(tag, ".-")
+	if len(tag) > 128 {
+		tag = tag[:128]
+	}
+	return tag
+}
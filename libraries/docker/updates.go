@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/libraries/docker/internal/dagger"
+)
+
+// craneImage provides crane with a busybox shell to log in with
+const craneImage = "gcr.io/go-containerregistry/crane:debug"
+
+// UpdateCheckConfig represents configuration for checking a deployed image for upstream changes
+type UpdateCheckConfig struct {
+	DeployedDigest string          // Digest running in the deployment, e.g. sha256:... or a repo@sha256:... reference
+	Platform       dagger.Platform // Platform deployed, to also match single-platform digests, e.g. linux/amd64
+	Registry       *RegistryConfig // Credentials for the registry (defaults to the configured registry)
+}
+
+// UpdateCheck describes how a deployed image compares to its tag in the registry
+type UpdateCheck struct {
+	Image          string // Image reference checked, by tag
+	DeployedDigest string // Digest running in the deployment
+	CurrentDigest  string // Digest the tag points to in the registry
+	Drift          bool   // Whether the tag now points to a different image
+}
+
+// CheckForUpdates compares the digest of a deployed image with the digest its
+// tag currently points to in the registry, so deployments are only upgraded
+// when the upstream image changed. The deployed digest matches either the
+// multi-platform index or, when a platform is set, that platform's manifest,
+// as docker and the engine report either depending on how the image was pulled.
+func (d *Docker) CheckForUpdates(ctx context.Context, image string, config UpdateCheckConfig) (*UpdateCheck, error) {
+	if image == "" || config.DeployedDigest == "" {
+		return nil, fmt.Errorf("image and deployed digest are required")
+	}
+	if strings.Contains(image, "@") {
+		return nil, fmt.Errorf("image %s must be referenced by tag to check for updates", image)
+	}
+
+	deployed := config.DeployedDigest
+	if _, digest, ok := strings.Cut(deployed, "@"); ok {
+		deployed = digest
+	}
+
+	registry := config.Registry
+	if registry == nil {
+		registry = d.Registry
+	}
+
+	crane := dag.Container().
+		From(craneImage).
+		WithoutEntrypoint().
+		WithEnvVariable("IMAGE", image).
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano))
+	if registry != nil {
+		crane = crane.
+			WithSecretVariable("REGISTRY_PASSWORD", registry.Password).
+			WithExec([]string{
+				"/busybox/sh", "-c", `printenv REGISTRY_PASSWORD | crane auth login "$0" -u "$1" --password-stdin`,
+				registry.URL, registry.Username,
+			})
+	}
+
+	fmt.Printf("🔍 Checking %s for updates...\n", image)
+	current, err := crane.WithExec([]string{"crane", "digest", image}).Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get digest of %s: %w", image, err)
+	}
+	current = strings.TrimSpace(current)
+
+	check := &UpdateCheck{
+		Image:          image,
+		DeployedDigest: deployed,
+		CurrentDigest:  current,
+		Drift:          deployed != current,
+	}
+
+	if check.Drift && config.Platform != "" {
+		platformDigest, err := crane.
+			WithExec([]string{"crane", "digest", "--platform", string(config.Platform), image}).
+			Stdout(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s digest of %s: %w", config.Platform, image, err)
+		}
+		if strings.TrimSpace(platformDigest) == deployed {
+			check.Drift = false
+		}
+	}
+
+	if check.Drift {
+		fmt.Printf("🔄 %s changed upstream: %s -> %s\n", image, deployed, current)
+	} else {
+		fmt.Printf("✅ %s is up to date\n", image)
+	}
+	return check, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/felipepimentel/daggerverse/libraries/docusaurus/internal/dagger"
+)
+
+// checkOverrides make broken links, anchors and markdown links fail the build
+const checkOverrides = `    onBrokenLinks: "throw",
+    onBrokenAnchors: "throw",
+    onBrokenMarkdownLinks: "throw",
+`
+
+// typecheckScript runs tsc when the site is a TypeScript project
+const typecheckScript = `if [ -f tsconfig.json ]; then npx --no-install tsc; else echo "No tsconfig.json, skipping typecheck"; fi`
+
+// Check builds the site failing on broken links, anchors and markdown links,
+// and typechecks it with tsc when it has a tsconfig.json
+func (m *Docusaurus) Check(
+	ctx context.Context,
+	// Typecheck the site
+	// +optional
+	// +default=true
+	typecheck bool,
+) (string, error) {
+	ctr, args := m.withConfig(m.Base(), checkOverrides)
+
+	if typecheck {
+		fmt.Println("🔎 Typechecking...")
+		ctr = ctr.WithExec([]string{"sh", "-c", typecheckScript})
+	}
+
+	fmt.Println("🔗 Building with broken link checks...")
+	output, err := ctr.
+		WithExec(append([]string{"npx", "--no-install", "docusaurus", "build", "--out-dir", "/tmp/check"}, args...)).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("docusaurus check failed: %w", err)
+	}
+
+	fmt.Println("✅ No broken links")
+	return output, nil
+}
+
+// BuildLocales builds the site for each locale into one directory: the
+// default locale at its root and the others in a subdirectory named after
+// them, matching the base URLs Docusaurus gives them. Without locales, all
+// the locales configured in i18n are built.
+func (m *Docusaurus) BuildLocales(
+	// Locales to build, e.g. en, fr, pt-BR
+	// +optional
+	locales []string,
+	// Default locale of the site (i18n.defaultLocale)
+	// +optional
+	// +default="en"
+	defaultLocale string,
+) *dagger.Directory {
+	if len(locales) == 0 {
+		return m.Build()
+	}
+	if defaultLocale == "" {
+		defaultLocale = "en"
+	}
+
+	// The default locale is built first, since a build empties its output
+	// directory and the other locales live inside it
+	ordered := []string{}
+	for _, locale := range locales {
+		if locale == defaultLocale {
+			ordered = append([]string{locale}, ordered...)
+		} else {
+			ordered = append(ordered, locale)
+		}
+	}
+
+	ctr, args := m.withConfig(m.Base(), "")
+	ctr = ctr.WithMountedDirectory("/tmp/build", dag.Directory())
+	for _, locale := range ordered {
+		out := "/tmp/build"
+		if locale != defaultLocale {
+			out = path.Join(out, locale)
+		}
+
+		fmt.Printf("🌐 Building locale %s...\n", locale)
+		ctr = ctr.WithExec(m.run("build", append([]string{"--locale", locale, "--out-dir", out}, args...)...))
+	}
+
+	return ctr.Directory("/tmp/build")
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"github.com/felipepimentel/daggerverse/libraries/docusaurus/internal/dagger"
+)
+
+// wrapperConfigFile is the name of the config written next to the site's
+// config when builds need to change it
+const wrapperConfigFile = "docusaurus.dagger.config.mjs"
+
+// wrapperConfig wraps the site's config, merging the custom fields from the
+// environment and applying overrides (%s, object properties); Docusaurus
+// loads it with jiti, which resolves docusaurus.config.js or .ts alike
+const wrapperConfig = `import config from "./docusaurus.config";
+
+export default async function wrappedConfig(...args) {
+  const site = typeof config === "function" ? await config(...args) : await config;
+  return {
+    ...site,
+    customFields: {
+      ...site.customFields,
+      ...JSON.parse(process.env.DOCUSAURUS_CUSTOM_FIELDS || "{}"),
+    },
+%s  };
+}
+`
+
+// CustomField is a value added to the customFields of the site config
+type CustomField struct {
+	Name  string
+	Value string
+}
+
+// Add a field to customFields of the site config, read by components with
+// useDocusaurusContext().siteConfig.customFields. Custom fields end up in the
+// client bundle: use them for public values such as an API base URL or an
+// analytics ID, and WithSecretVariable for keys only the build may see.
+func (m *Docusaurus) WithCustomField(name string, value string) *Docusaurus {
+	docusaurus := *m
+	docusaurus.CustomFields = append(append([]CustomField{}, m.CustomFields...), CustomField{Name: name, Value: value})
+	return &docusaurus
+}
+
+// withConfig writes the wrapper config when there are custom fields or
+// overrides, returning the docusaurus arguments selecting it
+func (m *Docusaurus) withConfig(ctr *dagger.Container, overrides string) (*dagger.Container, []string) {
+	if len(m.CustomFields) == 0 && overrides == "" {
+		return ctr, nil
+	}
+
+	fields := map[string]string{}
+	for _, field := range m.CustomFields {
+		fields[field.Name] = field.Value
+	}
+	// A map of strings always encodes
+	encoded, _ := json.Marshal(fields)
+
+	ctr = ctr.
+		WithEnvVariable("DOCUSAURUS_CUSTOM_FIELDS", string(encoded)).
+		WithNewFile(path.Join(m.Dir, wrapperConfigFile), fmt.Sprintf(wrapperConfig, overrides))
+	return ctr, []string{"--config", wrapperConfigFile}
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"path"
+	"strings"
 
 	"github.com/felipepimentel/daggerverse/libraries/docusaurus/internal/dagger"
 )
@@ -44,6 +47,25 @@ type Docusaurus struct {
 	DisableCache    bool
 	CacheVolumeName string
 	Yarn            bool
+	// Environment variables set for builds, e.g. for process.env in the config
+	Env []EnvVariable
+	// Secret environment variables set for builds
+	// +private
+	Secrets []SecretVariable
+	// Fields added to customFields of the site config, public in the bundle
+	CustomFields []CustomField
+}
+
+// EnvVariable is an environment variable set for builds
+type EnvVariable struct {
+	Name  string
+	Value string
+}
+
+// SecretVariable is a secret environment variable set for builds
+type SecretVariable struct {
+	Name  string
+	Value *dagger.Secret
 }
 
 // Return base container for running docusaurus with docs mounted and docusaurus
@@ -56,34 +78,70 @@ func (m *Docusaurus) Base() *dagger.Container {
 		WithWorkdir(m.Dir)
 
 	if !m.DisableCache {
+		// node_modules is keyed by the lockfiles so it starts fresh when
+		// dependencies change, instead of npm failing on a stale tree
+		project := strings.TrimPrefix(strings.TrimPrefix(m.Dir, "/src"), "/")
+		cache := dag.Cache(m.CacheVolumeName)
 		ctr = ctr.
 			WithMountedCache(
 				fmt.Sprintf("%s/node_modules", m.Dir),
-				dag.CacheVolume(m.CacheVolumeName),
+				cache.Volume("node-modules", dagger.CacheVolumeOpts{
+					Source: m.Src,
+					Lockfiles: []string{
+						path.Join(project, "package-lock.json"),
+						path.Join(project, "yarn.lock"),
+					},
+				}),
 			).
 			WithMountedCache(
 				fmt.Sprintf("%s/build", m.Dir),
-				dag.CacheVolume(m.CacheVolumeName+"-build"),
+				cache.Volume("build"),
 			).
 			WithMountedCache(
 				"/root/.npm",
-				dag.CacheVolume("node-docusaurus-root"),
+				dag.Cache("node-docusaurus").Volume("root"),
 			).
 			WithMountedCache(
 				"/root/.yarn",
-				dag.CacheVolume("node-docusaurus-root-yarn"),
+				dag.Cache("node-docusaurus").Volume("root-yarn"),
 			)
 	}
 
-	return ctr.
+	ctr = ctr.
 		WithExposedPort(3000).
 		WithExec([]string{m.packageManager(), "install"})
+
+	// Variables are set after the install so they don't invalidate it
+	for _, env := range m.Env {
+		ctr = ctr.WithEnvVariable(env.Name, env.Value)
+	}
+	for _, secret := range m.Secrets {
+		ctr = ctr.WithSecretVariable(secret.Name, secret.Value)
+	}
+
+	return ctr
+}
+
+// Set an environment variable for builds, e.g. a value read by
+// docusaurus.config.js through process.env
+func (m *Docusaurus) WithEnvVariable(name string, value string) *Docusaurus {
+	docusaurus := *m
+	docusaurus.Env = append(append([]EnvVariable{}, m.Env...), EnvVariable{Name: name, Value: value})
+	return &docusaurus
+}
+
+// Set a secret environment variable for builds, e.g. an Algolia API key
+func (m *Docusaurus) WithSecretVariable(name string, value *dagger.Secret) *Docusaurus {
+	docusaurus := *m
+	docusaurus.Secrets = append(append([]SecretVariable{}, m.Secrets...), SecretVariable{Name: name, Value: value})
+	return &docusaurus
 }
 
 // Build production docs
 func (m *Docusaurus) Build() *dagger.Directory {
-	return m.Base().
-		WithExec([]string{m.packageManager(), "run", "build"}).
+	ctr, args := m.withConfig(m.Base(), "")
+	return ctr.
+		WithExec(m.run("build", args...)).
 		// copying build to a temp directory because
 		// cache volumes cannot be exported. This is totally
 		// worth vs the time it takes to build on a cold cache
@@ -94,22 +152,89 @@ func (m *Docusaurus) Build() *dagger.Directory {
 
 // Serve production docs locally as a service
 func (m *Docusaurus) Serve() *dagger.Service {
-	return m.Base().
-		WithExec([]string{m.packageManager(), "run", "build"}).
-		WithExec([]string{m.packageManager(), "run", "serve", "--build"}).
+	ctr, args := m.withConfig(m.Base(), "")
+	return ctr.
+		WithExec(m.run("build", args...)).
+		WithExec(m.run("serve", append([]string{"--build"}, args...)...)).
 		AsService()
 }
 
 // Build and serve development docs as a service
 func (m *Docusaurus) ServeDev() *dagger.Service {
-	return m.Base().
-		WithExec([]string{m.packageManager(), "start", "--", "--host", "0.0.0.0"}).
+	ctr, args := m.withConfig(m.Base(), "")
+	return ctr.
+		WithExec(m.run("start", append([]string{"--host", "0.0.0.0"}, args...)...)).
 		AsService()
 }
 
+// Publish production docs to gh-pages://, s3:// or netlify:// destinations
+// with the site-publish module, returning the public URL
+func (m *Docusaurus) Publish(
+	ctx context.Context,
+	destination string,
+	// GitHub or Netlify token
+	// +optional
+	token *dagger.Secret,
+	// +optional
+	accessKey *dagger.Secret,
+	// +optional
+	secretKey *dagger.Secret,
+	// S3 endpoint URL, e.g. https://nyc3.digitaloceanspaces.com
+	// +optional
+	endpoint string,
+	// +optional
+	region string,
+) (string, error) {
+	return dag.SitePublish(m.Build()).Publish(ctx, destination, dagger.SitePublishPublishOpts{
+		Token:     token,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		Endpoint:  endpoint,
+		Region:    region,
+	})
+}
+
+// Production docs in a Caddy container, ready to be published as an image
+func (m *Docusaurus) Image(
+	// +optional
+	// +default=8080
+	port int,
+) *dagger.Container {
+	return dag.SitePublish(m.Build()).Image(dagger.SitePublishImageOpts{Port: port})
+}
+
 func (m *Docusaurus) packageManager() string {
 	if m.Yarn {
 		return "yarn"
 	}
 	return "npm"
 }
+
+// run returns the command running a package script with the package manager,
+// passing args through to the script
+func (m *Docusaurus) run(script string, args ...string) []string {
+	command := []string{m.packageManager(), "run", script}
+	if len(args) > 0 {
+		command = append(append(command, "--"), args...)
+	}
+	return command
+}
+
+// Snapshot the current docs as a version with docs:version, returning the
+// source directory with the new versioned_docs, versioned_sidebars and
+// versions.json, e.g. to commit alongside a release
+func (m *Docusaurus) CutVersion(
+	// Version to cut, e.g. 1.2.0
+	version string,
+) *dagger.Directory {
+	project := strings.TrimPrefix(strings.TrimPrefix(m.Dir, "/src"), "/")
+	ctr := m.Base().
+		WithExec([]string{m.packageManager(), "run", "docusaurus", "docs:version", version})
+
+	// Only the generated files are copied back, since node_modules is a
+	// cache mount of the working directory
+	return m.Src.
+		WithDirectory(path.Join(project, "versioned_docs"), ctr.Directory(path.Join(m.Dir, "versioned_docs"))).
+		WithDirectory(path.Join(project, "versioned_sidebars"), ctr.Directory(path.Join(m.Dir, "versioned_sidebars"))).
+		WithFile(path.Join(project, "versions.json"), ctr.File(path.Join(m.Dir, "versions.json")))
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/libraries/envoy/internal/dagger"
+)
+
+const (
+	// echoImage is the test upstream; it echoes requests back as JSON and
+	// answers with the status set in the x-set-response-status-code header
+	echoImage = "mendhak/http-https-echo:34"
+	echoPort  = 8080
+	// envoyHost is the hostname the Envoy service is bound to in the client
+	envoyHost = "envoy"
+)
+
+// RouteCase is a request sent through Envoy and the response expected back
+type RouteCase struct {
+	// Name shown in the report (defaults to "<method> <path>")
+	Name string
+	Path string
+	// Defaults to GET
+	Method string
+	// Request headers as "Name: value", e.g. "Host: api.example.com".
+	// "x-set-response-status-code: 503" makes the upstream fail, to exercise retries.
+	Headers []string
+	// Expected status code (defaults to 200)
+	ExpectStatus int
+	// Response headers that must be present, as "Name" or "Name: value"
+	ExpectHeaders []string
+	// Expected number of upstream attempts, read from the x-envoy-attempt-count
+	// response header; requires include_attempt_count_in_response on the virtual host
+	ExpectAttempts int
+}
+
+// RouteResult is the outcome of a single RouteCase
+type RouteResult struct {
+	Name     string
+	Status   int
+	Passed   bool
+	Failures []string
+}
+
+// RouteReport is the outcome of a TestRoute run
+type RouteReport struct {
+	Passed  bool
+	Results []RouteResult
+}
+
+// TestRoute runs Envoy with the given configuration in front of an echo
+// upstream, sends the requests of each case and checks status, headers and
+// retry attempts. The report is returned along with an error when a case fails.
+func (m *Envoy) TestRoute(
+	ctx context.Context,
+	// Envoy configuration routing to the upstream host and port
+	config *dagger.File,
+	cases []RouteCase,
+	// Hostname the echo upstream is bound to, as used by the cluster config
+	// +optional
+	// +default="upstream"
+	upstream string,
+	// Port of the Envoy listener under test
+	// +optional
+	// +default=10000
+	port int,
+) (*RouteReport, error) {
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("at least one route case is required")
+	}
+	if upstream == "" {
+		upstream = "upstream"
+	}
+	if port <= 0 {
+		port = 10000
+	}
+
+	echo := dag.Container().
+		From(echoImage).
+		WithEnvVariable("HTTP_PORT", strconv.Itoa(echoPort)).
+		WithExposedPort(echoPort).
+		AsService()
+
+	envoy := dag.Container().
+		From("envoyproxy/envoy:"+m.Version).
+		WithFile("/etc/envoy/envoy.yaml", config).
+		WithServiceBinding(upstream, echo).
+		WithExposedPort(port).
+		AsService(dagger.ContainerAsServiceOpts{
+			Args: []string{"envoy", "-c", "/etc/envoy/envoy.yaml"},
+		})
+
+	client := dag.Container().
+		From("curlimages/curl:8.11.1").
+		WithServiceBinding(envoyHost, envoy).
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano))
+
+	report := &RouteReport{Passed: true}
+	for _, c := range cases {
+		result, err := runRouteCase(ctx, client, port, c)
+		if err != nil {
+			return nil, err
+		}
+
+		if result.Passed {
+			fmt.Printf("✅ %s: %d\n", result.Name, result.Status)
+		} else {
+			report.Passed = false
+			fmt.Printf("❌ %s: %s\n", result.Name, strings.Join(result.Failures, "; "))
+		}
+		report.Results = append(report.Results, *result)
+	}
+
+	if !report.Passed {
+		return report, fmt.Errorf("route tests failed")
+	}
+	return report, nil
+}
+
+// runRouteCase sends the request of a case through Envoy and checks the response
+func runRouteCase(ctx context.Context, client *dagger.Container, port int, c RouteCase) (*RouteResult, error) {
+	method := c.Method
+	if method == "" {
+		method = "GET"
+	}
+	expectStatus := c.ExpectStatus
+	if expectStatus == 0 {
+		expectStatus = 200
+	}
+	result := &RouteResult{Name: c.Name}
+	if result.Name == "" {
+		result.Name = fmt.Sprintf("%s %s", method, c.Path)
+	}
+
+	args := []string{
+		"curl", "--silent", "--show-error",
+		"--output", "/tmp/body",
+		"--dump-header", "/tmp/headers",
+		"--write-out", "%{http_code}",
+		"--request", method,
+	}
+	for _, header := range c.Headers {
+		args = append(args, "--header", header)
+	}
+	args = append(args, fmt.Sprintf("http://%s:%d/%s", envoyHost, port, strings.TrimPrefix(c.Path, "/")))
+
+	response := client.WithExec(args)
+	output, err := response.Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send %s: %w", result.Name, err)
+	}
+	result.Status, err = strconv.Atoi(strings.TrimSpace(output))
+	if err != nil {
+		return nil, fmt.Errorf("unexpected status %q for %s", output, result.Name)
+	}
+
+	rawHeaders, err := response.File("/tmp/headers").Contents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response headers for %s: %w", result.Name, err)
+	}
+	headers := parseHeaders(rawHeaders)
+
+	if result.Status != expectStatus {
+		result.Failures = append(result.Failures, fmt.Sprintf("expected status %d, got %d", expectStatus, result.Status))
+	}
+	for _, expected := range c.ExpectHeaders {
+		name, value, hasValue := strings.Cut(expected, ":")
+		name = strings.ToLower(strings.TrimSpace(name))
+		actual, ok := headers[name]
+		switch {
+		case !ok:
+			result.Failures = append(result.Failures, fmt.Sprintf("missing header %s", name))
+		case hasValue && actual != strings.TrimSpace(value):
+			result.Failures = append(result.Failures, fmt.Sprintf("expected header %s to be %q, got %q", name, strings.TrimSpace(value), actual))
+		}
+	}
+	if c.ExpectAttempts > 0 {
+		attempts := headers["x-envoy-attempt-count"]
+		if attempts != strconv.Itoa(c.ExpectAttempts) {
+			result.Failures = append(result.Failures, fmt.Sprintf("expected %d attempts, got %q", c.ExpectAttempts, attempts))
+		}
+	}
+
+	result.Passed = len(result.Failures) == 0
+	return result, nil
+}
+
+// parseHeaders returns the headers of the last response in a curl header
+// dump, keyed by lowercase name
+func parseHeaders(raw string) map[string]string {
+	headers := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(raw))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(line, "HTTP/") {
+			headers = make(map[string]string)
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		headers[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+	return headers
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/felipepimentel/daggerverse/libraries/gh/internal/dagger"
+)
+
+// A tag of a GitHub repository.
+type Tag struct {
+	Name string
+	Sha  string
+}
+
+// A commit returned by the GitHub API.
+type Commit struct {
+	Sha     string
+	Message string
+	Author  string
+	URL     string
+}
+
+// The comparison of two commits.
+type Comparison struct {
+	// identical, ahead, behind or diverged.
+	Status   string
+	AheadBy  int
+	BehindBy int
+	// Commits reachable from head but not from base, oldest first (at most 250).
+	Commits []Commit
+	// Paths of the changed files.
+	Files []string
+	URL   string
+}
+
+// Commit status states.
+type CommitState string
+
+const (
+	CommitStateError   CommitState = "error"
+	CommitStateFailure CommitState = "failure"
+	CommitStatePending CommitState = "pending"
+	CommitStateSuccess CommitState = "success"
+)
+
+// List the tags of a repository, newest first.
+func (m *Repo) Tags(
+	ctx context.Context,
+
+	// Maximum number of tags to return.
+	//
+	// +optional
+	// +default=30
+	limit int,
+
+	// GitHub token.
+	//
+	// +optional
+	token *dagger.Secret,
+
+	// GitHub repository (e.g. "owner/repo").
+	//
+	// +optional
+	repo string,
+) ([]Tag, error) {
+	if limit <= 0 {
+		limit = 30
+	}
+
+	var tags []struct {
+		Name   string `json:"name"`
+		Commit struct {
+			Sha string `json:"sha"`
+		} `json:"commit"`
+	}
+
+	// Tags are paginated by 100 at most
+	perPage := min(limit, 100)
+	err := m.Gh.api(ctx, token, repo, "GET", fmt.Sprintf("repos/{repo}/tags?per_page=%d", perPage), nil, limit > perPage, &tags)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Tag, 0, min(len(tags), limit))
+	for _, tag := range tags[:min(len(tags), limit)] {
+		result = append(result, Tag{Name: tag.Name, Sha: tag.Commit.Sha})
+	}
+
+	return result, nil
+}
+
+// Compare two commits, branches or tags (e.g. the previous release tag and HEAD).
+func (m *Repo) Compare(
+	ctx context.Context,
+
+	// Base commit, branch or tag.
+	base string,
+
+	// Head commit, branch or tag.
+	head string,
+
+	// GitHub token.
+	//
+	// +optional
+	token *dagger.Secret,
+
+	// GitHub repository (e.g. "owner/repo").
+	//
+	// +optional
+	repo string,
+) (*Comparison, error) {
+	var comparison struct {
+		Status   string `json:"status"`
+		AheadBy  int    `json:"ahead_by"`
+		BehindBy int    `json:"behind_by"`
+		URL      string `json:"html_url"`
+		Commits  []struct {
+			Sha    string `json:"sha"`
+			URL    string `json:"html_url"`
+			Commit struct {
+				Message string `json:"message"`
+				Author  struct {
+					Name string `json:"name"`
+				} `json:"author"`
+			} `json:"commit"`
+		} `json:"commits"`
+		Files []struct {
+			Filename string `json:"filename"`
+		} `json:"files"`
+	}
+
+	err := m.Gh.api(ctx, token, repo, "GET", fmt.Sprintf("repos/{repo}/compare/%s...%s", base, head), nil, false, &comparison)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Comparison{
+		Status:   comparison.Status,
+		AheadBy:  comparison.AheadBy,
+		BehindBy: comparison.BehindBy,
+		URL:      comparison.URL,
+	}
+
+	for _, commit := range comparison.Commits {
+		result.Commits = append(result.Commits, Commit{
+			Sha:     commit.Sha,
+			Message: commit.Commit.Message,
+			Author:  commit.Commit.Author.Name,
+			URL:     commit.URL,
+		})
+	}
+
+	for _, file := range comparison.Files {
+		result.Files = append(result.Files, file.Filename)
+	}
+
+	return result, nil
+}
+
+// Set a commit status, shown on pull requests and usable in branch protection rules.
+func (m *Repo) SetCommitStatus(
+	ctx context.Context,
+
+	// Commit SHA.
+	sha string,
+
+	// Status state.
+	state CommitState,
+
+	// Label distinguishing this status from others (e.g. "ci/dagger").
+	//
+	// +optional
+	// +default="default"
+	statusContext string,
+
+	// Short description of the status.
+	//
+	// +optional
+	description string,
+
+	// URL linked from the status (e.g. the pipeline logs).
+	//
+	// +optional
+	targetUrl string,
+
+	// GitHub token.
+	//
+	// +optional
+	token *dagger.Secret,
+
+	// GitHub repository (e.g. "owner/repo").
+	//
+	// +optional
+	repo string,
+) error {
+	if sha == "" {
+		return errors.New("\"sha\" is required")
+	}
+
+	if statusContext == "" {
+		statusContext = "default"
+	}
+
+	fields := map[string]any{
+		"state":   state,
+		"context": statusContext,
+	}
+
+	if description != "" {
+		fields["description"] = description
+	}
+
+	if targetUrl != "" {
+		fields["target_url"] = targetUrl
+	}
+
+	return m.Gh.api(ctx, token, repo, "POST", "repos/{repo}/statuses/"+sha, fields, false, nil)
+}
+
+// Create a check run for a commit. Check runs can only be created with a GitHub App token
+// (such as the GITHUB_TOKEN of GitHub Actions).
+//
+// Returns the ID of the check run.
+func (m *Repo) CreateCheckRun(
+	ctx context.Context,
+
+	// Commit SHA.
+	sha string,
+
+	// Name of the check.
+	name string,
+
+	// Conclusion of a completed check: success, failure, neutral, cancelled, skipped, timed_out or action_required.
+	// The check is created in progress when empty.
+	//
+	// +optional
+	conclusion string,
+
+	// Title of the check output.
+	//
+	// +optional
+	title string,
+
+	// Summary of the check output (Markdown).
+	//
+	// +optional
+	summary string,
+
+	// URL linked from the check (e.g. the pipeline logs).
+	//
+	// +optional
+	detailsUrl string,
+
+	// GitHub token.
+	//
+	// +optional
+	token *dagger.Secret,
+
+	// GitHub repository (e.g. "owner/repo").
+	//
+	// +optional
+	repo string,
+) (int, error) {
+	if sha == "" || name == "" {
+		return 0, errors.New("\"sha\" and \"name\" are required")
+	}
+
+	fields := map[string]any{
+		"head_sha": sha,
+		"name":     name,
+		"status":   "in_progress",
+	}
+
+	if conclusion != "" {
+		fields["status"] = "completed"
+		fields["conclusion"] = conclusion
+	}
+
+	if title != "" || summary != "" {
+		if title == "" {
+			title = name
+		}
+
+		fields["output"] = map[string]any{
+			"title":   title,
+			"summary": summary,
+		}
+	}
+
+	if detailsUrl != "" {
+		fields["details_url"] = detailsUrl
+	}
+
+	var checkRun struct {
+		ID int `json:"id"`
+	}
+
+	if err := m.Gh.api(ctx, token, repo, "POST", "repos/{repo}/check-runs", fields, false, &checkRun); err != nil {
+		return 0, err
+	}
+
+	return checkRun.ID, nil
+}
+
+// Call the GitHub REST API and decode the JSON response into out (when not nil).
+// "{repo}" in the path is replaced with the repository.
+func (m *Gh) api(
+	ctx context.Context,
+	token *dagger.Secret,
+	repo string,
+	method string,
+	path string,
+	fields map[string]any,
+	paginate bool,
+	out any,
+) error {
+	if repo == "" {
+		repo = m.Repository
+	}
+
+	if repo == "" {
+		return errors.New("no repository specified")
+	}
+
+	ctr := m.container(token, repo)
+	args := []string{"gh", "api", "--method", method, strings.ReplaceAll(path, "{repo}", repo)}
+
+	if paginate {
+		// Print the items of every page, one per line
+		args = append(args, "--paginate", "--jq", ".[]")
+	}
+
+	if fields != nil {
+		body, err := json.Marshal(fields)
+		if err != nil {
+			return err
+		}
+
+		ctr = ctr.WithNewFile("/work/tmp/request.json", string(body))
+		args = append(args, "--input", "/work/tmp/request.json")
+	}
+
+	output, err := ctr.WithExec(args).Stdout(ctx)
+	if err != nil {
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if paginate {
+		output = "[" + strings.Join(strings.Split(strings.TrimSpace(output), "\n"), ",") + "]"
+	}
+
+	if err := json.Unmarshal([]byte(output), out); err != nil {
+		return fmt.Errorf("failed to parse GitHub API response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/felipepimentel/daggerverse/libraries/gh/internal/dagger"
+)
+
+// Trigger a repository_dispatch event (e.g. to start workflows in another repository).
+func (m *Gh) RepositoryDispatch(
+	ctx context.Context,
+
+	// Event type received by "repository_dispatch" workflow triggers.
+	eventType string,
+
+	// JSON object sent as the event's client_payload.
+	//
+	// +optional
+	payload string,
+
+	// GitHub token.
+	//
+	// +optional
+	token *dagger.Secret,
+
+	// GitHub repository (e.g. "owner/repo").
+	//
+	// +optional
+	repo string,
+) error {
+	if eventType == "" {
+		return errors.New("\"eventType\" is required")
+	}
+
+	if repo == "" {
+		repo = m.Repository
+	}
+
+	if repo == "" {
+		return errors.New("no repository specified")
+	}
+
+	request := map[string]any{
+		"event_type": eventType,
+	}
+
+	if payload != "" {
+		var clientPayload map[string]any
+		if err := json.Unmarshal([]byte(payload), &clientPayload); err != nil {
+			return fmt.Errorf("payload must be a JSON object: %w", err)
+		}
+
+		request["client_payload"] = clientPayload
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.container(token, repo).
+		WithNewFile("/work/tmp/dispatch.json", string(body)).
+		WithExec([]string{
+			"gh", "api",
+			"--method", "POST",
+			fmt.Sprintf("repos/%s/dispatches", repo),
+			"--input", "/work/tmp/dispatch.json",
+		}).
+		Sync(ctx)
+
+	return err
+}
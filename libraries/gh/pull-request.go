@@ -201,6 +201,186 @@ func (m *PullRequest) Create(
 	return err
 }
 
+// Commit generated changes (e.g. a version bump) to a new branch and open a pull request for it.
+//
+// Returns the URL of the pull request.
+func (m *PullRequest) CreateFromChanges(
+	ctx context.Context,
+
+	// Branch to commit the changes to.
+	branch string,
+
+	// Files to add or overwrite, relative to the repository root.
+	changes *dagger.Directory,
+
+	// Commit message.
+	message string,
+
+	// Title for the pull request (default: first line of the commit message).
+	//
+	// +optional
+	title string,
+
+	// Body for the pull request.
+	//
+	// +optional
+	body string,
+
+	// The branch into which you want your code merged (default: default branch).
+	//
+	// +optional
+	base string,
+
+	// Add labels by name.
+	//
+	// +optional
+	labels []string,
+
+	// Commit author name.
+	//
+	// +optional
+	// +default="github-actions[bot]"
+	authorName string,
+
+	// Commit author email.
+	//
+	// +optional
+	// +default="github-actions[bot]@users.noreply.github.com"
+	authorEmail string,
+
+	// GitHub token.
+	//
+	// +optional
+	token *dagger.Secret,
+
+	// GitHub repository (e.g. "owner/repo").
+	//
+	// +optional
+	repo string,
+) (string, error) {
+	return m.submitChanges(ctx, token, repo, branch, changes, message, title, body, base, labels, authorName, authorEmail, false)
+}
+
+// Commit generated changes to a branch and open a pull request for it, or
+// update the pull request already open for the branch. The branch is reset to
+// the base on every run, so it always holds exactly one commit.
+//
+// Returns the URL of the pull request.
+func (m *PullRequest) CreateOrUpdate(
+	ctx context.Context,
+
+	// Branch to commit the changes to.
+	branch string,
+
+	// Files to add or overwrite, relative to the repository root.
+	changes *dagger.Directory,
+
+	// Commit message.
+	message string,
+
+	// Title for the pull request (default: first line of the commit message).
+	//
+	// +optional
+	title string,
+
+	// Body for the pull request.
+	//
+	// +optional
+	body string,
+
+	// The branch into which you want your code merged (default: default branch).
+	//
+	// +optional
+	base string,
+
+	// Add labels by name.
+	//
+	// +optional
+	labels []string,
+
+	// Commit author name.
+	//
+	// +optional
+	// +default="github-actions[bot]"
+	authorName string,
+
+	// Commit author email.
+	//
+	// +optional
+	// +default="github-actions[bot]@users.noreply.github.com"
+	authorEmail string,
+
+	// GitHub token.
+	//
+	// +optional
+	token *dagger.Secret,
+
+	// GitHub repository (e.g. "owner/repo").
+	//
+	// +optional
+	repo string,
+) (string, error) {
+	return m.submitChanges(ctx, token, repo, branch, changes, message, title, body, base, labels, authorName, authorEmail, true)
+}
+
+// Comment on a pull request, e.g. with a test or deployment report.
+func (m *PullRequest) Comment(
+	ctx context.Context,
+
+	// Pull request number, url or branch name.
+	pullRequest string,
+
+	// Comment body.
+	//
+	// +optional
+	body string,
+
+	// Read body text from file.
+	//
+	// +optional
+	bodyFile *dagger.File,
+
+	// Edit the last comment of the current user instead of adding a new one.
+	//
+	// +optional
+	editLast bool,
+
+	// GitHub token.
+	//
+	// +optional
+	token *dagger.Secret,
+
+	// GitHub repository (e.g. "owner/repo").
+	//
+	// +optional
+	repo string,
+) error {
+	if body == "" && bodyFile == nil {
+		return errors.New("\"body\" or \"bodyFile\" is required")
+	}
+
+	ctr := m.Gh.container(token, repo)
+
+	args := []string{"gh", "pr", "comment", pullRequest}
+
+	if body != "" {
+		args = append(args, "--body", body)
+	}
+
+	if bodyFile != nil {
+		ctr = ctr.WithMountedFile("/work/tmp/body", bodyFile)
+		args = append(args, "--body-file", "/work/tmp/body")
+	}
+
+	if editLast {
+		args = append(args, "--edit-last")
+	}
+
+	_, err := ctr.WithExec(args).Sync(ctx)
+
+	return err
+}
+
 // Check if a PR exists
 func (m *PullRequest) Exists(
 	ctx context.Context,
@@ -314,3 +494,115 @@ func (m *PullRequestReview) do(ctx context.Context, action string) error {
 
 	return err
 }
+
+// submitChanges commits the changes to the branch, pushes it and opens a pull
+// request for it. With update, the branch is force pushed and the pull request
+// already open for it, if any, is edited instead.
+func (m *PullRequest) submitChanges(
+	ctx context.Context,
+	token *dagger.Secret,
+	repo string,
+	branch string,
+	changes *dagger.Directory,
+	message string,
+	title string,
+	body string,
+	base string,
+	labels []string,
+	authorName string,
+	authorEmail string,
+	update bool,
+) (string, error) {
+	if repo == "" {
+		repo = m.Gh.Repository
+	}
+
+	if repo == "" {
+		return "", errors.New("no repository specified")
+	}
+
+	if title == "" {
+		title = strings.SplitN(message, "\n", 2)[0]
+	}
+
+	// Only an open pull request is updated; a merged or closed one gets a successor
+	var existing string
+	if update {
+		out, err := m.Gh.container(token, repo).
+			WithExec([]string{"gh", "pr", "list", "--head", branch, "--state", "open", "--json", "url", "--jq", ".[0].url"}).
+			Stdout(ctx)
+		if err != nil {
+			return "", err
+		}
+		existing = strings.TrimSpace(out)
+	}
+
+	push := []string{"git", "push", "--set-upstream", "origin", branch}
+	if update {
+		push = []string{"git", "push", "--force", "--set-upstream", "origin", branch}
+	}
+
+	ctr := m.commitChanges(token, repo, branch, changes, message, base, authorName, authorEmail).
+		WithExec(push)
+
+	args := []string{"gh", "pr", "create", "--head", branch, "--title", title, "--body", body}
+	labelFlag := "--label"
+	if existing != "" {
+		args = []string{"gh", "pr", "edit", existing, "--title", title, "--body", body}
+		labelFlag = "--add-label"
+	}
+
+	if base != "" {
+		args = append(args, "--base", base)
+	}
+
+	for _, label := range labels {
+		args = append(args, labelFlag, label)
+	}
+
+	url, err := ctr.WithExec(args).Stdout(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if existing != "" {
+		return existing, nil
+	}
+
+	return strings.TrimSpace(url), nil
+}
+
+// Clone the repository at base and commit the changes to a new branch.
+func (m *PullRequest) commitChanges(
+	token *dagger.Secret,
+	repo string,
+	branch string,
+	changes *dagger.Directory,
+	message string,
+	base string,
+	authorName string,
+	authorEmail string,
+) *dagger.Container {
+	if authorName == "" {
+		authorName = "github-actions[bot]"
+	}
+
+	if authorEmail == "" {
+		authorEmail = "github-actions[bot]@users.noreply.github.com"
+	}
+
+	cloneArgs := []string{"gh", "repo", "clone", repo, "/work/pr", "--", "--depth", "1"}
+	if base != "" {
+		cloneArgs = append(cloneArgs, "--branch", base)
+	}
+
+	return m.Gh.container(token, repo).
+		WithExec(cloneArgs).
+		WithWorkdir("/work/pr").
+		WithDirectory("/work/pr", changes).
+		WithExec([]string{"git", "config", "user.name", authorName}).
+		WithExec([]string{"git", "config", "user.email", authorEmail}).
+		WithExec([]string{"git", "checkout", "-b", branch}).
+		WithExec([]string{"git", "add", "-A"}).
+		WithExec([]string{"git", "commit", "-m", message})
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"path"
 
 	"github.com/felipepimentel/daggerverse/libraries/gh/internal/dagger"
@@ -190,3 +191,113 @@ func (m *Release) Create(
 
 	return err
 }
+
+// Upload assets to an existing release.
+func (m *Release) UploadAssets(
+	ctx context.Context,
+
+	// Tag of the release.
+	tag string,
+
+	// Release assets to upload.
+	files []*dagger.File,
+
+	// Overwrite existing assets of the same name.
+	//
+	// +optional
+	clobber bool,
+
+	// GitHub token.
+	//
+	// +optional
+	token *dagger.Secret,
+
+	// GitHub repository (e.g. "owner/repo").
+	//
+	// +optional
+	repo string,
+) error {
+	if len(files) == 0 {
+		return errors.New("no assets to upload")
+	}
+
+	dir := dag.Directory()
+
+	for _, file := range files {
+		dir = dir.WithFile("", file)
+	}
+
+	entries, err := dir.Entries(ctx)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"gh", "release", "upload", tag}
+
+	for _, e := range entries {
+		args = append(args, path.Join("/work/assets", e))
+	}
+
+	if clobber {
+		args = append(args, "--clobber")
+	}
+
+	_, err = m.Gh.container(token, repo).
+		WithMountedDirectory("/work/assets", dir).
+		WithExec(args).
+		Sync(ctx)
+
+	return err
+}
+
+// Generate release notes with GitHub from the pull requests merged since the previous release.
+//
+// Returns the release notes as Markdown.
+func (m *Release) GenerateNotes(
+	ctx context.Context,
+
+	// Tag of the release (it doesn't need to exist yet).
+	tag string,
+
+	// Tag of the previous release (default: the latest release).
+	//
+	// +optional
+	previousTag string,
+
+	// Branch or commit SHA the tag is created from when it doesn't exist (default: default branch).
+	//
+	// +optional
+	target string,
+
+	// GitHub token.
+	//
+	// +optional
+	token *dagger.Secret,
+
+	// GitHub repository (e.g. "owner/repo").
+	//
+	// +optional
+	repo string,
+) (string, error) {
+	fields := map[string]any{
+		"tag_name": tag,
+	}
+
+	if previousTag != "" {
+		fields["previous_tag_name"] = previousTag
+	}
+
+	if target != "" {
+		fields["target_commitish"] = target
+	}
+
+	var notes struct {
+		Body string `json:"body"`
+	}
+
+	if err := m.Gh.api(ctx, token, repo, "POST", "repos/{repo}/releases/generate-notes", fields, false, &notes); err != nil {
+		return "", err
+	}
+
+	return notes.Body, nil
+}
@@ -1,6 +1,11 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/felipepimentel/daggerverse/libraries/gh/internal/dagger"
 )
 
@@ -37,3 +42,66 @@ func (m *Repo) Clone(
 
 	return m.Gh.container(token, repository).WithExec(cmdArgs).Directory("/tmp/repo")
 }
+
+// Create a branch in a GitHub repository without cloning it.
+func (m *Repo) CreateBranch(
+	ctx context.Context,
+
+	// Name of the branch to create.
+	branch string,
+
+	// Branch, tag or commit SHA to create the branch from (default: default branch).
+	//
+	// +optional
+	from string,
+
+	// GitHub token.
+	//
+	// +optional
+	token *dagger.Secret,
+
+	// GitHub repository (e.g. "owner/repo").
+	//
+	// +optional
+	repo string,
+) error {
+	if repo == "" {
+		repo = m.Gh.Repository
+	}
+
+	if repo == "" {
+		return errors.New("no repository specified")
+	}
+
+	ctr := m.Gh.container(token, repo)
+
+	if from == "" {
+		defaultBranch, err := ctr.
+			WithExec([]string{"gh", "repo", "view", repo, "--json", "defaultBranchRef", "--jq", ".defaultBranchRef.name"}).
+			Stdout(ctx)
+		if err != nil {
+			return err
+		}
+
+		from = strings.TrimSpace(defaultBranch)
+	}
+
+	sha, err := ctr.
+		WithExec([]string{"gh", "api", fmt.Sprintf("repos/%s/commits/%s", repo, from), "--jq", ".sha"}).
+		Stdout(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = ctr.
+		WithExec([]string{
+			"gh", "api",
+			"--method", "POST",
+			fmt.Sprintf("repos/%s/git/refs", repo),
+			"-f", "ref=refs/heads/" + branch,
+			"-f", "sha=" + strings.TrimSpace(sha),
+		}).
+		Sync(ctx)
+
+	return err
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/felipepimentel/daggerverse/libraries/gh/internal/dagger"
+)
+
+// Fields of a workflow run requested from GitHub CLI.
+const workflowRunFields = "databaseId,name,event,headBranch,headSha,status,conclusion,url,createdAt"
+
+// Work with GitHub Actions workflows.
+func (m *Gh) Workflow() *Workflow {
+	return &Workflow{Gh: m}
+}
+
+type Workflow struct {
+	// +private
+	Gh *Gh
+}
+
+// A GitHub Actions workflow run.
+type WorkflowRun struct {
+	ID         int    `json:"databaseId"`
+	Name       string `json:"name"`
+	Event      string `json:"event"`
+	HeadBranch string `json:"headBranch"`
+	HeadSha    string `json:"headSha"`
+	// queued, in_progress or completed.
+	Status string `json:"status"`
+	// success, failure, cancelled, skipped... (empty until completed).
+	Conclusion string `json:"conclusion"`
+	URL        string `json:"url"`
+	CreatedAt  string `json:"createdAt"`
+}
+
+// Whether the run has completed.
+func (r *WorkflowRun) Completed() bool {
+	return r.Status == "completed"
+}
+
+// Whether the run has completed successfully.
+func (r *WorkflowRun) Succeeded() bool {
+	return r.Completed() && r.Conclusion == "success"
+}
+
+// Trigger a workflow_dispatch event for a workflow.
+func (m *Workflow) Dispatch(
+	ctx context.Context,
+
+	// Workflow file name or ID (e.g. "deploy.yml").
+	workflow string,
+
+	// Branch or tag to run the workflow on (default: default branch).
+	//
+	// +optional
+	ref string,
+
+	// Workflow inputs (e.g. "environment=staging").
+	//
+	// +optional
+	inputs []string,
+
+	// GitHub token.
+	//
+	// +optional
+	token *dagger.Secret,
+
+	// GitHub repository (e.g. "owner/repo").
+	//
+	// +optional
+	repo string,
+) error {
+	if workflow == "" {
+		return errors.New("\"workflow\" is required")
+	}
+
+	args := []string{"gh", "workflow", "run", workflow}
+
+	if ref != "" {
+		args = append(args, "--ref", ref)
+	}
+
+	for _, input := range inputs {
+		if !strings.Contains(input, "=") {
+			return fmt.Errorf("invalid input %q: expected key=value", input)
+		}
+
+		args = append(args, "--raw-field", input)
+	}
+
+	_, err := m.Gh.container(token, repo).WithExec(args).Sync(ctx)
+
+	return err
+}
+
+// Get the status of a workflow run: the given run, or the latest run of a workflow.
+func (m *Workflow) RunStatus(
+	ctx context.Context,
+
+	// Workflow file name or ID, to get its latest run.
+	//
+	// +optional
+	workflow string,
+
+	// Run ID.
+	//
+	// +optional
+	runId string,
+
+	// Only consider runs on this branch when looking up the latest run.
+	//
+	// +optional
+	branch string,
+
+	// GitHub token.
+	//
+	// +optional
+	token *dagger.Secret,
+
+	// GitHub repository (e.g. "owner/repo").
+	//
+	// +optional
+	repo string,
+) (*WorkflowRun, error) {
+	if workflow == "" && runId == "" {
+		return nil, errors.New("\"workflow\" or \"runId\" is required")
+	}
+
+	args := []string{"gh", "run", "view", runId, "--json", workflowRunFields}
+
+	if runId == "" {
+		args = []string{"gh", "run", "list", "--workflow", workflow, "--limit", "1", "--json", workflowRunFields, "--jq", ".[0]"}
+
+		if branch != "" {
+			args = append(args, "--branch", branch)
+		}
+	}
+
+	output, err := m.Gh.container(token, repo).WithExec(args).Stdout(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(output) == "" {
+		return nil, fmt.Errorf("no runs found for workflow %s", workflow)
+	}
+
+	var run WorkflowRun
+	if err := json.Unmarshal([]byte(output), &run); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow run: %w", err)
+	}
+
+	return &run, nil
+}
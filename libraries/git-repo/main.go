@@ -0,0 +1,456 @@
+// Package main provides Git operations shared across pipelines: cloning with
+// a token or SSH key, tagging and pushing, and reading tags, changed files and
+// commit logs as typed results.
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/libraries/git-repo/internal/dagger"
+)
+
+const (
+	// DefaultAuthorName is the default commit and tag author name
+	DefaultAuthorName = "github-actions[bot]"
+	// DefaultAuthorEmail is the default commit and tag author email
+	DefaultAuthorEmail = "github-actions[bot]@users.noreply.github.com"
+
+	// repoDir is where the repository is mounted in the container
+	repoDir = "/src"
+	// sshKeyPath is where the SSH key is mounted in the container
+	sshKeyPath = "/run/secrets/git-ssh-key"
+)
+
+// credentialHelper makes git read the token from the environment at runtime,
+// so it never ends up in a remote URL, the git config or the layer cache.
+const credentialHelper = `!f() { echo username=x-access-token; echo "password=${GIT_TOKEN}"; }; f`
+
+// logSeparator separates the fields of a commit in CommitLog output
+const logSeparator = "\x1f"
+
+// GitRepo runs git commands on a repository
+type GitRepo struct {
+	// Repository, with its .git directory
+	// +private
+	Source *dagger.Directory
+
+	// Token for HTTPS remotes
+	// +private
+	Token *dagger.Secret
+
+	// Private key for SSH remotes
+	// +private
+	SSHKey *dagger.Secret
+
+	// Commit and tag author name
+	// +private
+	AuthorName string
+
+	// Commit and tag author email
+	// +private
+	AuthorEmail string
+
+	// Report what pushes would update instead of pushing
+	// +private
+	DryRun bool
+}
+
+// Commit is a commit of the repository
+type Commit struct {
+	Sha         string
+	AuthorName  string
+	AuthorEmail string
+	// Author date in ISO 8601 format
+	Date    string
+	Subject string
+	Body    string
+}
+
+// PlannedAction is a ref update a push makes, or only plans in dry-run mode,
+// e.g. {push, ref, refs/tags/v1.2.3}
+type PlannedAction struct {
+	// push or delete
+	Action   string
+	Resource string
+	// Remote ref the action updates
+	ID string
+}
+
+// New creates a new GitRepo instance
+func New(
+	// Repository, with its .git directory
+	// +optional
+	source *dagger.Directory,
+	// Token for HTTPS remotes (e.g. a GitHub token)
+	// +optional
+	token *dagger.Secret,
+	// Private key for SSH remotes
+	// +optional
+	sshKey *dagger.Secret,
+	// Commit and tag author name
+	// +optional
+	// +default="github-actions[bot]"
+	authorName string,
+	// Commit and tag author email
+	// +optional
+	// +default="github-actions[bot]@users.noreply.github.com"
+	authorEmail string,
+) *GitRepo {
+	if authorName == "" {
+		authorName = DefaultAuthorName
+	}
+	if authorEmail == "" {
+		authorEmail = DefaultAuthorEmail
+	}
+
+	return &GitRepo{
+		Source:      source,
+		Token:       token,
+		SSHKey:      sshKey,
+		AuthorName:  authorName,
+		AuthorEmail: authorEmail,
+	}
+}
+
+// WithSource sets the repository
+func (m *GitRepo) WithSource(source *dagger.Directory) *GitRepo {
+	git := *m
+	git.Source = source
+	return &git
+}
+
+// WithDryRun makes pushes log and return the ref updates they would make
+// instead of pushing
+func (m *GitRepo) WithDryRun() *GitRepo {
+	git := *m
+	git.DryRun = true
+	return &git
+}
+
+// Clone clones a repository over HTTPS (with the token) or SSH (with the key)
+func (m *GitRepo) Clone(
+	// Repository URL, e.g. https://github.com/owner/repo.git or git@github.com:owner/repo.git
+	url string,
+	// Branch or tag to check out (defaults to the remote HEAD)
+	// +optional
+	ref string,
+	// Number of commits to fetch (defaults to the full history, which tag and
+	// log operations need)
+	// +optional
+	depth int,
+) *GitRepo {
+	args := []string{"git", "clone"}
+	if ref != "" {
+		args = append(args, "--branch", ref)
+	}
+	if depth > 0 {
+		args = append(args, "--depth", fmt.Sprint(depth))
+	}
+	args = append(args, url, repoDir)
+
+	cloned := m.base().
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)).
+		WithExec(args).
+		Directory(repoDir)
+
+	return m.WithSource(cloned)
+}
+
+// Container returns a container with git configured (author and
+// credentials) and the repository as working directory
+func (m *GitRepo) Container() *dagger.Container {
+	ctr := m.base().WithWorkdir(repoDir)
+	if m.Source != nil {
+		ctr = ctr.WithDirectory(repoDir, m.Source)
+	}
+	return ctr
+}
+
+// Directory returns the repository
+func (m *GitRepo) Directory() *dagger.Directory {
+	return m.Source
+}
+
+// Fetch fetches the tags and, for shallow clones, the full history from a remote
+func (m *GitRepo) Fetch(
+	ctx context.Context,
+	// Remote to fetch from
+	// +optional
+	// +default="origin"
+	remote string,
+) (*GitRepo, error) {
+	if remote == "" {
+		remote = "origin"
+	}
+
+	ctr := m.Container().WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano))
+	shallow, err := ctr.WithExec([]string{"git", "rev-parse", "--is-shallow-repository"}).Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect repository: %w", err)
+	}
+
+	args := []string{"git", "fetch", "--tags", "--force", remote}
+	if strings.TrimSpace(shallow) == "true" {
+		args = []string{"git", "fetch", "--unshallow", "--tags", "--force", remote}
+	}
+
+	return m.WithSource(ctr.WithExec(args).Directory(repoDir)), nil
+}
+
+// Tag creates an annotated tag
+func (m *GitRepo) Tag(
+	// Tag name, e.g. v1.2.3
+	name string,
+	// Tag message (defaults to the tag name)
+	// +optional
+	message string,
+	// Commit to tag
+	// +optional
+	// +default="HEAD"
+	ref string,
+	// Replace an existing tag of the same name
+	// +optional
+	force bool,
+) *GitRepo {
+	if message == "" {
+		message = name
+	}
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	args := []string{"git", "tag", "-a", name, "-m", message}
+	if force {
+		args = append(args, "--force")
+	}
+	args = append(args, ref)
+
+	fmt.Printf("🏷️  Tagging %s as %s\n", ref, name)
+	return m.WithSource(m.Container().WithExec(args).Directory(repoDir))
+}
+
+// Push pushes refs and tags to a remote, returning the ref updates. In
+// dry-run mode, git computes and logs them without sending them.
+func (m *GitRepo) Push(
+	ctx context.Context,
+	// Refs to push, e.g. main or v1.2.3 (defaults to the current branch,
+	// unless only tags are pushed)
+	// +optional
+	refs []string,
+	// Push all tags
+	// +optional
+	tags bool,
+	// Force the update of the remote refs
+	// +optional
+	force bool,
+	// Remote to push to
+	// +optional
+	// +default="origin"
+	remote string,
+) ([]PlannedAction, error) {
+	if remote == "" {
+		remote = "origin"
+	}
+	if m.Token == nil && m.SSHKey == nil {
+		return nil, fmt.Errorf("a token or SSH key is required to push")
+	}
+
+	push := []string{"git", "push", "--porcelain"}
+	if m.DryRun {
+		push = append(push, "--dry-run")
+	}
+
+	// With only tags requested, no ref is pushed: a bare git push would push
+	// the current branch, and fails on a detached HEAD
+	var commands [][]string
+	if len(refs) > 0 || !tags {
+		args := append([]string{}, push...)
+		if force {
+			args = append(args, "--force")
+		}
+		args = append(args, remote)
+		if len(refs) == 0 {
+			args = append(args, "HEAD")
+		}
+		commands = append(commands, append(args, refs...))
+	}
+	if tags {
+		commands = append(commands, append(append([]string{}, push...), remote, "--tags"))
+	}
+
+	if !m.DryRun {
+		fmt.Printf("🚀 Pushing to %s...\n", remote)
+	}
+	var plan []PlannedAction
+	ctr := m.Container().WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano))
+	for _, command := range commands {
+		ctr = ctr.WithExec(command)
+		output, err := ctr.Stdout(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to push to %s: %w", remote, err)
+		}
+		plan = append(plan, pushActions(output)...)
+	}
+	if m.DryRun {
+		for _, action := range plan {
+			fmt.Printf("📝 Would %s %s %s on %s\n", action.Action, action.Resource, action.ID, remote)
+		}
+	}
+	return plan, nil
+}
+
+// pushActions returns the ref updates of git push --porcelain output, whose
+// lines are <flag>\t<from>:<to>\t<summary>; refs already up to date (=) and
+// rejected ones (!) are skipped
+func pushActions(output string) []PlannedAction {
+	var actions []PlannedAction
+	// The flag of fast-forwards is a space, so lines are not trimmed
+	for _, line := range strings.Split(output, "\n") {
+		flag, rest, ok := strings.Cut(line, "\t")
+		if !ok || flag == "=" || flag == "!" {
+			continue
+		}
+		refs, _, _ := strings.Cut(rest, "\t")
+		_, to, _ := strings.Cut(refs, ":")
+
+		action := "push"
+		if flag == "-" {
+			action = "delete"
+		}
+		actions = append(actions, PlannedAction{Action: action, Resource: "ref", ID: to})
+	}
+	return actions
+}
+
+// ChangedFiles returns the files changed between a ref and HEAD
+func (m *GitRepo) ChangedFiles(
+	ctx context.Context,
+	// Ref to compare HEAD with, e.g. the last release tag or origin/main
+	sinceRef string,
+	// Only consider these paths
+	// +optional
+	paths []string,
+) ([]string, error) {
+	args := []string{"git", "diff", "--name-only", sinceRef + "...HEAD"}
+	if len(paths) > 0 {
+		args = append(append(args, "--"), paths...)
+	}
+
+	output, err := m.Container().WithExec(args).Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changes since %s: %w", sinceRef, err)
+	}
+	return lines(output), nil
+}
+
+// LastTag returns the highest version tag with a prefix reachable from HEAD,
+// or an empty string when there is none. Pre-releases sort before their
+// release, so v1.0.0-rc.1 < v1.0.0.
+func (m *GitRepo) LastTag(
+	ctx context.Context,
+	// Tag prefix, e.g. v or mymodule/v
+	// +optional
+	// +default="v"
+	prefix string,
+) (string, error) {
+	if prefix == "" {
+		prefix = "v"
+	}
+
+	output, err := m.Container().
+		WithExec([]string{
+			"git", "-c", "versionsort.suffix=-",
+			"tag", "--list", prefix + "*",
+			"--merged", "HEAD",
+			"--sort", "-version:refname",
+		}).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	tags := lines(output)
+	if len(tags) == 0 {
+		return "", nil
+	}
+	return tags[0], nil
+}
+
+// CommitLog returns the commits reachable from HEAD but not from a ref,
+// newest first
+func (m *GitRepo) CommitLog(
+	ctx context.Context,
+	// Ref to start after, e.g. the last release tag (defaults to the whole history)
+	// +optional
+	sinceRef string,
+	// Only consider commits touching these paths
+	// +optional
+	paths []string,
+) ([]Commit, error) {
+	format := strings.Join([]string{"%H", "%an", "%ae", "%aI", "%s", "%b"}, logSeparator) + "%x00"
+	args := []string{"git", "log", "--format=" + format}
+	if sinceRef != "" {
+		args = append(args, sinceRef+"..HEAD")
+	}
+	if len(paths) > 0 {
+		args = append(append(args, "--"), paths...)
+	}
+
+	output, err := m.Container().WithExec(args).Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit log: %w", err)
+	}
+
+	var commits []Commit
+	for _, record := range strings.Split(output, "\x00") {
+		fields := strings.SplitN(strings.TrimSpace(record), logSeparator, 6)
+		if len(fields) != 6 {
+			continue
+		}
+		commits = append(commits, Commit{
+			Sha:         fields[0],
+			AuthorName:  fields[1],
+			AuthorEmail: fields[2],
+			Date:        fields[3],
+			Subject:     fields[4],
+			Body:        strings.TrimSpace(fields[5]),
+		})
+	}
+	return commits, nil
+}
+
+// base returns the git container with the author and credentials configured
+func (m *GitRepo) base() *dagger.Container {
+	ctr := dag.Container().
+		From("alpine:latest").
+		WithExec([]string{"apk", "add", "--no-cache", "git", "openssh-client"}).
+		WithExec([]string{"git", "config", "--global", "user.name", m.AuthorName}).
+		WithExec([]string{"git", "config", "--global", "user.email", m.AuthorEmail}).
+		WithExec([]string{"git", "config", "--global", "--add", "safe.directory", "*"})
+
+	if m.Token != nil {
+		ctr = ctr.
+			WithSecretVariable("GIT_TOKEN", m.Token).
+			WithExec([]string{"git", "config", "--global", "credential.helper", credentialHelper})
+	}
+	if m.SSHKey != nil {
+		ctr = ctr.
+			WithMountedSecret(sshKeyPath, m.SSHKey, dagger.ContainerWithMountedSecretOpts{Mode: 0o600}).
+			WithEnvVariable("GIT_SSH_COMMAND", "ssh -i "+sshKeyPath+" -o StrictHostKeyChecking=accept-new")
+	}
+	return ctr
+}
+
+// lines splits command output into its non-empty lines
+func lines(output string) []string {
+	var result []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
@@ -1,6 +1,7 @@
 // Provides Helm functionality
 //
-// The main focus is to publish new Helm Chart versions on registries.
+// The main focus is to publish new Helm Chart versions on registries: OCI
+// registries and ChartMuseum, after linting, unit testing and rendering them.
 //
 // For accomplishing this the https://helm.sh/ tool is used.
 
@@ -188,13 +189,7 @@ func (h *Helm) Lint(
 	// +optional
 	args []string,
 ) (string, error) {
-	var c *dagger.Container
-
-	if h.hasMissingDependencies(ctx, directory) {
-		c = h.createContainer(directory).WithMountedDirectory("./charts", h.dependencyUpdate(directory))
-	} else {
-		c = h.createContainer(directory)
-	}
+	c := h.withDependencies(ctx, directory)
 
 	out, err := c.WithExec([]string{"sh", "-c", fmt.Sprintf("%s %s", "helm lint", strings.Join(args, " "))}).Stdout(ctx)
 	if err != nil {
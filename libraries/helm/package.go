@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/libraries/helm/internal/dagger"
+)
+
+// Render the Helm Chart templates with values overlays applied in order, as helm template does.
+//
+// Returns the rendered manifests.
+//
+// Example usage: dagger call template --directory ./helm/examples/testdata/mychart/ --values ./values-prod.yaml --set image.tag=1.2.3
+func (h *Helm) Template(
+	// method call context
+	ctx context.Context,
+	// directory that contains the Helm Chart
+	directory *dagger.Directory,
+	// values files, later files override earlier ones
+	// +optional
+	values []*dagger.File,
+	// values set on the command line (key=value)
+	// +optional
+	set []string,
+	// release name
+	// +optional
+	// +default="release"
+	releaseName string,
+	// release namespace
+	// +optional
+	namespace string,
+) (string, error) {
+	if releaseName == "" {
+		releaseName = "release"
+	}
+
+	c := h.withDependencies(ctx, directory)
+	args := []string{"helm", "template", releaseName, "."}
+	for i, file := range values {
+		valuesPath := fmt.Sprintf("/values/%d.yaml", i)
+		c = c.WithMountedFile(valuesPath, file)
+		args = append(args, "--values", valuesPath)
+	}
+	for _, value := range set {
+		args = append(args, "--set", value)
+	}
+	if namespace != "" {
+		args = append(args, "--namespace", namespace)
+	}
+
+	out, err := c.WithExec(args).Stdout(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return out, nil
+}
+
+// Package the Helm Chart with its dependencies.
+//
+// Returns the chart archive (<name>-<version>.tgz).
+//
+// Example usage: dagger call package --directory ./helm/examples/testdata/mychart/ --version 1.2.3 export --path .
+func (h *Helm) Package(
+	// method call context
+	ctx context.Context,
+	// directory that contains the Helm Chart
+	directory *dagger.Directory,
+	// chart version, overriding the one in Chart.yaml
+	// +optional
+	version string,
+	// app version, overriding the one in Chart.yaml
+	// +optional
+	appVersion string,
+) (*dagger.File, error) {
+	args := []string{"helm", "package", ".", "--destination", "/tmp/package"}
+	if version != "" {
+		args = append(args, "--version", version)
+	}
+	if appVersion != "" {
+		args = append(args, "--app-version", appVersion)
+	}
+
+	packaged := h.withDependencies(ctx, directory).
+		WithExec(args).
+		Directory("/tmp/package")
+
+	archives, err := packaged.Glob(ctx, "*.tgz")
+	if err != nil {
+		return nil, err
+	}
+	if len(archives) != 1 {
+		return nil, fmt.Errorf("expected one chart archive, found %d", len(archives))
+	}
+
+	return packaged.File(archives[0]), nil
+}
+
+// Packages and pushes a Helm chart to a ChartMuseum repository, authenticating with basic auth.
+//
+// Returns true if the chart was successfully pushed, or false if the chart version already exists.
+//
+// Example usage:
+//
+//	dagger call package-push-chart-museum \
+//	  --url https://charts.example.com \
+//	  --username $CHARTMUSEUM_USER \
+//	  --password env:CHARTMUSEUM_PASSWORD \
+//	  --directory ./examples/testdata/mychart/
+func (h *Helm) PackagePushChartMuseum(
+	// method call context
+	ctx context.Context,
+	// directory that contains the Helm Chart
+	directory *dagger.Directory,
+	// URL of the ChartMuseum server
+	url string,
+	// basic auth username
+	// +optional
+	username string,
+	// basic auth password
+	// +optional
+	password *dagger.Secret,
+) (bool, error) {
+	fmt.Println("☸️ Helm package and push to ChartMuseum")
+	chart, err := h.Package(ctx, directory, "", "")
+	if err != nil {
+		return false, err
+	}
+
+	name, err := chart.Name(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	c := dag.Container().
+		From("curlimages/curl:8.11.1").
+		WithMountedFile(path.Join("/tmp", name), chart).
+		WithEnvVariable("CHART", path.Join("/tmp", name)).
+		WithEnvVariable("CHARTMUSEUM_URL", strings.TrimSuffix(url, "/")).
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano))
+
+	auth := ""
+	if username != "" && password != nil {
+		c = c.
+			WithEnvVariable("CHARTMUSEUM_USERNAME", username).
+			WithSecretVariable("CHARTMUSEUM_PASSWORD", password)
+		auth = `-u "${CHARTMUSEUM_USERNAME}:${CHARTMUSEUM_PASSWORD}" `
+	}
+
+	status, err := c.WithExec([]string{"sh", "-c",
+		`curl -sS -o /dev/stderr -w '%{http_code}' ` + auth + `--data-binary "@${CHART}" "${CHARTMUSEUM_URL}/api/charts"`,
+	}).Stdout(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	code, err := strconv.Atoi(strings.TrimSpace(status))
+	if err != nil {
+		return false, fmt.Errorf("unexpected response from ChartMuseum: %q", status)
+	}
+	switch {
+	case code == 409:
+		// Chart version exists
+		return false, nil
+	case code >= 200 && code < 300:
+		return true, nil
+	default:
+		return false, fmt.Errorf("ChartMuseum rejected %s with status %d", name, code)
+	}
+}
+
+// withDependencies returns the Helm container with the chart dependencies downloaded when any are missing.
+func (h *Helm) withDependencies(
+	// method call context
+	ctx context.Context,
+	// directory that contains the Helm Chart
+	directory *dagger.Directory,
+) *dagger.Container {
+	if h.hasMissingDependencies(ctx, directory) {
+		return h.createContainer(directory).WithMountedDirectory("./charts", h.dependencyUpdate(directory))
+	}
+	return h.createContainer(directory)
+}
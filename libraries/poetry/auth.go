@@ -0,0 +1,160 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/felipepimentel/daggerverse/libraries/poetry/internal/dagger"
+)
+
+const (
+	// caCertPath is where the extra CA certificate is mounted
+	caCertPath = "/etc/ssl/certs/poetry-extra-ca.crt"
+	// caBundlePath is the system bundle combined with the extra certificate
+	caBundlePath = "/etc/ssl/certs/poetry-ca-bundle.crt"
+)
+
+// pipInstallScript installs packages with pip, from the private index when
+// one is configured. Credentials are only put in the index URL for the
+// duration of the command, so they never reach the filesystem.
+const pipInstallScript = `set -e
+if [ -n "${PIP_PRIVATE_INDEX:-}" ]; then
+  index="$PIP_PRIVATE_INDEX"
+  if [ -n "${PIP_PRIVATE_USERNAME:-}" ]; then
+    index="${index%%://*}://${PIP_PRIVATE_USERNAME}:${PIP_PRIVATE_PASSWORD}@${index#*://}"
+  fi
+  export PIP_INDEX_URL="$index"
+fi
+pip install "$@"`
+
+// Source is a private package repository, such as a PyPI mirror or Artifactory
+type Source struct {
+	Name     string
+	URL      string
+	Priority string
+	// Used as pip index to install Poetry and its plugins
+	PipIndex bool
+
+	Username *dagger.Secret
+	Password *dagger.Secret
+}
+
+// WithSource adds a package source to the project before resolving
+// dependencies, unless pyproject.toml already declares it
+func (m *Poetry) WithSource(
+	name string,
+	url string,
+	// Source priority: primary, supplemental or explicit
+	// +optional
+	// +default="primary"
+	priority string,
+	// Also install Poetry itself from this source, for hosts without access to pypi.org
+	// +optional
+	pipIndex bool,
+) *Poetry {
+	if priority == "" {
+		priority = "primary"
+	}
+
+	source := Source{Name: name, URL: url, Priority: priority, PipIndex: pipIndex}
+	for i := range m.Sources {
+		if m.Sources[i].Name == name {
+			source.Username = m.Sources[i].Username
+			source.Password = m.Sources[i].Password
+			m.Sources[i] = source
+			return m
+		}
+	}
+	m.Sources = append(m.Sources, source)
+	return m
+}
+
+// WithAuth sets the credentials of a source, either added with WithSource or
+// declared in pyproject.toml. Use the token as password for token auth.
+func (m *Poetry) WithAuth(
+	// Source name, as in pyproject.toml
+	name string,
+	username *dagger.Secret,
+	password *dagger.Secret,
+) *Poetry {
+	for i := range m.Sources {
+		if m.Sources[i].Name == name {
+			m.Sources[i].Username = username
+			m.Sources[i].Password = password
+			return m
+		}
+	}
+	m.Sources = append(m.Sources, Source{Name: name, Username: username, Password: password})
+	return m
+}
+
+// WithCACert trusts an extra CA certificate (PEM), for sources served with a
+// certificate from an internal CA
+func (m *Poetry) WithCACert(cert *dagger.File) *Poetry {
+	m.CACert = cert
+	return m
+}
+
+// withCACert makes pip, Poetry and Python trust the extra CA certificate
+func (m *Poetry) withCACert(container *dagger.Container) *dagger.Container {
+	if m.CACert == nil {
+		return container
+	}
+
+	return container.
+		WithMountedFile(caCertPath, m.CACert).
+		WithExec([]string{"sh", "-c", "cat /etc/ssl/certs/ca-certificates.crt " + caCertPath + " > " + caBundlePath}).
+		WithEnvVariable("SSL_CERT_FILE", caBundlePath).
+		WithEnvVariable("REQUESTS_CA_BUNDLE", caBundlePath).
+		WithEnvVariable("PIP_CERT", caBundlePath)
+}
+
+// withSourceAuth exposes the source credentials to Poetry through its
+// POETRY_HTTP_BASIC_<NAME>_* settings, and to pip for the pip index
+func (m *Poetry) withSourceAuth(container *dagger.Container) *dagger.Container {
+	for _, source := range m.Sources {
+		if source.PipIndex {
+			container = container.WithEnvVariable("PIP_PRIVATE_INDEX", source.URL)
+		}
+		if source.Username == nil || source.Password == nil {
+			continue
+		}
+
+		prefix := "POETRY_HTTP_BASIC_" + envName(source.Name)
+		container = container.
+			WithSecretVariable(prefix+"_USERNAME", source.Username).
+			WithSecretVariable(prefix+"_PASSWORD", source.Password)
+		if source.PipIndex {
+			container = container.
+				WithSecretVariable("PIP_PRIVATE_USERNAME", source.Username).
+				WithSecretVariable("PIP_PRIVATE_PASSWORD", source.Password)
+		}
+	}
+	return container
+}
+
+// withSources adds the sources with a URL to the project, skipping those
+// pyproject.toml already declares
+func (m *Poetry) withSources(container *dagger.Container) *dagger.Container {
+	for _, source := range m.Sources {
+		if source.URL == "" {
+			continue
+		}
+		container = container.WithExec([]string{
+			"sh", "-c",
+			`poetry source show "$0" >/dev/null 2>&1 || poetry source add --priority="$2" "$0" "$1"`,
+			source.Name, source.URL, source.Priority,
+		})
+	}
+	return container
+}
+
+// pipInstall installs packages with pip, honoring the private pip index
+func pipInstall(container *dagger.Container, packages ...string) *dagger.Container {
+	return container.WithExec(append([]string{"sh", "-c", pipInstallScript, "sh"}, packages...))
+}
+
+// envName converts a source name to the form Poetry expects in environment
+// variables, e.g. my-mirror becomes MY_MIRROR
+func envName(name string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
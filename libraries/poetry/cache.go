@@ -0,0 +1,73 @@
+package main
+
+import (
+	"github.com/felipepimentel/daggerverse/libraries/poetry/internal/dagger"
+)
+
+const (
+	defaultPipCache    = "poetry-pip-cache"
+	defaultPoetryCache = "poetry-cache"
+	// virtualenvsPath is where the virtualenv cache volume is mounted
+	virtualenvsPath = "/venvs"
+)
+
+// CacheConfig names the cache volumes mounted by Poetry operations; an empty
+// name leaves that cache out
+type CacheConfig struct {
+	// pip download cache
+	Pip string
+	// Poetry package and metadata cache
+	Poetry string
+	// Project virtualenvs. Without it dependencies are installed in the
+	// system site-packages of every container again.
+	Virtualenv string
+}
+
+// WithCache sets the names of the cache volumes, e.g. to scope them per
+// project or to keep installed virtualenvs between Install and Test calls
+func (m *Poetry) WithCache(
+	// +optional
+	// +default="poetry-pip-cache"
+	pip string,
+	// +optional
+	// +default="poetry-cache"
+	poetry string,
+	// Virtualenv cache volume (disabled when empty)
+	// +optional
+	virtualenv string,
+) *Poetry {
+	m.Cache = CacheConfig{
+		Pip:        pip,
+		Poetry:     poetry,
+		Virtualenv: virtualenv,
+	}
+	return m
+}
+
+// WithoutCache disables all cache volumes, for fully isolated runs
+func (m *Poetry) WithoutCache() *Poetry {
+	m.Cache = CacheConfig{}
+	return m
+}
+
+// withCache mounts the configured cache volumes and sets where Poetry
+// installs dependencies
+func (m *Poetry) withCache(container *dagger.Container) *dagger.Container {
+	if m.Cache.Pip != "" {
+		container = container.WithMountedCache("/root/.cache/pip", dag.CacheVolume(m.Cache.Pip))
+	}
+	if m.Cache.Poetry != "" {
+		container = container.WithMountedCache("/root/.cache/pypoetry", dag.CacheVolume(m.Cache.Poetry))
+	}
+	if m.Cache.Virtualenv == "" {
+		return container.WithEnvVariable("POETRY_VIRTUALENVS_CREATE", "false")
+	}
+
+	// Concurrent installs into the same virtualenv would corrupt it
+	return container.
+		WithMountedCache(virtualenvsPath, dag.CacheVolume(m.Cache.Virtualenv), dagger.ContainerWithMountedCacheOpts{
+			Sharing: dagger.Locked,
+		}).
+		WithEnvVariable("POETRY_VIRTUALENVS_CREATE", "true").
+		WithEnvVariable("POETRY_VIRTUALENVS_PATH", virtualenvsPath)
+}
@@ -0,0 +1,75 @@
+package main
+
+// DependencyConfig selects the dependency groups and extras that Install,
+// Build and Test install
+type DependencyConfig struct {
+	// Optional groups to install, e.g. docs
+	With []string
+	// Groups to skip, e.g. dev
+	Without []string
+	// Only install these groups, e.g. main
+	Only []string
+	// Extras to install
+	Extras []string
+	// Skip installing the project itself
+	NoRoot bool
+	// Remove packages not in the lock file from the environment
+	Sync bool
+}
+
+// WithDependencies selects what poetry install installs, e.g. skip the dev
+// group when building production wheels
+func (m *Poetry) WithDependencies(
+	// Optional groups to install, as in --with
+	// +optional
+	with []string,
+	// Groups to skip, as in --without
+	// +optional
+	without []string,
+	// Only install these groups, as in --only; main for runtime dependencies only
+	// +optional
+	only []string,
+	// Extras to install, as in --extras
+	// +optional
+	extras []string,
+	// Skip installing the project itself, as in --no-root
+	// +optional
+	noRoot bool,
+	// Remove packages not in the lock file, as in --sync
+	// +optional
+	sync bool,
+) *Poetry {
+	m.Dependencies = DependencyConfig{
+		With:    with,
+		Without: without,
+		Only:    only,
+		Extras:  extras,
+		NoRoot:  noRoot,
+		Sync:    sync,
+	}
+	return m
+}
+
+// installArgs returns the poetry install command for the selected dependencies
+func (m *Poetry) installArgs() []string {
+	args := []string{"poetry", "install", "--no-interaction"}
+	for _, group := range m.Dependencies.With {
+		args = append(args, "--with", group)
+	}
+	for _, group := range m.Dependencies.Without {
+		args = append(args, "--without", group)
+	}
+	for _, group := range m.Dependencies.Only {
+		args = append(args, "--only", group)
+	}
+	for _, extra := range m.Dependencies.Extras {
+		args = append(args, "--extras", extra)
+	}
+	if m.Dependencies.NoRoot {
+		args = append(args, "--no-root")
+	}
+	if m.Dependencies.Sync {
+		args = append(args, "--sync")
+	}
+	return args
+}
@@ -13,6 +13,22 @@ type Poetry struct {
 	// Base image for Poetry operations
 	// +private
 	BaseImage string
+
+	// Cache volumes mounted by Poetry operations
+	// +private
+	Cache CacheConfig
+
+	// Private package sources and their credentials
+	// +private
+	Sources []Source
+
+	// Extra CA certificate trusted for sources
+	// +private
+	CACert *dagger.File
+
+	// Dependency groups and extras installed by Install, Build and Test
+	// +private
+	Dependencies DependencyConfig
 }
 
 // New creates a new instance of Poetry with the provided configuration.
@@ -28,23 +44,29 @@ func New(
 
 	return &Poetry{
 		BaseImage: baseImage,
+		Cache: CacheConfig{
+			Pip:    defaultPipCache,
+			Poetry: defaultPoetryCache,
+		},
 	}
 }
 
-// getBaseContainer returns a configured base container with Poetry installed
+// getBaseContainer returns a configured base container with Poetry installed,
+// the cache volumes mounted and the private sources set up.
 func (m *Poetry) getBaseContainer(source *dagger.Directory) *dagger.Container {
-	return dag.Container().
-		From(m.BaseImage).
+	container := m.withCache(dag.Container().From(m.BaseImage))
+	container = m.withSourceAuth(m.withCACert(container))
+	container = pipInstall(container, "poetry").
 		WithDirectory("/src", source).
-		WithWorkdir("/src").
-		WithExec([]string{"pip", "install", "--no-cache-dir", "poetry"})
+		WithWorkdir("/src")
+
+	return m.withSources(container)
 }
 
 // Install installs project dependencies using Poetry.
 func (m *Poetry) Install(source *dagger.Directory) *dagger.Directory {
 	container := m.getBaseContainer(source).
-		WithExec([]string{"poetry", "config", "virtualenvs.create", "false"}).
-		WithExec([]string{"poetry", "install", "--no-interaction"})
+		WithExec(m.installArgs())
 
 	return container.Directory("/src")
 }
@@ -52,8 +74,7 @@ func (m *Poetry) Install(source *dagger.Directory) *dagger.Directory {
 // Build builds the Python package using Poetry.
 func (m *Poetry) Build(source *dagger.Directory) *dagger.Directory {
 	container := m.getBaseContainer(source).
-		WithExec([]string{"poetry", "config", "virtualenvs.create", "false"}).
-		WithExec([]string{"poetry", "install", "--no-interaction"}).
+		WithExec(m.installArgs()).
 		WithExec([]string{"poetry", "build"})
 
 	return container.Directory("/src/dist")
@@ -62,9 +83,8 @@ func (m *Poetry) Build(source *dagger.Directory) *dagger.Directory {
 // BuildWithVersion builds the Python package using Poetry with a specific version.
 func (m *Poetry) BuildWithVersion(source *dagger.Directory, version string) *dagger.Directory {
 	container := m.getBaseContainer(source).
-		WithExec([]string{"poetry", "config", "virtualenvs.create", "false"}).
 		WithExec([]string{"poetry", "version", version}).
-		WithExec([]string{"poetry", "install", "--no-interaction"}).
+		WithExec(m.installArgs()).
 		WithExec([]string{"poetry", "build"})
 
 	return container.Directory("/src/dist")
@@ -73,8 +93,7 @@ func (m *Poetry) BuildWithVersion(source *dagger.Directory, version string) *dag
 // Test runs tests using Poetry.
 func (m *Poetry) Test(ctx context.Context, source *dagger.Directory) (string, error) {
 	container := m.getBaseContainer(source).
-		WithExec([]string{"poetry", "config", "virtualenvs.create", "false"}).
-		WithExec([]string{"poetry", "install", "--no-interaction"})
+		WithExec(m.installArgs())
 
 	output, err := container.WithExec([]string{"poetry", "run", "pytest"}).Stdout(ctx)
 	if err != nil {
@@ -98,4 +117,53 @@ func (m *Poetry) Update(source *dagger.Directory) *dagger.Directory {
 		WithExec([]string{"poetry", "update"})
 
 	return container.Directory("/src")
-}
\ No newline at end of file
+}
+
+// Export writes the locked dependencies in pip format with poetry export, for
+// Docker builds and security scanners that consume requirements files.
+func (m *Poetry) Export(
+	source *dagger.Directory,
+	// Output format: requirements.txt or constraints.txt
+	// +optional
+	// +default="requirements.txt"
+	format string,
+	// Leave out package hashes
+	// +optional
+	withoutHashes bool,
+	// Optional dependency groups to include, e.g. dev
+	// +optional
+	groups []string,
+	// Only include these groups, e.g. main for runtime dependencies only
+	// +optional
+	only []string,
+	// Extras to include
+	// +optional
+	extras []string,
+) (*dagger.File, error) {
+	if format == "" {
+		format = "requirements.txt"
+	}
+	if format != "requirements.txt" && format != "constraints.txt" {
+		return nil, fmt.Errorf("unsupported export format %q, expected requirements.txt or constraints.txt", format)
+	}
+
+	output := "/tmp/" + format
+	args := []string{"poetry", "export", "--format", format, "--output", output}
+	if withoutHashes {
+		args = append(args, "--without-hashes")
+	}
+	for _, group := range groups {
+		args = append(args, "--with", group)
+	}
+	for _, group := range only {
+		args = append(args, "--only", group)
+	}
+	for _, extra := range extras {
+		args = append(args, "--extras", extra)
+	}
+
+	container := pipInstall(m.getBaseContainer(source), "poetry-plugin-export").
+		WithExec(args)
+
+	return container.File(output), nil
+}
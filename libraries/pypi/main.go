@@ -4,10 +4,14 @@ package main
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/felipepimentel/daggerverse/libraries/pypi/internal/dagger"
 )
 
+// distDir is where the distributions are mounted for upload
+const distDir = "/dist"
+
 // Pypi handles publishing Python packages to PyPI.
 type Pypi struct {
 	// Base image for PyPI operations
@@ -31,25 +35,104 @@ func New(
 	}
 }
 
-// Publish publishes a Python package to PyPI.
-// It expects the source directory to contain the built package (dist directory).
-func (m *Pypi) Publish(ctx context.Context, source *dagger.Directory, token *dagger.Secret) error {
-	// Get the parent directory that contains pyproject.toml
-	parentDir := source.Directory("..")
+// Publish uploads built distributions (wheels and sdists) with twine.
+// When running in GitHub Actions, pass the OIDC request URL and token to use
+// trusted publishing instead of a long-lived API token.
+func (m *Pypi) Publish(
+	ctx context.Context,
+	// Directory with the distributions, e.g. the dist directory of poetry build
+	dist *dagger.Directory,
+	// API token (not needed with trusted publishing)
+	// +optional
+	token *dagger.Secret,
+	// Index to upload to: pypi or testpypi
+	// +optional
+	// +default="pypi"
+	repository string,
+	// Upload URL of another index, e.g. a private registry (token auth only)
+	// +optional
+	repositoryUrl string,
+	// Skip distributions already on the index instead of failing
+	// +optional
+	skipExisting bool,
+	// Skip validating the distributions with twine check --strict before uploading
+	// +optional
+	skipCheck bool,
+	// OIDC token request URL ($ACTIONS_ID_TOKEN_REQUEST_URL) to enable trusted publishing
+	// +optional
+	oidcRequestUrl string,
+	// OIDC token request bearer ($ACTIONS_ID_TOKEN_REQUEST_TOKEN) to enable trusted publishing
+	// +optional
+	oidcRequestToken *dagger.Secret,
+	// Sign and upload PEP 740 attestations (requires trusted publishing)
+	// +optional
+	attestations bool,
+) error {
+	if repository == "" {
+		repository = "pypi"
+	}
+	index, ok := indexes[repository]
+	if !ok {
+		return fmt.Errorf("unsupported repository %q, expected pypi or testpypi", repository)
+	}
+	if repositoryUrl != "" {
+		index = packageIndex{UploadURL: repositoryUrl}
+	}
+
+	trusted := oidcRequestUrl != "" && oidcRequestToken != nil
+	if attestations && !trusted {
+		return fmt.Errorf("attestations require trusted publishing")
+	}
+	if trusted && index.MintTokenURL == "" {
+		return fmt.Errorf("trusted publishing is only supported for pypi and testpypi")
+	}
+	if !trusted && token == nil {
+		return fmt.Errorf("a token is required when trusted publishing is not configured")
+	}
 
 	container := dag.Container().
 		From(m.BaseImage).
-		WithDirectory("/src", parentDir).
-		WithWorkdir("/src").
-		WithExec([]string{"pip", "install", "--no-cache-dir", "poetry"}).
-		WithSecretVariable("POETRY_PYPI_TOKEN_PYPI", token).
-		WithExec([]string{"ls", "-la"}) // Debug: list files
-
-	// Publish package
-	_, err := container.WithExec([]string{"poetry", "publish"}).Stdout(ctx)
+		WithExec([]string{"pip", "install", "--no-cache-dir", "twine", "pypi-attestations"}).
+		WithMountedDirectory(distDir, dist).
+		WithWorkdir(distDir).
+		WithEnvVariable("TWINE_USERNAME", "__token__")
+
+	if !skipCheck {
+		fmt.Println("🔍 Checking distributions...")
+		container = container.WithExec([]string{"sh", "-c", "twine check --strict " + distDir + "/*"})
+	}
+
+	args := []string{"--repository-url", index.UploadURL}
+	if skipExisting {
+		args = append(args, "--skip-existing")
+	}
+
+	if trusted {
+		fmt.Println("🔐 Using trusted publishing")
+		container = container.
+			WithEnvVariable("ACTIONS_ID_TOKEN_REQUEST_URL", oidcRequestUrl).
+			WithSecretVariable("ACTIONS_ID_TOKEN_REQUEST_TOKEN", oidcRequestToken).
+			WithEnvVariable("PYPI_AUDIENCE", index.Audience).
+			WithEnvVariable("PYPI_MINT_TOKEN_URL", index.MintTokenURL).
+			WithNewFile(mintTokenPath, mintTokenScript)
+		if attestations {
+			fmt.Println("🖋️  Signing distributions with PEP 740 attestations")
+			container = container.WithExec([]string{"sh", "-c", "pypi-attestations sign " + distDir + "/*"})
+			args = append(args, "--attestations")
+		}
+	} else {
+		container = container.WithSecretVariable("TWINE_PASSWORD", token)
+	}
+
+	fmt.Printf("📤 Uploading distributions to %s...\n", index.UploadURL)
+	_, err := container.
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)).
+		WithExec(append([]string{"sh", "-c", uploadScript, "sh"}, args...)).
+		Sync(ctx)
 	if err != nil {
-		return fmt.Errorf("error publishing package: %v", err)
+		return fmt.Errorf("error publishing package: %w", err)
 	}
 
+	fmt.Println("✅ Package published")
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,60 @@
+package main
+
+// mintTokenPath is where the token exchange script is written
+const mintTokenPath = "/tmp/mint_token.py"
+
+// packageIndex describes where distributions are uploaded and how trusted
+// publishing tokens are minted for it
+type packageIndex struct {
+	UploadURL    string
+	MintTokenURL string
+	Audience     string
+}
+
+// indexes are the indexes supporting trusted publishing
+var indexes = map[string]packageIndex{
+	"pypi": {
+		UploadURL:    "https://upload.pypi.org/legacy/",
+		MintTokenURL: "https://pypi.org/_/oidc/mint-token",
+		Audience:     "pypi",
+	},
+	"testpypi": {
+		UploadURL:    "https://test.pypi.org/legacy/",
+		MintTokenURL: "https://test.pypi.org/_/oidc/mint-token",
+		Audience:     "testpypi",
+	},
+}
+
+// mintTokenScript requests an OIDC token from GitHub Actions and exchanges it
+// for a short-lived API token, written to /tmp/pypi-token.
+const mintTokenScript = `
+import json, os, urllib.request
+
+req = urllib.request.Request(
+    os.environ["ACTIONS_ID_TOKEN_REQUEST_URL"] + "&audience=" + os.environ["PYPI_AUDIENCE"],
+    headers={"Authorization": "bearer " + os.environ["ACTIONS_ID_TOKEN_REQUEST_TOKEN"]},
+)
+oidc = json.load(urllib.request.urlopen(req))["value"]
+
+req = urllib.request.Request(
+    os.environ["PYPI_MINT_TOKEN_URL"],
+    data=json.dumps({"token": oidc}).encode(),
+    headers={"Content-Type": "application/json"},
+)
+token = json.load(urllib.request.urlopen(req))["token"]
+
+with open("/tmp/pypi-token", "w") as f:
+    f.write(token)
+`
+
+// uploadScript uploads the distributions with twine, first exchanging the
+// OIDC token when trusted publishing is set up. The minted token only lives
+// for the duration of the upload.
+const uploadScript = `set -eu
+if [ -n "${PYPI_MINT_TOKEN_URL:-}" ]; then
+  python ` + mintTokenPath + `
+  TWINE_PASSWORD="$(cat /tmp/pypi-token)"
+  rm -f /tmp/pypi-token
+  export TWINE_PASSWORD
+fi
+twine upload --non-interactive "$@" ` + distDir + `/*`
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/libraries/site-publish/internal/dagger"
+)
+
+const (
+	lycheeImage  = "lycheeverse/lychee:0.18.0"
+	htmlValidate = "html-validate@8"
+	reportPath   = "/report"
+)
+
+// Check checks the links of the site with lychee and validates its HTML with
+// html-validate, so broken links fail CI before the site is published. It
+// returns a directory with the JSON reports of both tools, links.json and
+// html.json.
+func (m *SitePublish) Check(
+	ctx context.Context,
+	// Only check links within the site, e.g. when the network is unavailable
+	// +optional
+	offline bool,
+	// Regular expressions of URLs not to check, e.g. ^https://example\.com
+	// +optional
+	exclude []string,
+	// Skip HTML validation
+	// +optional
+	skipHtml bool,
+	// Return an error when links are broken or the HTML is invalid; otherwise
+	// only the reports tell
+	// +optional
+	// +default=true
+	failOnError bool,
+) (*dagger.Directory, error) {
+	args := []string{
+		"lychee",
+		"--no-progress",
+		"--root-dir", sitePath,
+		"--format", "json",
+		"--output", reportPath + "/links.json",
+	}
+	if offline {
+		args = append(args, "--offline")
+	}
+	for _, pattern := range exclude {
+		args = append(args, "--exclude", pattern)
+	}
+	args = append(args, sitePath+"/**/*.html")
+
+	fmt.Println("🔗 Checking links...")
+	links := dag.Container().
+		From(lycheeImage).
+		WithDirectory(sitePath, m.Site).
+		WithExec([]string{"mkdir", "-p", reportPath}).
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)).
+		WithExec(args, dagger.ContainerWithExecOpts{Expect: dagger.ReturnTypeAny})
+
+	var failures []string
+	if failure, err := checkFailure(ctx, links, "broken links"); err != nil {
+		return nil, err
+	} else if failure != "" {
+		failures = append(failures, failure)
+	}
+	report := dag.Directory().WithFile("links.json", links.File(reportPath+"/links.json"))
+
+	if !skipHtml {
+		fmt.Println("🧾 Validating HTML...")
+		html := dag.Container().
+			From(nodeImage).
+			WithExec([]string{"npm", "install", "--global", "--no-fund", "--no-audit", htmlValidate}).
+			WithDirectory(sitePath, m.Site).
+			WithExec([]string{"mkdir", "-p", reportPath}).
+			WithExec([]string{
+				"html-validate",
+				"--preset", "standard",
+				"--formatter", "stylish,json=" + reportPath + "/html.json",
+				sitePath + "/**/*.html",
+			}, dagger.ContainerWithExecOpts{Expect: dagger.ReturnTypeAny})
+
+		if failure, err := checkFailure(ctx, html, "invalid HTML"); err != nil {
+			return nil, err
+		} else if failure != "" {
+			failures = append(failures, failure)
+		}
+		report = report.WithFile("html.json", html.File(reportPath+"/html.json"))
+	}
+
+	if len(failures) > 0 && failOnError {
+		return nil, fmt.Errorf("site check failed:\n%s", strings.Join(failures, "\n"))
+	}
+
+	fmt.Println("✅ Site checked")
+	return report, nil
+}
+
+// checkFailure describes the failure of a check run with ReturnTypeAny, or
+// returns an empty string when it passed
+func checkFailure(ctx context.Context, ctr *dagger.Container, problem string) (string, error) {
+	exitCode, err := ctr.ExitCode(ctx)
+	if err != nil {
+		return "", err
+	}
+	if exitCode == 0 {
+		return "", nil
+	}
+
+	stdout, _ := ctr.Stdout(ctx)
+	stderr, _ := ctr.Stderr(ctx)
+	return fmt.Sprintf("%s (exit code %d):\n%s", problem, exitCode, strings.TrimSpace(stdout+"\n"+stderr)), nil
+}
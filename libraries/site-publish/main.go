@@ -0,0 +1,288 @@
+// A module to publish built static sites to GitHub Pages, S3 compatible
+// storage (including DigitalOcean Spaces), Netlify, or a Caddy image
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/libraries/site-publish/internal/dagger"
+)
+
+const (
+	awsCliImage    = "amazon/aws-cli:2.15.0"
+	caddyImage     = "caddy:2.8.4"
+	nodeImage      = "node:20-alpine"
+	netlifyCli     = "netlify-cli@17"
+	sitePath       = "/site"
+	defaultPort    = 8080
+	ghPagesDir     = "/publish"
+	defaultMessage = "Publish site"
+)
+
+// credentialHelper makes git read the token from the environment at runtime,
+// so it never ends up in a remote URL, the git config or the layer cache
+const credentialHelper = `!f() { echo username=x-access-token; echo "password=${GITHUB_TOKEN}"; }; f`
+
+// SitePublish publishes a built static site
+type SitePublish struct {
+	// +private
+	Site *dagger.Directory
+}
+
+func New(
+	// Directory with the built site, e.g. the output of mkdocs or docusaurus
+	site *dagger.Directory,
+) *SitePublish {
+	return &SitePublish{Site: site}
+}
+
+// Publish pushes the site to a destination and returns its public URL.
+//
+// Supported destinations:
+//   - gh-pages://<owner>/<repo>[?branch=gh-pages&cname=docs.example.com] (token: GitHub token)
+//   - s3://<bucket>[/<prefix>] (accessKey and secretKey; endpoint for Spaces or other S3 compatible storage)
+//   - netlify://<site-id> (token: Netlify personal access token)
+func (m *SitePublish) Publish(
+	ctx context.Context,
+	destination string,
+	// GitHub or Netlify token
+	// +optional
+	token *dagger.Secret,
+	// S3 access key ID
+	// +optional
+	accessKey *dagger.Secret,
+	// S3 secret access key
+	// +optional
+	secretKey *dagger.Secret,
+	// S3 endpoint URL, e.g. https://nyc3.digitaloceanspaces.com
+	// +optional
+	endpoint string,
+	// +optional
+	// +default="us-east-1"
+	region string,
+	// Commit or deploy message
+	// +optional
+	// +default="Publish site"
+	message string,
+	// Cache-Control header of the published files, e.g. public, max-age=3600
+	// (S3 and Netlify; GitHub Pages sets its own)
+	// +optional
+	cacheControl string,
+	// Show what would be published without publishing: S3 lists the changes,
+	// GitHub Pages commits without pushing and Netlify makes a draft deploy
+	// +optional
+	dryRun bool,
+) (string, error) {
+	target, err := url.Parse(destination)
+	if err != nil {
+		return "", fmt.Errorf("invalid destination %q: %w", destination, err)
+	}
+	if target.Host == "" {
+		return "", fmt.Errorf("invalid destination %q: missing repository owner, bucket or site", destination)
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	if message == "" {
+		message = defaultMessage
+	}
+
+	switch target.Scheme {
+	case "gh-pages":
+		if token == nil {
+			return "", fmt.Errorf("a GitHub token is required to publish to %s", destination)
+		}
+		return m.ghPages(ctx, target, token, message, dryRun)
+	case "s3":
+		if accessKey == nil || secretKey == nil {
+			return "", fmt.Errorf("access and secret keys are required to publish to %s", destination)
+		}
+		return m.s3(ctx, target, accessKey, secretKey, endpoint, region, cacheControl, dryRun)
+	case "netlify":
+		if token == nil {
+			return "", fmt.Errorf("a Netlify token is required to publish to %s", destination)
+		}
+		return m.netlify(ctx, target.Host, token, message, cacheControl, dryRun)
+	default:
+		return "", fmt.Errorf("unsupported destination scheme %q, expected gh-pages, s3 or netlify", target.Scheme)
+	}
+}
+
+// Image returns a Caddy container serving the site, ready to be published
+func (m *SitePublish) Image(
+	// +optional
+	// +default=8080
+	port int,
+) *dagger.Container {
+	if port <= 0 {
+		port = defaultPort
+	}
+
+	return dag.Container().
+		From(caddyImage).
+		WithDirectory("/srv", m.Site).
+		WithExposedPort(port).
+		WithDefaultArgs([]string{"caddy", "file-server", "--root", "/srv", "--listen", fmt.Sprintf(":%d", port)})
+}
+
+// ghPages commits the site to a branch of a GitHub repository, replacing its
+// previous contents; with dryRun the commit is made but not pushed
+func (m *SitePublish) ghPages(ctx context.Context, target *url.URL, token *dagger.Secret, message string, dryRun bool) (string, error) {
+	owner := target.Host
+	repo := strings.Trim(target.Path, "/")
+	if repo == "" || strings.Contains(repo, "/") {
+		return "", fmt.Errorf("invalid GitHub Pages destination %q, expected gh-pages://<owner>/<repo>", target)
+	}
+
+	branch := target.Query().Get("branch")
+	if branch == "" {
+		branch = "gh-pages"
+	}
+
+	script := `set -eu
+remote="https://github.com/${REPOSITORY}.git"
+if git ls-remote --exit-code --heads "$remote" "$BRANCH" >/dev/null; then
+  git clone --quiet --depth 1 --branch "$BRANCH" "$remote" ` + ghPagesDir + `
+else
+  git init --quiet -b "$BRANCH" ` + ghPagesDir + `
+  git -C ` + ghPagesDir + ` remote add origin "$remote"
+fi
+cd ` + ghPagesDir + `
+git rm -rq --ignore-unmatch .
+cp -a ` + sitePath + `/. .
+touch .nojekyll
+if [ -n "$CNAME" ]; then echo "$CNAME" > CNAME; fi
+git add -A
+if git diff --cached --quiet; then
+  echo "No changes to publish"
+  exit 0
+fi
+git commit --quiet -m "$MESSAGE"
+if [ "$DRY_RUN" = true ]; then
+  git show --stat --format="Dry run, not pushing: %s" HEAD | tail -n 1
+  exit 0
+fi
+git push --quiet origin "HEAD:$BRANCH"`
+
+	fmt.Printf("📤 Publishing site to %s/%s@%s...\n", owner, repo, branch)
+	_, err := dag.Container().
+		From("alpine:3").
+		WithExec([]string{"apk", "add", "--no-cache", "git"}).
+		WithExec([]string{"git", "config", "--global", "user.name", "github-actions[bot]"}).
+		WithExec([]string{"git", "config", "--global", "user.email", "github-actions[bot]@users.noreply.github.com"}).
+		WithExec([]string{"git", "config", "--global", "credential.helper", credentialHelper}).
+		WithDirectory(sitePath, m.Site).
+		WithEnvVariable("REPOSITORY", owner+"/"+repo).
+		WithEnvVariable("BRANCH", branch).
+		WithEnvVariable("CNAME", target.Query().Get("cname")).
+		WithEnvVariable("MESSAGE", message).
+		WithEnvVariable("DRY_RUN", fmt.Sprint(dryRun)).
+		WithSecretVariable("GITHUB_TOKEN", token).
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)).
+		WithExec([]string{"sh", "-c", script}).
+		Sync(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to publish to GitHub Pages: %w", err)
+	}
+
+	if cname := target.Query().Get("cname"); cname != "" {
+		return fmt.Sprintf("https://%s/", cname), nil
+	}
+	return fmt.Sprintf("https://%s.github.io/%s/", owner, repo), nil
+}
+
+// s3 syncs the site to a bucket, deleting objects that are no longer part of it
+func (m *SitePublish) s3(ctx context.Context, target *url.URL, accessKey, secretKey *dagger.Secret, endpoint, region, cacheControl string, dryRun bool) (string, error) {
+	bucket := target.Host
+	prefix := strings.Trim(target.Path, "/")
+	destination := "s3://" + bucket
+	if prefix != "" {
+		destination += "/" + prefix
+	}
+
+	args := []string{"aws", "s3", "sync", sitePath, destination, "--delete"}
+	if cacheControl != "" {
+		args = append(args, "--cache-control", cacheControl)
+	}
+	if dryRun {
+		args = append(args, "--dryrun")
+	}
+	host := fmt.Sprintf("%s.s3.%s.amazonaws.com", bucket, region)
+	if endpoint != "" {
+		endpointURL, err := url.Parse(endpoint)
+		if err != nil || endpointURL.Host == "" {
+			return "", fmt.Errorf("invalid endpoint %q", endpoint)
+		}
+		args = append(args, "--endpoint-url", endpoint)
+		host = fmt.Sprintf("%s.%s", bucket, endpointURL.Host)
+	}
+
+	fmt.Printf("📤 Publishing site to %s...\n", destination)
+	_, err := dag.Container().
+		From(awsCliImage).
+		WithoutEntrypoint().
+		WithDirectory(sitePath, m.Site).
+		WithSecretVariable("AWS_ACCESS_KEY_ID", accessKey).
+		WithSecretVariable("AWS_SECRET_ACCESS_KEY", secretKey).
+		WithEnvVariable("AWS_DEFAULT_REGION", region).
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)).
+		WithExec(args).
+		Sync(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to publish to %s: %w", destination, err)
+	}
+
+	return fmt.Sprintf("https://%s/%s", host, prefix), nil
+}
+
+// netlify deploys the site to production on Netlify, or as a draft with dryRun
+func (m *SitePublish) netlify(ctx context.Context, siteID string, token *dagger.Secret, message, cacheControl string, dryRun bool) (string, error) {
+	args := []string{
+		"netlify", "deploy",
+		"--dir", sitePath,
+		"--site", siteID,
+		"--message", message,
+		"--json",
+	}
+	if !dryRun {
+		args = append(args, "--prod")
+	}
+
+	container := dag.Container().
+		From(nodeImage).
+		WithExec([]string{"npm", "install", "--global", "--no-fund", "--no-audit", netlifyCli}).
+		WithDirectory(sitePath, m.Site)
+	if cacheControl != "" {
+		// Netlify reads custom headers from the _headers file of the site
+		container = container.WithExec([]string{
+			"sh", "-c", `printf '/*\n  Cache-Control: %s\n' "$1" >> "$2/_headers"`, "sh", cacheControl, sitePath,
+		})
+	}
+
+	fmt.Printf("📤 Publishing site to Netlify site %s...\n", siteID)
+	output, err := container.
+		WithSecretVariable("NETLIFY_AUTH_TOKEN", token).
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)).
+		WithExec(args).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to publish to Netlify: %w", err)
+	}
+
+	var deploy struct {
+		URL       string `json:"url"`
+		DeployURL string `json:"deploy_url"`
+	}
+	if err := json.Unmarshal([]byte(output), &deploy); err != nil {
+		return "", fmt.Errorf("failed to parse Netlify deploy output: %w", err)
+	}
+	if deploy.URL != "" && !dryRun {
+		return deploy.URL, nil
+	}
+	return deploy.DeployURL, nil
+}
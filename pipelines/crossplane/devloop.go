@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/pipelines/crossplane/internal/dagger"
+)
+
+const (
+	registryImage = "registry:2"
+	k3sImage      = "rancher/k3s:v1.31.4-k3s1"
+	kubectlImage  = "alpine/k8s:1.31.4"
+
+	// registryHost is the hostname the registry service is bound to
+	registryHost = "registry:5000"
+	// kubeconfigDir is shared between the k3s service and the kubectl container
+	kubeconfigDir = "/kubeconfig"
+)
+
+// registriesConfig lets k3s pull from the local registry over plain HTTP
+const registriesConfig = `mirrors:
+  "` + registryHost + `":
+    endpoint:
+      - "http://` + registryHost + `"
+`
+
+// installScript waits for the cluster, installs Crossplane and the package,
+// and waits for the package to become healthy
+const installScript = `set -eu
+until [ -s ` + kubeconfigDir + `/k3s.yaml ]; do sleep 1; done
+sed 's/127.0.0.1/k3s/' ` + kubeconfigDir + `/k3s.yaml > /root/.kube/config
+until kubectl get nodes >/dev/null 2>&1; do sleep 2; done
+kubectl wait --for=condition=Ready nodes --all --timeout=300s
+helm repo add crossplane-stable https://charts.crossplane.io/stable
+helm upgrade --install crossplane crossplane-stable/crossplane \
+  --namespace crossplane-system --create-namespace \
+  --version "$CROSSPLANE_VERSION" --wait
+cat <<EOF | kubectl apply -f -
+apiVersion: pkg.crossplane.io/v1
+kind: $PACKAGE_KIND
+metadata:
+  name: $PACKAGE_NAME
+spec:
+  package: $PACKAGE
+EOF
+kubectl wait --for=condition=Healthy "$(echo "$PACKAGE_KIND" | tr '[:upper:]' '[:lower:]').pkg.crossplane.io/$PACKAGE_NAME" --timeout=300s
+kubectl get pkg`
+
+// Registry returns a local OCI registry service, reachable as registry:5000
+// from the containers it is bound to
+func (m *Crossplane) Registry() *dagger.Service {
+	return registryService(dag.Container().From(registryImage))
+}
+
+// DevLoop builds the package in src, pushes it to a local registry service
+// and installs it with Crossplane into a throwaway k3s cluster. It returns a
+// kubectl container wired to the cluster for manual testing, e.g.
+// dagger call dev-loop --src . terminal
+func (m *Crossplane) DevLoop(
+	ctx context.Context,
+	src *dagger.Directory,
+	// Package kind: Configuration, Provider or Function
+	// +optional
+	// +default="Configuration"
+	kind string,
+	// Name of the package in the cluster and the registry
+	// +optional
+	// +default="dev-package"
+	name string,
+	// Crossplane Helm chart version
+	// +optional
+	// +default="1.18.0"
+	crossplaneVersion string,
+) (*dagger.Container, error) {
+	if kind == "" {
+		kind = "Configuration"
+	}
+	if name == "" {
+		name = "dev-package"
+	}
+	if crossplaneVersion == "" {
+		crossplaneVersion = "1.18.0"
+	}
+	switch kind {
+	case "Configuration", "Provider", "Function":
+	default:
+		return nil, fmt.Errorf("unsupported package kind %q, expected Configuration, Provider or Function", kind)
+	}
+
+	built := m.Package(ctx, src)
+	packages, err := built.Glob(ctx, "*.xpkg")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the built package: %w", err)
+	}
+	if len(packages) != 1 {
+		return nil, fmt.Errorf("expected one .xpkg in the package directory, found %d", len(packages))
+	}
+
+	// Services stop when no container uses them; the volumes unique to this
+	// run keep the registry contents and the kubeconfig between the steps
+	run := fmt.Sprintf("crossplane-devloop-%d", time.Now().UnixNano())
+	registry := registryService(dag.Container().
+		From(registryImage).
+		WithMountedCache("/var/lib/registry", dag.CacheVolume(run+"-registry")))
+	ref := fmt.Sprintf("%s/%s:dev", registryHost, name)
+
+	fmt.Printf("📦 Pushing %s to %s\n", packages[0], ref)
+	_, err = dag.Container().
+		From("gcr.io/go-containerregistry/crane:debug").
+		WithServiceBinding("registry", registry).
+		WithMountedFile("/package.xpkg", built.File(packages[0])).
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)).
+		WithExec([]string{"crane", "push", "--insecure", "/package.xpkg", ref}).
+		Sync(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to push package to the local registry: %w", err)
+	}
+
+	// k3s writes its kubeconfig to a volume the kubectl container reads once
+	// the cluster is up, and keeps its state in another so the cluster the
+	// returned container is wired to still has the package installed
+	kubeconfig := dag.CacheVolume(run + "-kubeconfig")
+	k3s := dag.Container().
+		From(k3sImage).
+		WithServiceBinding("registry", registry).
+		WithNewFile("/etc/rancher/k3s/registries.yaml", registriesConfig).
+		WithMountedCache(kubeconfigDir, kubeconfig).
+		WithMountedCache("/var/lib/rancher/k3s", dag.CacheVolume(run+"-k3s")).
+		WithExposedPort(6443).
+		WithExec([]string{
+			"k3s", "server",
+			"--disable", "traefik",
+			"--disable", "metrics-server",
+			"--tls-san", "k3s",
+			"--write-kubeconfig", kubeconfigDir + "/k3s.yaml",
+			"--write-kubeconfig-mode", "644",
+		}, dagger.ContainerWithExecOpts{InsecureRootCapabilities: true}).
+		AsService()
+
+	fmt.Printf("🚀 Installing %s %s into a k3s cluster with Crossplane %s\n", kind, name, crossplaneVersion)
+	ctr, err := dag.Container().
+		From(kubectlImage).
+		WithServiceBinding("k3s", k3s).
+		WithMountedCache(kubeconfigDir, kubeconfig).
+		WithExec([]string{"mkdir", "-p", "/root/.kube"}).
+		WithEnvVariable("CROSSPLANE_VERSION", crossplaneVersion).
+		WithEnvVariable("PACKAGE_KIND", kind).
+		WithEnvVariable("PACKAGE_NAME", name).
+		WithEnvVariable("PACKAGE", ref).
+		WithExec([]string{"sh", "-c", installScript}).
+		Sync(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to install %s %s: %w", kind, name, err)
+	}
+
+	fmt.Printf("✅ %s %s is healthy\n", kind, name)
+	return ctr, nil
+}
+
+// registryService runs the registry in ctr as a service
+func registryService(ctr *dagger.Container) *dagger.Service {
+	return ctr.
+		WithExposedPort(5000).
+		AsService()
+}
@@ -0,0 +1,364 @@
+// Package main provides a pipeline for Go projects: build, test, lint,
+// vulnerability scanning and releases with goreleaser.
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/pipelines/go/internal/dagger"
+)
+
+// Tool versions and paths.
+const (
+	// DefaultGoVersion is the default tag of the golang image.
+	DefaultGoVersion = "1.23"
+	// DefaultGolangciLintVersion is the default golangci-lint release.
+	DefaultGolangciLintVersion = "v1.62.2"
+	// DefaultGovulncheckVersion is the default govulncheck release.
+	DefaultGovulncheckVersion = "v1.1.3"
+	// DefaultGoreleaserVersion is the default goreleaser release.
+	DefaultGoreleaserVersion = "v2.5.0"
+
+	// containerWorkdir is the working directory inside the container.
+	containerWorkdir = "/src"
+	// outputDir is where binaries are written.
+	outputDir = "/out"
+	// coverageProfile is where Test writes the coverage profile.
+	coverageProfile = "/tmp/coverage.out"
+)
+
+// Error messages for common failures.
+const (
+	errTest    = "go test failed"
+	errLint    = "golangci-lint failed"
+	errVuln    = "vulnerabilities found"
+	errRelease = "goreleaser failed"
+)
+
+// Go runs the build, test and release workflow of a Go project.
+type Go struct {
+	// Project source
+	// +private
+	Source *dagger.Directory
+
+	// Tag of the golang image
+	// +private
+	Version string
+
+	// Build with cgo
+	// +private
+	Cgo bool
+
+	// Prefix of the module and build cache volumes
+	// +private
+	CacheNamespace string
+}
+
+// New creates a new instance of Go for a project.
+func New(
+	// Project source, with go.mod at its root
+	source *dagger.Directory,
+	// Tag of the golang image, e.g. 1.23 or 1.23-alpine
+	// +optional
+	// +default="1.23"
+	version string,
+	// Build with cgo (required by the race detector, which always enables it)
+	// +optional
+	cgo bool,
+	// Prefix of the cache volumes; set it per project to keep caches apart
+	// +optional
+	// +default="go"
+	cacheNamespace string,
+) *Go {
+	if version == "" {
+		version = DefaultGoVersion
+	}
+	if cacheNamespace == "" {
+		cacheNamespace = "go"
+	}
+
+	return &Go{
+		Source:         source,
+		Version:        version,
+		Cgo:            cgo,
+		CacheNamespace: cacheNamespace,
+	}
+}
+
+// Container returns the golang container with the source mounted and the
+// module and build caches set up.
+func (m *Go) Container() *dagger.Container {
+	return m.withCaches(dag.Container().From("golang:" + m.Version))
+}
+
+// Build compiles the packages and returns the binaries, named after their
+// packages. With platforms, the binaries of each platform are written to an
+// <os>_<arch>/ directory.
+func (m *Go) Build(
+	// Packages to build
+	// +optional
+	// +default=["."]
+	packages []string,
+	// Target platforms, e.g. linux/amd64,darwin/arm64,windows/amd64 (defaults
+	// to the engine's platform)
+	// +optional
+	platforms []dagger.Platform,
+	// Linker flags, e.g. -s -w -X main.version=1.0.0
+	// +optional
+	ldflags string,
+) (*dagger.Directory, error) {
+	if len(packages) == 0 {
+		packages = []string{"."}
+	}
+
+	if len(platforms) == 0 {
+		return m.Container().
+			WithExec(m.buildArgs(outputDir+"/", packages, ldflags)).
+			Directory(outputDir), nil
+	}
+
+	output := dag.Directory()
+	for _, platform := range platforms {
+		goos, goarch, goarm, err := parsePlatform(platform)
+		if err != nil {
+			return nil, err
+		}
+
+		target := goos + "_" + goarch
+		if goarm != "" {
+			target += "_v" + goarm
+		}
+
+		fmt.Printf("🏗️  Building for %s...\n", platform)
+		container := m.Container().
+			WithEnvVariable("GOOS", goos).
+			WithEnvVariable("GOARCH", goarch).
+			WithEnvVariable("GOARM", goarm).
+			WithExec(m.buildArgs(outputDir+"/"+target+"/", packages, ldflags))
+		output = output.WithDirectory(target, container.Directory(outputDir+"/"+target))
+	}
+
+	return output, nil
+}
+
+// Test runs the tests and returns their output followed by the coverage
+// summary.
+func (m *Go) Test(
+	ctx context.Context,
+	// Packages to test
+	// +optional
+	// +default=["./..."]
+	packages []string,
+	// Enable the race detector
+	// +optional
+	// +default=true
+	race bool,
+	// Extra go test arguments, e.g. -run TestFoo or -short
+	// +optional
+	args []string,
+) (string, error) {
+	// Stdout only holds the output of the last exec, so the test output is
+	// read before the coverage summary is added
+	tested := m.test(packages, race, args)
+	output, err := tested.Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", errTest, err)
+	}
+
+	summary, err := tested.
+		WithExec([]string{"go", "tool", "cover", "-func", coverageProfile}).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize coverage: %w", err)
+	}
+
+	fmt.Println("✅ Tests passed")
+	return output + summary, nil
+}
+
+// Coverage runs the tests and returns the coverage profile, e.g. to upload to
+// a coverage service or render with go tool cover -html.
+func (m *Go) Coverage(
+	// Packages to test
+	// +optional
+	// +default=["./..."]
+	packages []string,
+	// Enable the race detector
+	// +optional
+	// +default=true
+	race bool,
+) *dagger.File {
+	return m.test(packages, race, nil).File(coverageProfile)
+}
+
+// Lint runs golangci-lint with the project's configuration.
+func (m *Go) Lint(
+	ctx context.Context,
+	// golangci-lint release
+	// +optional
+	// +default="v1.62.2"
+	version string,
+	// Extra golangci-lint arguments, e.g. --timeout=5m
+	// +optional
+	args []string,
+) (string, error) {
+	if version == "" {
+		version = DefaultGolangciLintVersion
+	}
+
+	output, err := m.withCaches(dag.Container().From("golangci/golangci-lint:"+version)).
+		WithMountedCache("/root/.cache/golangci-lint", dag.Cache(m.CacheNamespace).Volume("golangci-lint")).
+		WithExec(append([]string{"golangci-lint", "run"}, args...)).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", errLint, err)
+	}
+
+	fmt.Println("✅ Lint passed")
+	return output, nil
+}
+
+// Vuln scans the project and its dependencies for known vulnerabilities with
+// govulncheck. The scan is never cached, so new advisories are picked up.
+func (m *Go) Vuln(
+	ctx context.Context,
+	// govulncheck release
+	// +optional
+	// +default="v1.1.3"
+	version string,
+	// Packages to scan
+	// +optional
+	// +default=["./..."]
+	packages []string,
+) (string, error) {
+	if version == "" {
+		version = DefaultGovulncheckVersion
+	}
+	if len(packages) == 0 {
+		packages = []string{"./..."}
+	}
+
+	output, err := m.Container().
+		WithExec([]string{"go", "install", "golang.org/x/vuln/cmd/govulncheck@" + version}).
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)).
+		WithExec(append([]string{"govulncheck"}, packages...)).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", errVuln, err)
+	}
+
+	fmt.Println("✅ No known vulnerabilities")
+	return output, nil
+}
+
+// Release runs goreleaser with the project's .goreleaser.yaml and returns its
+// dist directory. The source must contain the .git directory and, unless
+// snapshot is set, HEAD must be tagged.
+func (m *Go) Release(
+	ctx context.Context,
+	// GitHub token used to publish the release
+	// +optional
+	token *dagger.Secret,
+	// Build a snapshot without publishing
+	// +optional
+	snapshot bool,
+	// goreleaser release
+	// +optional
+	// +default="v2.5.0"
+	version string,
+) (*dagger.Directory, error) {
+	if version == "" {
+		version = DefaultGoreleaserVersion
+	}
+	if token == nil && !snapshot {
+		return nil, fmt.Errorf("%s: a GitHub token is required unless snapshot is set", errRelease)
+	}
+
+	args := []string{"goreleaser", "release", "--clean"}
+	if snapshot {
+		args = append(args, "--snapshot")
+	}
+
+	container := m.withCaches(dag.Container().From("goreleaser/goreleaser:" + version)).
+		WithoutEntrypoint()
+	if token != nil {
+		container = container.WithSecretVariable("GITHUB_TOKEN", token)
+	}
+
+	fmt.Println("📦 Releasing with goreleaser...")
+	container, err := container.
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)).
+		WithExec(args).
+		Sync(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errRelease, err)
+	}
+
+	return container.Directory(containerWorkdir + "/dist"), nil
+}
+
+// withCaches mounts the source and the module and build caches.
+func (m *Go) withCaches(container *dagger.Container) *dagger.Container {
+	cache := dag.Cache(m.CacheNamespace)
+	cgo := "0"
+	if m.Cgo {
+		cgo = "1"
+	}
+
+	return container.
+		WithMountedCache("/go/pkg/mod", cache.Volume("mod")).
+		WithEnvVariable("GOMODCACHE", "/go/pkg/mod").
+		WithMountedCache("/root/.cache/go-build", cache.Volume("build")).
+		WithEnvVariable("GOCACHE", "/root/.cache/go-build").
+		WithEnvVariable("CGO_ENABLED", cgo).
+		WithDirectory(containerWorkdir, m.Source).
+		WithWorkdir(containerWorkdir)
+}
+
+// test returns the container after running the tests with coverage.
+func (m *Go) test(packages []string, race bool, args []string) *dagger.Container {
+	if len(packages) == 0 {
+		packages = []string{"./..."}
+	}
+
+	command := []string{"go", "test", "-coverprofile=" + coverageProfile, "-covermode=atomic"}
+	container := m.Container()
+	if race {
+		// The race detector needs cgo
+		command = append(command, "-race")
+		container = container.WithEnvVariable("CGO_ENABLED", "1")
+	}
+	command = append(command, args...)
+
+	fmt.Println("🧪 Running tests...")
+	return container.WithExec(append(command, packages...))
+}
+
+// buildArgs returns the go build command writing into output.
+func (m *Go) buildArgs(output string, packages []string, ldflags string) []string {
+	args := []string{"go", "build", "-trimpath", "-o", output}
+	if ldflags != "" {
+		args = append(args, "-ldflags", ldflags)
+	}
+	return append(args, packages...)
+}
+
+// parsePlatform splits a platform such as linux/arm/v7 into GOOS, GOARCH and GOARM.
+func parsePlatform(platform dagger.Platform) (string, string, string, error) {
+	parts := strings.Split(string(platform), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return "", "", "", fmt.Errorf("invalid platform %q, expected <os>/<arch>[/<variant>]", platform)
+	}
+
+	goarm := ""
+	if len(parts) == 3 {
+		if parts[1] != "arm" {
+			return "", "", "", fmt.Errorf("unsupported platform variant %q", platform)
+		}
+		goarm = strings.TrimPrefix(parts[2], "v")
+	}
+	return parts[0], parts[1], goarm, nil
+}
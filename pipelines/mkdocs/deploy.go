@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"dagger/mkdocs/internal/dagger"
+)
+
+// credentialHelper makes git read the token from the environment at runtime,
+// so it never ends up in a remote URL, the git config or the layer cache
+const credentialHelper = `!f() { echo username=x-access-token; echo "password=${GITHUB_TOKEN}"; }; f`
+
+// deployScript points origin at the repository, fetches the existing pages
+// branch (mike adds versions to it) and deploys with the given command
+const deployScript = `set -eu
+remote="https://github.com/${REPOSITORY}.git"
+if [ -d .git ]; then
+  git remote set-url origin "$remote" 2>/dev/null || git remote add origin "$remote"
+else
+  git init -q
+  git remote add origin "$remote"
+fi
+git fetch -q origin "+$BRANCH:refs/remotes/origin/$BRANCH" 2>/dev/null \
+  && git branch -f "$BRANCH" "origin/$BRANCH" 2>/dev/null || true
+"$@"`
+
+// strictConfig is the config mike deploys with in strict mode, as mike has no
+// --strict flag; INHERIT resolves relative to it, so it sits next to mkdocs.yml
+const strictConfig = "mkdocs.strict.yml"
+
+// Deploy builds the documentation and pushes it to the GitHub Pages branch of
+// a repository, returning the deployment URL. With a version, the site is
+// deployed with mike as one version of versioned documentation, next to the
+// versions already on the branch.
+func (m *MkDocs) Deploy(
+	ctx context.Context,
+	config *MkDocsConfig,
+	// GitHub repository, e.g. owner/repo
+	repository string,
+	// GitHub token allowed to push to the repository
+	token *dagger.Secret,
+	// Branch GitHub Pages serves
+	// +optional
+	// +default="gh-pages"
+	branch string,
+	// Version to deploy with mike, e.g. 1.2 (deploys with gh-deploy when empty)
+	// +optional
+	version string,
+	// Aliases of the version, e.g. latest
+	// +optional
+	aliases []string,
+	// Make the version the one served at the root of the site
+	// +optional
+	setDefault bool,
+) (string, error) {
+	if config == nil || config.Source == nil {
+		return "", fmt.Errorf("source directory is required")
+	}
+	owner, repo, ok := strings.Cut(repository, "/")
+	if !ok || owner == "" || repo == "" {
+		return "", fmt.Errorf("invalid repository %q, expected owner/repo", repository)
+	}
+	if token == nil {
+		return "", fmt.Errorf("a GitHub token is required to deploy")
+	}
+	if branch == "" {
+		branch = "gh-pages"
+	}
+
+	command := []string{"mkdocs", "gh-deploy", "--force", "--remote-branch", branch}
+	if config.Strict {
+		command = append(command, "--strict")
+	}
+	container := m.withSource(m.Container(), config)
+	if version != "" {
+		command = []string{"mike", "deploy", "--push", "--branch", branch, "--update-aliases"}
+		if config.Strict {
+			container = container.WithNewFile("/src/"+strictConfig, "INHERIT: mkdocs.yml\nstrict: true\n")
+			command = append(command, "--config-file", strictConfig)
+		}
+		command = append(append(command, version), aliases...)
+	}
+
+	container = container.
+		WithExec([]string{"sh", "-c", "apt-get update && apt-get install -y --no-install-recommends git && rm -rf /var/lib/apt/lists/*"}).
+		WithExec([]string{"pip", "install", "--no-cache-dir", "mike"}).
+		WithExec([]string{"git", "config", "--global", "user.name", "github-actions[bot]"}).
+		WithExec([]string{"git", "config", "--global", "user.email", "github-actions[bot]@users.noreply.github.com"}).
+		WithExec([]string{"git", "config", "--global", "credential.helper", credentialHelper}).
+		WithExec([]string{"git", "config", "--global", "--add", "safe.directory", "*"}).
+		WithSecretVariable("GITHUB_TOKEN", token).
+		WithEnvVariable("REPOSITORY", repository).
+		WithEnvVariable("BRANCH", branch).
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)).
+		WithExec(append([]string{"sh", "-c", deployScript, "sh"}, command...))
+
+	if version != "" && setDefault {
+		container = container.
+			WithExec([]string{"mike", "set-default", "--push", "--branch", branch, version})
+	}
+
+	fmt.Printf("📤 Deploying documentation to %s@%s...\n", repository, branch)
+	if _, err := container.Sync(ctx); err != nil {
+		return "", fmt.Errorf("failed to deploy documentation: %w", err)
+	}
+
+	url := config.BaseURL
+	if url == "" {
+		url = fmt.Sprintf("https://%s.github.io/%s/", owner, repo)
+	}
+	if version != "" {
+		url = strings.TrimSuffix(url, "/") + "/" + version + "/"
+	}
+
+	fmt.Printf("✅ Documentation deployed to %s\n", url)
+	return url, nil
+}
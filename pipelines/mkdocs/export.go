@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"dagger/mkdocs/internal/dagger"
+)
+
+const (
+	// exportDir is where the exports build the site
+	exportDir = "/tmp/export"
+	// pdfPath is where the PDF plugins write the document, within the site
+	pdfPath = "pdf/document.pdf"
+)
+
+// pluginBuildScript builds the site with the plugins of its first argument, a
+// JSON list, added to the ones of mkdocs.yml, and the config overrides of its
+// second argument; MkDocs replaces the plugins of an inherited config as a
+// whole, so they can't be added with INHERIT
+const pluginBuildScript = `import json, sys
+from mkdocs.commands.build import build
+from mkdocs.config import load_config
+from mkdocs.utils import yaml_load
+
+extra, overrides = json.loads(sys.argv[1]), json.loads(sys.argv[2])
+with open("mkdocs.yml", encoding="utf-8") as f:
+    plugins = (yaml_load(f) or {}).get("plugins") or ["search"]
+if isinstance(plugins, dict):
+    plugins = [{name: options} for name, options in plugins.items()]
+overrides["plugins"] = plugins + extra
+config = load_config("mkdocs.yml", **overrides)
+config.plugins.on_startup(command="build", dirty=False)
+try:
+    build(config)
+finally:
+    config.plugins.on_shutdown()
+`
+
+// pdfPlugin is a MkDocs plugin rendering the documentation to a PDF
+type pdfPlugin struct {
+	// Python package of the plugin
+	Package string
+	// Plugin entry of the config, writing the PDF to pdfPath
+	Entry map[string]any
+}
+
+// pdfPlugins are the supported PDF plugins, by name
+var pdfPlugins = map[string]pdfPlugin{
+	"with-pdf": {
+		Package: "mkdocs-with-pdf",
+		Entry:   map[string]any{"with-pdf": map[string]any{"output_path": pdfPath}},
+	},
+	"pdf-export": {
+		Package: "mkdocs-pdf-export-plugin",
+		Entry:   map[string]any{"pdf-export": map[string]any{"combined": true, "combined_output_path": pdfPath}},
+	},
+}
+
+// archiveFormats maps the bundle formats to the shutil.make_archive ones
+var archiveFormats = map[string]string{
+	"tar.gz": "gztar",
+	"zip":    "zip",
+}
+
+// ExportPdf builds the documentation into a single PDF with mkdocs-with-pdf
+// (with-pdf) or mkdocs-pdf-export-plugin (pdf-export)
+func (m *MkDocs) ExportPdf(
+	ctx context.Context,
+	config *MkDocsConfig,
+	// PDF plugin: with-pdf or pdf-export
+	// +optional
+	// +default="with-pdf"
+	plugin string,
+) (*dagger.File, error) {
+	site, err := m.export(ctx, config, true, plugin, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return site.File(pdfPath), nil
+}
+
+// OfflineBundle builds a self-contained copy of the documentation into a
+// single archive for air-gapped distribution: the Material offline plugin
+// makes it browsable from the file system and the privacy plugin downloads
+// the external assets, such as fonts, into it
+func (m *MkDocs) OfflineBundle(
+	ctx context.Context,
+	config *MkDocsConfig,
+	// Archive format: tar.gz or zip
+	// +optional
+	// +default="tar.gz"
+	format string,
+	// Include a PDF of the documentation, at pdf/document.pdf
+	// +optional
+	withPdf bool,
+	// PDF plugin: with-pdf or pdf-export
+	// +optional
+	// +default="with-pdf"
+	pdfPlugin string,
+) (*dagger.File, error) {
+	if format == "" {
+		format = "tar.gz"
+	}
+	archiveFormat, ok := archiveFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported archive format %q, expected tar.gz or zip", format)
+	}
+
+	site, err := m.export(ctx, config, withPdf, pdfPlugin, true)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("📦 Archiving the offline bundle as %s...\n", format)
+	archive := "/tmp/docs." + format
+	bundle := m.Container().
+		WithMountedDirectory("/tmp/site", site).
+		WithExec([]string{
+			"python", "-c", "import shutil, sys; shutil.make_archive(*sys.argv[1:4])",
+			"/tmp/docs", archiveFormat, "/tmp/site",
+		}).
+		File(archive)
+
+	return bundle, nil
+}
+
+// export builds the documentation with the PDF and offline plugins enabled
+func (m *MkDocs) export(ctx context.Context, config *MkDocsConfig, pdf bool, plugin string, offline bool) (*dagger.Directory, error) {
+	if config == nil || config.Source == nil {
+		return nil, fmt.Errorf("source directory is required")
+	}
+
+	container := m.withSource(m.Container(), config)
+	extra := []any{}
+
+	if pdf {
+		if plugin == "" {
+			plugin = "with-pdf"
+		}
+		selected, ok := pdfPlugins[plugin]
+		if !ok {
+			return nil, fmt.Errorf("unsupported PDF plugin %q, expected with-pdf or pdf-export", plugin)
+		}
+
+		// WeasyPrint renders the PDF with Pango
+		container = container.
+			WithExec([]string{"sh", "-c", "apt-get update && apt-get install -y --no-install-recommends libpango-1.0-0 libpangoft2-1.0-0 fonts-dejavu-core && rm -rf /var/lib/apt/lists/*"}).
+			WithExec([]string{"pip", "install", "--no-cache-dir", selected.Package})
+		extra = append(extra, selected.Entry)
+	}
+	if offline {
+		extra = append(extra, "offline", "privacy")
+	}
+
+	overrides := map[string]any{"site_dir": exportDir}
+	if config.Strict {
+		overrides["strict"] = true
+	}
+	if config.BaseURL != "" {
+		overrides["site_url"] = config.BaseURL
+	}
+
+	// Values of these types always encode
+	encodedExtra, _ := json.Marshal(extra)
+	encodedOverrides, _ := json.Marshal(overrides)
+
+	fmt.Println("📚 Building the documentation for export...")
+
+	site := container.
+		WithNewFile("/tmp/build.py", pluginBuildScript).
+		WithExec([]string{"python", "/tmp/build.py", string(encodedExtra), string(encodedOverrides)}).
+		Directory(exportDir)
+	if _, err := site.Sync(ctx); err != nil {
+		return nil, fmt.Errorf("failed to build the documentation: %w", err)
+	}
+
+	return site, nil
+}
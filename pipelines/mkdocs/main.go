@@ -63,18 +63,7 @@ func (m *MkDocs) Build(ctx context.Context, config *MkDocsConfig) (*dagger.Direc
 		return nil, fmt.Errorf("source directory is required")
 	}
 
-	container := m.Container()
-
-	// Mount source directory
-	container = container.WithMountedDirectory("/src", config.Source)
-	container = container.WithWorkdir("/src")
-
-	// Install custom requirements if provided
-	if config.RequirementsFile != nil {
-		container = container.
-			WithMountedFile("/src/requirements.txt", config.RequirementsFile).
-			WithExec([]string{"pip", "install", "--no-cache-dir", "-r", "requirements.txt"})
-	}
+	container := m.withSource(m.Container(), config)
 
 	// Build command
 	buildCmd := []string{"mkdocs", "build"}
@@ -111,9 +100,78 @@ func (m *MkDocs) Serve(config *MkDocsConfig) *dagger.Container {
 	return container.WithExec([]string{"mkdocs", "serve", "--dev-addr", "0.0.0.0:8000"})
 }
 
-// Deploy builds the documentation and returns a container ready to deploy
-func (m *MkDocs) Deploy(ctx context.Context, config *MkDocsConfig) (*dagger.Directory, error) {
-	return m.Build(ctx, config)
+// Publish builds the documentation and publishes it with the site-publish
+// module to gh-pages://, s3:// or netlify:// destinations, returning the public URL
+func (m *MkDocs) Publish(
+	ctx context.Context,
+	config *MkDocsConfig,
+	destination string,
+	// GitHub or Netlify token
+	// +optional
+	token *dagger.Secret,
+	// +optional
+	accessKey *dagger.Secret,
+	// +optional
+	secretKey *dagger.Secret,
+	// S3 endpoint URL, e.g. https://nyc3.digitaloceanspaces.com
+	// +optional
+	endpoint string,
+	// +optional
+	region string,
+	// Cache-Control header of the published files
+	// +optional
+	cacheControl string,
+	// Show what would be published without publishing
+	// +optional
+	dryRun bool,
+) (string, error) {
+	site, err := m.Build(ctx, config)
+	if err != nil {
+		return "", err
+	}
+
+	return dag.SitePublish(site).Publish(ctx, destination, dagger.SitePublishPublishOpts{
+		Token:        token,
+		AccessKey:    accessKey,
+		SecretKey:    secretKey,
+		Endpoint:     endpoint,
+		Region:       region,
+		CacheControl: cacheControl,
+		DryRun:       dryRun,
+	})
+}
+
+// Check builds the documentation, checks its links and validates its HTML
+// with the site-publish module, returning the JSON reports
+func (m *MkDocs) Check(
+	ctx context.Context,
+	config *MkDocsConfig,
+	// Only check links within the site
+	// +optional
+	offline bool,
+	// Regular expressions of URLs not to check
+	// +optional
+	exclude []string,
+) (*dagger.Directory, error) {
+	site, err := m.Build(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return dag.SitePublish(site).Check(ctx, dagger.SitePublishCheckOpts{
+		Offline: offline,
+		Exclude: exclude,
+	})
+}
+
+// Image builds the documentation into a Caddy container serving it
+func (m *MkDocs) Image(ctx context.Context, config *MkDocsConfig) (*dagger.Container, error) {
+	site, err := m.Build(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return dag.SitePublish(site).Image(), nil
 }
 
 // ValidateConfig validates the MkDocs configuration
@@ -131,3 +189,19 @@ func (m *MkDocs) ValidateConfig(ctx context.Context, config *MkDocsConfig) (bool
 
 	return err == nil, err
 }
+
+// withSource mounts the source directory and installs its custom requirements
+func (m *MkDocs) withSource(container *dagger.Container, config *MkDocsConfig) *dagger.Container {
+	// Mount source directory
+	container = container.WithMountedDirectory("/src", config.Source)
+	container = container.WithWorkdir("/src")
+
+	// Install custom requirements if provided
+	if config.RequirementsFile != nil {
+		container = container.
+			WithMountedFile("/src/requirements.txt", config.RequirementsFile).
+			WithExec([]string{"pip", "install", "--no-cache-dir", "-r", "requirements.txt"})
+	}
+
+	return container
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dagger/mkdocs/internal/dagger"
+)
+
+// previewDir is where the diff pages are added to the previewed site
+const previewDir = "_preview"
+
+// diffScript compares the pages of two builds of the site and writes an index
+// of the added, removed and changed pages with a side-by-side diff of the
+// text of each changed page
+const diffScript = `import difflib, html, os, re, sys
+
+base, head, out = sys.argv[1:4]
+
+def pages(root):
+    found = set()
+    for directory, _, files in os.walk(root):
+        for name in files:
+            if name.endswith(".html"):
+                found.add(os.path.relpath(os.path.join(directory, name), root))
+    return found
+
+def text(path):
+    with open(path, encoding="utf-8", errors="replace") as f:
+        content = f.read()
+    content = re.sub(r"(?s)<(script|style|nav|header|footer)\b.*?</\1>", "", content)
+    content = re.sub(r"<[^>]+>", "\n", content)
+    lines = (html.unescape(line).strip() for line in content.splitlines())
+    return [line for line in lines if line]
+
+base_pages, head_pages = pages(base), pages(head)
+added = sorted(head_pages - base_pages)
+removed = sorted(base_pages - head_pages)
+changed = []
+os.makedirs(out, exist_ok=True)
+for page in sorted(base_pages & head_pages):
+    before, after = text(os.path.join(base, page)), text(os.path.join(head, page))
+    if before == after:
+        continue
+    changed.append(page)
+    diff = difflib.HtmlDiff(wrapcolumn=80).make_file(before, after, "base", "head", context=True)
+    target = os.path.join(out, page)
+    os.makedirs(os.path.dirname(target), exist_ok=True)
+    with open(target, "w", encoding="utf-8") as f:
+        f.write(diff)
+
+def section(title, items, link):
+    if not items:
+        return ""
+    rows = "".join("<li>%s</li>" % link(page) for page in items)
+    return "<h2>%s (%d)</h2><ul>%s</ul>" % (title, len(items), rows)
+
+up = "../"
+index = "<!doctype html><meta charset=utf-8><title>Docs preview</title><h1>Docs preview</h1>"
+index += section("Changed", changed, lambda p: '<a href="%s%s">%s</a> (<a href="%s">diff</a>)' % (up, p, p, p))
+index += section("Added", added, lambda p: '<a href="%s%s">%s</a>' % (up, p, p))
+index += section("Removed", removed, lambda p: html.escape(p))
+if not (changed or added or removed):
+    index += "<p>No page changed.</p>"
+with open(os.path.join(out, "index.html"), "w", encoding="utf-8") as f:
+    f.write(index)
+print("%d changed, %d added, %d removed" % (len(changed), len(added), len(removed)))
+`
+
+// Preview builds the site at the head and base refs of a pull request, adds a
+// _preview/ index of the changed pages with their diffs, and publishes the
+// result for review: to ttl.sh as a Caddy image (the default) or to an s3://
+// destination. With a pull request and GitHub token, the link is posted as a
+// comment. Returns the preview URL, or the image reference for ttl.sh.
+func (m *MkDocs) Preview(
+	ctx context.Context,
+	// Configuration of the head build; its source must contain the .git
+	// directory, with the base ref fetched
+	config *MkDocsConfig,
+	// Ref the changes are compared with
+	// +optional
+	// +default="origin/main"
+	baseRef string,
+	// ttl.sh, or s3://<bucket>[/<prefix>] for S3 compatible storage
+	// +optional
+	// +default="ttl.sh"
+	destination string,
+	// How long the ttl.sh image is kept, up to 24h
+	// +optional
+	// +default="24h"
+	ttl string,
+	// S3 access key ID
+	// +optional
+	accessKey *dagger.Secret,
+	// S3 secret access key
+	// +optional
+	secretKey *dagger.Secret,
+	// S3 endpoint URL, e.g. https://nyc3.digitaloceanspaces.com
+	// +optional
+	endpoint string,
+	// +optional
+	region string,
+	// Pull request number or URL to comment on
+	// +optional
+	pullRequest string,
+	// GitHub repository of the pull request, e.g. owner/repo
+	// +optional
+	repository string,
+	// GitHub token to comment on the pull request
+	// +optional
+	token *dagger.Secret,
+) (string, error) {
+	if config == nil || config.Source == nil {
+		return "", fmt.Errorf("source directory is required")
+	}
+	if baseRef == "" {
+		baseRef = "origin/main"
+	}
+	if destination == "" {
+		destination = "ttl.sh"
+	}
+	if ttl == "" {
+		ttl = "24h"
+	}
+
+	head, err := m.Build(ctx, config)
+	if err != nil {
+		return "", err
+	}
+
+	baseConfig := *config
+	baseConfig.Source = dag.Container().
+		From("alpine/git").
+		WithoutEntrypoint().
+		WithMountedDirectory("/src", config.Source).
+		WithExec([]string{"mkdir", "-p", "/base"}).
+		WithExec([]string{"sh", "-c", `git -C /src archive "$1" | tar -x -C /base`, "sh", baseRef}).
+		Directory("/base")
+	base, err := m.Build(ctx, &baseConfig)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("🔍 Comparing the site with %s...\n", baseRef)
+	diff := m.Container().
+		WithMountedDirectory("/base", base).
+		WithMountedDirectory("/head", head).
+		WithNewFile("/diff.py", diffScript).
+		WithExec([]string{"python", "/diff.py", "/base", "/head", "/out"})
+	summary, err := diff.Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to compare the builds: %w", err)
+	}
+	site := head.WithDirectory(previewDir, diff.Directory("/out"))
+
+	var url, body string
+	if destination == "ttl.sh" {
+		ref, err := dag.Ttlsh().Publish(ctx, dag.SitePublish(site).Image(), dagger.TtlshPublishOpts{Tag: ttl})
+		if err != nil {
+			return "", fmt.Errorf("failed to publish the preview: %w", err)
+		}
+		url = ref
+		body = fmt.Sprintf("Run `docker run --rm -p 8080:8080 %s` and open http://localhost:8080/%s/ (available for %s).", ref, previewDir, ttl)
+	} else {
+		if !strings.HasPrefix(destination, "s3://") {
+			return "", fmt.Errorf("unsupported preview destination %q, expected ttl.sh or s3://", destination)
+		}
+		published, err := dag.SitePublish(site).Publish(ctx, destination, dagger.SitePublishPublishOpts{
+			AccessKey: accessKey,
+			SecretKey: secretKey,
+			Endpoint:  endpoint,
+			Region:    region,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to publish the preview: %w", err)
+		}
+		url = strings.TrimSuffix(published, "/") + "/" + previewDir + "/index.html"
+		body = fmt.Sprintf("Open %s to review the changed pages.", url)
+	}
+
+	fmt.Printf("👀 Preview: %s (%s)\n", url, strings.TrimSpace(summary))
+	if pullRequest != "" && token != nil {
+		comment := fmt.Sprintf("### 📖 Docs preview\n\n%s\n\n%s compared with `%s`.", body, strings.TrimSpace(summary), baseRef)
+		err := dag.Gh().PullRequest().Comment(ctx, pullRequest, dagger.GhPullRequestCommentOpts{
+			Body:  comment,
+			Token: token,
+			Repo:  repository,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to comment on pull request %s: %w", pullRequest, err)
+		}
+	}
+
+	return url, nil
+}
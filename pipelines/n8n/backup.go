@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/pipelines/n8n/internal/dagger"
+)
+
+const (
+	// remoteBackupPath is where backups are staged on the droplet
+	remoteBackupPath = "/tmp/n8n-backup.tar.gz"
+	// restoreArchive is where archives to restore are uploaded, relative to remoteDir
+	restoreArchive = "n8n-restore.tar.gz"
+)
+
+// backupScript stops n8n, dumps Postgres when configured and archives the
+// deployment files and data; n8n is restarted even if the backup fails
+const backupScript = `set -eu
+cd /opt/n8n
+rm -rf /tmp/n8n-dump && mkdir -p /tmp/n8n-dump
+docker compose stop n8n
+trap 'docker compose start n8n' EXIT
+if grep -q '^DB_TYPE=postgresdb' .env; then
+  if docker compose ps --services | grep -qx postgres; then
+    docker compose exec -T postgres sh -c 'pg_dump -U "$POSTGRES_USER" -d "$POSTGRES_DB" --clean --if-exists' > /tmp/n8n-dump/postgres.sql
+  else
+    docker run --rm --env-file .env postgres:16-alpine sh -c 'PGPASSWORD="$DB_POSTGRESDB_PASSWORD" pg_dump -h "$DB_POSTGRESDB_HOST" -p "$DB_POSTGRESDB_PORT" -U "$DB_POSTGRESDB_USER" -d "$DB_POSTGRESDB_DATABASE" --clean --if-exists' > /tmp/n8n-dump/postgres.sql
+  fi
+fi
+tar -czf /tmp/n8n-backup.tar.gz --exclude=data/postgres .env docker-compose.yml Caddyfile data -C /tmp/n8n-dump .
+rm -rf /tmp/n8n-dump`
+
+// restoreScript replaces the deployment files and data with the archive
+// contents and loads the Postgres dump when there is one
+const restoreScript = `set -eu
+cd /opt/n8n
+rm -rf /tmp/n8n-restore && mkdir -p /tmp/n8n-restore
+tar -xzf n8n-restore.tar.gz -C /tmp/n8n-restore
+docker compose down
+rm -rf data/n8n
+cp -a /tmp/n8n-restore/.env /tmp/n8n-restore/docker-compose.yml /tmp/n8n-restore/Caddyfile /tmp/n8n-restore/data .
+chown -R 1000:1000 data/n8n
+if [ -f /tmp/n8n-restore/postgres.sql ]; then
+  if grep -q '^  postgres:' docker-compose.yml; then
+    docker compose up -d postgres
+    until docker compose exec -T postgres sh -c 'pg_isready -U "$POSTGRES_USER" -d "$POSTGRES_DB"'; do sleep 2; done
+    docker compose exec -T postgres sh -c 'psql -v ON_ERROR_STOP=1 -U "$POSTGRES_USER" -d "$POSTGRES_DB"' < /tmp/n8n-restore/postgres.sql
+  else
+    docker run --rm -i --env-file .env postgres:16-alpine sh -c 'PGPASSWORD="$DB_POSTGRESDB_PASSWORD" psql -v ON_ERROR_STOP=1 -h "$DB_POSTGRESDB_HOST" -p "$DB_POSTGRESDB_PORT" -U "$DB_POSTGRESDB_USER" -d "$DB_POSTGRESDB_DATABASE"' < /tmp/n8n-restore/postgres.sql
+  fi
+fi
+docker compose up -d --remove-orphans
+rm -rf /tmp/n8n-restore n8n-restore.tar.gz`
+
+// Backup archives the n8n data, the Postgres database when configured, and
+// the deployment files of the running droplet. The archive includes the
+// environment file with the encryption key, so store it as a secret.
+func (n *N8N) Backup(
+	ctx context.Context,
+	doToken *dagger.Secret,
+	// Private key authorized on the droplet (SSHPrivateKey of the deployment)
+	sshPrivateKey *dagger.Secret,
+	// Upload the archive to this Spaces bucket
+	// +optional
+	spacesBucket string,
+	// +optional
+	spacesAccessKey *dagger.Secret,
+	// +optional
+	spacesSecretKey *dagger.Secret,
+	// +optional
+	// +default="nyc3"
+	spacesRegion string,
+) (*dagger.File, error) {
+	n.DoToken = doToken
+
+	ip, err := n.findDropletIP(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ip == "" {
+		return nil, fmt.Errorf("no n8n droplet found to back up")
+	}
+
+	fmt.Printf("💾 Backing up n8n on %s...\n", ip)
+	target := stack(ip, sshPrivateKey)
+	if _, err := target.Exec(ctx, backupScript); err != nil {
+		return nil, fmt.Errorf("failed to back up n8n: %w", err)
+	}
+
+	archive := target.Download(remoteBackupPath)
+	if _, err := archive.Sync(ctx); err != nil {
+		return nil, fmt.Errorf("failed to download backup: %w", err)
+	}
+	if _, err := target.Exec(ctx, "rm -f "+remoteBackupPath); err != nil {
+		return nil, fmt.Errorf("failed to remove backup from %s: %w", ip, err)
+	}
+
+	if spacesBucket != "" {
+		if spacesAccessKey == nil || spacesSecretKey == nil {
+			return nil, fmt.Errorf("access and secret keys are required to upload backups to Spaces")
+		}
+
+		key := fmt.Sprintf("n8n/backups/%s-%s.tar.gz", n.host(), time.Now().UTC().Format("20060102T150405Z"))
+		fmt.Printf("📤 Uploading backup to %s/%s...\n", spacesBucket, key)
+		err := dag.DigitalOcean(doToken).
+			Spaces(spacesAccessKey, spacesSecretKey, dagger.DigitalOceanSpacesOpts{Region: spacesRegion}).
+			UploadFile(ctx, spacesBucket, archive, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload backup: %w", err)
+		}
+	}
+
+	fmt.Println("✅ Backup completed")
+	return archive, nil
+}
+
+// Restore replaces the data of the running droplet with a Backup archive
+// and waits for n8n to become healthy again
+func (n *N8N) Restore(
+	ctx context.Context,
+	doToken *dagger.Secret,
+	// Private key authorized on the droplet (SSHPrivateKey of the deployment)
+	sshPrivateKey *dagger.Secret,
+	// Archive created by Backup
+	archive *dagger.File,
+) error {
+	n.DoToken = doToken
+
+	ip, err := n.findDropletIP(ctx)
+	if err != nil {
+		return err
+	}
+	if ip == "" {
+		return fmt.Errorf("no n8n droplet found to restore to")
+	}
+
+	fmt.Printf("♻️ Restoring n8n on %s...\n", ip)
+	target := stack(ip, sshPrivateKey).WithFile(restoreArchive, archive)
+	if err := target.Upload(ctx); err != nil {
+		return fmt.Errorf("failed to upload backup: %w", err)
+	}
+	if _, err := target.Exec(ctx, restoreScript); err != nil {
+		return fmt.Errorf("failed to restore n8n: %w", err)
+	}
+
+	if err := n.waitForHealthy(ctx, ip, sshPrivateKey, 600); err != nil {
+		return err
+	}
+
+	fmt.Println("✅ Restore completed")
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/felipepimentel/daggerverse/pipelines/n8n/internal/dagger"
+)
+
+// postgresServiceHost is the compose service name of the bundled Postgres
+const postgresServiceHost = "postgres"
+
+// Database holds the Postgres settings n8n is deployed with
+type Database struct {
+	// Host of an external database; empty when Postgres runs in the compose stack
+	Host  string
+	Port  int
+	Name  string
+	User  string
+	SSL   bool
+	Image string
+
+	// +private
+	Password *dagger.Secret
+}
+
+// WithDatabase backs n8n with Postgres instead of SQLite. Without a host, a
+// Postgres container is added to the compose stack; with a host (e.g. a
+// DigitalOcean managed database), n8n connects to it directly.
+func (n *N8N) WithDatabase(
+	// Database password.
+	password *dagger.Secret,
+	// Host of an external database.
+	// +optional
+	host string,
+	// +optional
+	// +default=5432
+	port int,
+	// +optional
+	// +default="n8n"
+	name string,
+	// +optional
+	// +default="n8n"
+	user string,
+	// Require TLS, as DigitalOcean managed databases do.
+	// +optional
+	ssl bool,
+	// Postgres image for the bundled database.
+	// +optional
+	// +default="postgres:16-alpine"
+	image string,
+) *N8N {
+	if port <= 0 {
+		port = 5432
+	}
+	if name == "" {
+		name = "n8n"
+	}
+	if user == "" {
+		user = "n8n"
+	}
+	if image == "" {
+		image = "postgres:16-alpine"
+	}
+
+	n.Database = &Database{
+		Host:     host,
+		Port:     port,
+		Name:     name,
+		User:     user,
+		SSL:      ssl,
+		Image:    image,
+		Password: password,
+	}
+	return n
+}
+
+// bundled reports whether Postgres runs in the compose stack
+func (db *Database) bundled() bool {
+	return db.Host == ""
+}
+
+// env renders the non-secret database settings of the n8n environment file
+func (db *Database) env() string {
+	host := db.Host
+	if db.bundled() {
+		host = postgresServiceHost
+	}
+
+	env := fmt.Sprintf(`
+# Database Settings
+DB_TYPE=postgresdb
+DB_POSTGRESDB_HOST=%s
+DB_POSTGRESDB_PORT=%d
+DB_POSTGRESDB_DATABASE=%s
+DB_POSTGRESDB_USER=%s
+`, host, db.Port, db.Name, db.User)
+
+	if db.SSL {
+		env += "DB_POSTGRESDB_SSL_ENABLED=true\nDB_POSTGRESDB_SSL_REJECT_UNAUTHORIZED=false\n"
+	}
+
+	return env
+}
+
+// composeService renders the compose service of the bundled database; values
+// are interpolated by compose from the .env file on the droplet
+func (db *Database) composeService() string {
+	return fmt.Sprintf(`
+  postgres:
+    image: %s
+    restart: always
+    environment:
+      POSTGRES_DB: ${DB_POSTGRESDB_DATABASE}
+      POSTGRES_USER: ${DB_POSTGRESDB_USER}
+      POSTGRES_PASSWORD: ${DB_POSTGRESDB_PASSWORD}
+    volumes:
+      - ./data/postgres:/var/lib/postgresql/data
+    networks:
+      - n8n-network
+    healthcheck:
+      test: ["CMD-SHELL", "pg_isready -U $${POSTGRES_USER} -d $${POSTGRES_DB}"]
+      interval: 10s
+      timeout: 5s
+      retries: 10
+      start_period: 30s
+`, db.Image)
+}
+
+// healthPath returns the n8n endpoint that reports the instance as ready.
+// With Postgres, readiness also covers the database connection and migrations.
+func (n *N8N) healthPath() string {
+	if n.Database != nil {
+		return "/healthz/readiness"
+	}
+	return "/healthz"
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/felipepimentel/daggerverse/pipelines/n8n/internal/dagger"
+)
+
+// sopsImage provides the sops binary used to decrypt environment files
+const sopsImage = "ghcr.io/getsops/sops:v3.9.4-alpine"
+
+// requiredEnvKeys must have a value in a provided environment file
+var requiredEnvKeys = []string{
+	"N8N_HOST",
+	"N8N_PROTOCOL",
+	"WEBHOOK_URL",
+	"N8N_ENCRYPTION_KEY",
+}
+
+// requiredDatabaseEnvKeys must also have a value when WithDatabase is used,
+// as the bundled Postgres service reads its settings from the same file
+var requiredDatabaseEnvKeys = []string{
+	"DB_TYPE",
+	"DB_POSTGRESDB_HOST",
+	"DB_POSTGRESDB_PORT",
+	"DB_POSTGRESDB_DATABASE",
+	"DB_POSTGRESDB_USER",
+	"DB_POSTGRESDB_PASSWORD",
+}
+
+// WithEnvFile deploys n8n with the given environment file instead of the
+// generated one. The admin password and encryption key are then taken from
+// the file and not returned in the deployment result.
+func (n *N8N) WithEnvFile(env *dagger.Secret) *N8N {
+	n.EnvFile = env
+	n.SopsEnvFile = nil
+	return n
+}
+
+// WithSopsEnvFile deploys n8n with a SOPS-encrypted dotenv file, decrypted
+// with the given age key inside the upload container
+func (n *N8N) WithSopsEnvFile(
+	file *dagger.File,
+	// age private key the file is encrypted for
+	ageKey *dagger.Secret,
+) *N8N {
+	n.SopsEnvFile = file
+	n.SopsAgeKey = ageKey
+	n.EnvFile = nil
+	return n
+}
+
+// hasEnvFile reports whether the environment is provided instead of generated
+func (n *N8N) hasEnvFile() bool {
+	return n.EnvFile != nil || n.SopsEnvFile != nil
+}
+
+// withEnvFile uploads the provided environment file as the .env file of the
+// stack, decrypting it on the way when needed
+func (n *N8N) withEnvFile(deploy *dagger.ComposeDeploy) *dagger.ComposeDeploy {
+	if n.SopsEnvFile != nil {
+		return deploy.WithSopsFile(".env", n.SopsEnvFile, n.SopsAgeKey)
+	}
+	return deploy.WithSecretFile(".env", n.EnvFile)
+}
+
+// envFileReader prepares the container to read the provided environment file
+// and returns the command printing it, decrypted when needed. The file is
+// only read from mounts, so its values never land in a layer.
+func (n *N8N) envFileReader(container *dagger.Container) (*dagger.Container, string) {
+	if n.SopsEnvFile != nil {
+		container = container.
+			WithFile("/usr/local/bin/sops", dag.Container().From(sopsImage).File("/usr/local/bin/sops")).
+			WithMountedFile("/tmp/n8n.sops.env", n.SopsEnvFile).
+			WithSecretVariable("SOPS_AGE_KEY", n.SopsAgeKey)
+		return container, "sops --decrypt --input-type dotenv --output-type dotenv /tmp/n8n.sops.env"
+	}
+
+	return container.WithMountedSecret("/run/secrets/n8n.env", n.EnvFile), "cat /run/secrets/n8n.env"
+}
+
+// validateEnvFile checks that the provided environment file sets every
+// required key. Only key names leave the container, never values.
+func (n *N8N) validateEnvFile(ctx context.Context) error {
+	fmt.Println("🔍 Validating environment file...")
+	container, read := n.envFileReader(dag.Container().From("alpine:3"))
+	output, err := container.
+		WithExec([]string{"sh", "-c", fmt.Sprintf(
+			`set -o pipefail; %s | sed -n 's/^[[:space:]]*\(export[[:space:]]\{1,\}\)\{0,1\}\([A-Za-z_][A-Za-z0-9_]*\)=[[:space:]]*[^[:space:]#].*/\2/p'`,
+			read,
+		)}).
+		Stdout(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read environment file: %w", err)
+	}
+
+	present := make(map[string]bool)
+	for _, key := range strings.Fields(output) {
+		present[key] = true
+	}
+
+	required := requiredEnvKeys
+	if n.Database != nil {
+		required = append(append([]string{}, required...), requiredDatabaseEnvKeys...)
+	}
+
+	var missing []string
+	for _, key := range required {
+		if !present[key] {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("environment file is missing required keys: %s", strings.Join(missing, ", "))
+	}
+
+	fmt.Println("✅ Environment file is valid")
+	return nil
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/pipelines/n8n/internal/dagger"
+)
+
+const (
+	// dropletName is the name of the droplet running n8n
+	dropletName = "n8n"
+	// userDataPath is where the cloud-init script is mounted for doctl
+	userDataPath = "/tmp/user-data.sh"
+)
+
+// sshKeys holds a freshly generated deployment key pair
+type sshKeys struct {
+	Name       string
+	PublicKey  string
+	PrivateKey *dagger.Secret
+}
+
+// droplet mirrors the fields of the doctl droplet JSON output used here
+type droplet struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Networks struct {
+		V4 []struct {
+			IPAddress string `json:"ip_address"`
+			Type      string `json:"type"`
+		} `json:"v4"`
+	} `json:"networks"`
+}
+
+// dropletInfo is the ID and public IP of a droplet
+type dropletInfo struct {
+	ID string
+	IP string
+}
+
+// dnsRecord mirrors the fields of the doctl domain record JSON output used here
+type dnsRecord struct {
+	ID   int    `json:"id"`
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Data string `json:"data"`
+}
+
+// doctl runs a doctl command with JSON output and decodes the result into out
+func (n *N8N) doctl(ctx context.Context, container *dagger.Container, out any, args ...string) error {
+	if container == nil {
+		container = dag.Container().From("digitalocean/doctl:1.101.0")
+	}
+
+	args = append(args, "--output", "json")
+	output, err := container.
+		WithSecretVariable("DIGITALOCEAN_ACCESS_TOKEN", n.DoToken).
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)).
+		WithExec(args, dagger.ContainerWithExecOpts{UseEntrypoint: true}).
+		Stdout(ctx)
+	if err != nil {
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(output), out); err != nil {
+		return fmt.Errorf("failed to parse doctl output: %w", err)
+	}
+
+	return nil
+}
+
+// cleanupOldResources deletes droplets left over from previous deployments
+func (n *N8N) cleanupOldResources(ctx context.Context) error {
+	fmt.Println("🧹 Cleaning up old resources...")
+	var droplets []droplet
+	if err := n.doctl(ctx, nil, &droplets, "compute", "droplet", "list"); err != nil {
+		return fmt.Errorf("failed to list droplets: %w", err)
+	}
+
+	for _, d := range droplets {
+		if d.Name != dropletName {
+			continue
+		}
+
+		fmt.Printf("🗑️ Deleting old droplet: %s (%d)\n", d.Name, d.ID)
+		err := n.doctl(ctx, nil, nil, "compute", "droplet", "delete", strconv.Itoa(d.ID), "--force")
+		if err != nil {
+			return fmt.Errorf("failed to delete droplet %d: %w", d.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// findDropletIP returns the public IP of the n8n droplet, or an empty string
+// when there is no deployment
+func (n *N8N) findDropletIP(ctx context.Context) (string, error) {
+	var droplets []droplet
+	if err := n.doctl(ctx, nil, &droplets, "compute", "droplet", "list"); err != nil {
+		return "", fmt.Errorf("failed to list droplets: %w", err)
+	}
+
+	for _, d := range droplets {
+		if d.Name != dropletName {
+			continue
+		}
+		for _, network := range d.Networks.V4 {
+			if network.Type == "public" {
+				return network.IPAddress, nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// generateSSHKeys generates an ed25519 key pair for this deployment
+func (n *N8N) generateSSHKeys(ctx context.Context) (*sshKeys, error) {
+	fmt.Println("🔑 Generating SSH keys...")
+	name := fmt.Sprintf("%s%d", deployKeyPrefix, time.Now().Unix())
+
+	keygen := dag.Container().
+		From("alpine:3").
+		WithExec([]string{"apk", "add", "--no-cache", "openssh-keygen"}).
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)).
+		WithExec([]string{"ssh-keygen", "-t", "ed25519", "-N", "", "-C", name, "-f", "/tmp/id_ed25519"})
+
+	publicKey, err := keygen.File("/tmp/id_ed25519.pub").Contents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := keygen.File("/tmp/id_ed25519").Contents(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sshKeys{
+		Name:       name,
+		PublicKey:  publicKey,
+		PrivateKey: dag.SetSecret(name, privateKey),
+	}, nil
+}
+
+// registerSSHKey registers the deployment public key and returns its ID
+func (n *N8N) registerSSHKey(ctx context.Context, name, publicKey string) (string, error) {
+	fmt.Println("📝 Registering SSH key with DigitalOcean...")
+	var keys []struct {
+		ID int `json:"id"`
+	}
+	err := n.doctl(ctx, nil, &keys, "compute", "ssh-key", "create", name, "--public-key", publicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to register SSH key: %w", err)
+	}
+	if len(keys) == 0 {
+		return "", fmt.Errorf("no SSH key returned for %s", name)
+	}
+
+	keyID := strconv.Itoa(keys[0].ID)
+	fmt.Printf("✅ SSH key registered with ID: %s\n", keyID)
+	return keyID, nil
+}
+
+// createDroplet creates the n8n droplet and waits until it is active
+func (n *N8N) createDroplet(ctx context.Context, sshKeyID string) (*dropletInfo, error) {
+	fmt.Printf("🚀 Creating droplet %s (%s, %s)...\n", dropletName, n.Region, n.Size)
+	container := dag.Container().
+		From("digitalocean/doctl:1.101.0").
+		WithNewFile(userDataPath, n.getUserData())
+
+	var droplets []droplet
+	err := n.doctl(ctx, container, &droplets,
+		"compute", "droplet", "create", dropletName,
+		"--region", n.Region,
+		"--size", n.Size,
+		"--image", n.Image,
+		"--ssh-keys", sshKeyID,
+		"--user-data-file", userDataPath,
+		"--tag-names", "n8n",
+		"--wait",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create droplet: %w", err)
+	}
+	if len(droplets) == 0 {
+		return nil, fmt.Errorf("no droplet returned for %s", dropletName)
+	}
+
+	info := &dropletInfo{ID: strconv.Itoa(droplets[0].ID)}
+	for _, network := range droplets[0].Networks.V4 {
+		if network.Type == "public" {
+			info.IP = network.IPAddress
+		}
+	}
+	if info.IP == "" {
+		return nil, fmt.Errorf("droplet %s has no public IP", info.ID)
+	}
+
+	fmt.Printf("✅ Droplet %s created with IP %s\n", info.ID, info.IP)
+	return info, nil
+}
+
+// upsertDNSRecord points the n8n subdomain to the droplet, updating any
+// existing A record instead of creating a duplicate
+func (n *N8N) upsertDNSRecord(ctx context.Context, ip string) error {
+	fmt.Printf("🌐 Pointing %s to %s...\n", n.host(), ip)
+	var records []dnsRecord
+	if err := n.doctl(ctx, nil, &records, "compute", "domain", "records", "list", n.Domain); err != nil {
+		return fmt.Errorf("failed to list DNS records for %s: %w", n.Domain, err)
+	}
+
+	for _, record := range records {
+		if record.Type != "A" || record.Name != n.Subdomain {
+			continue
+		}
+		if record.Data == ip {
+			return nil
+		}
+
+		err := n.doctl(ctx, nil, nil,
+			"compute", "domain", "records", "update", n.Domain,
+			"--record-id", strconv.Itoa(record.ID),
+			"--record-data", ip,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update DNS record for %s: %w", n.host(), err)
+		}
+		return nil
+	}
+
+	err := n.doctl(ctx, nil, nil,
+		"compute", "domain", "records", "create", n.Domain,
+		"--record-type", "A",
+		"--record-name", n.Subdomain,
+		"--record-data", ip,
+		"--record-ttl", "300",
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create DNS record for %s: %w", n.host(), err)
+	}
+
+	return nil
+}
@@ -3,24 +3,63 @@ package main
 import (
 	"context"
 	"fmt"
-	"os"
-	"strings"
-	"time"
 
 	"github.com/felipepimentel/daggerverse/pipelines/n8n/internal/dagger"
 )
 
 // N8N represents a module for deploying N8N to DigitalOcean
 type N8N struct {
-	doToken   *dagger.Secret
 	Domain    string
 	Subdomain string
 	Region    string
 	Size      string
 	Image     string
+	// n8n image tag deployed on the droplet
+	Version string
+	// Platform images are pinned for; droplets are x86-64
+	Platform dagger.Platform
+
+	AdminUser string
+	Database  *Database
+
+	// Log and return the teardown plan instead of deleting anything
+	// +private
+	DryRun bool
+
+	// +private
+	DoToken *dagger.Secret
+	// +private
+	AdminPassword *dagger.Secret
+	// +private
+	EncryptionKey *dagger.Secret
+	// +private
+	ExistingSSHKey *dagger.Secret
+	// +private
+	APIKey *dagger.Secret
+	// +private
+	EnvFile *dagger.Secret
+	// +private
+	SopsEnvFile *dagger.File
+	// +private
+	SopsAgeKey *dagger.Secret
 }
 
-
+// DeploymentResult describes a completed n8n deployment
+type DeploymentResult struct {
+	DropletID string
+	DropletIP string
+	URL       string
+	AdminUser string
+
+	AdminPassword *dagger.Secret
+	EncryptionKey *dagger.Secret
+	// Private key authorized on the droplet, for maintenance over SSH
+	SSHPrivateKey *dagger.Secret
+
+	Verification *VerificationResult
+	// Images the deployment runs, also recorded on the droplet for Rollback
+	Manifest *DeploymentManifest
+}
 
 // New creates a new N8N module with default values
 func New() *N8N {
@@ -30,6 +69,9 @@ func New() *N8N {
 		Region:    "nyc1",
 		Size:      "s-2vcpu-2gb",
 		Image:     "ubuntu-20-04-x64",
+		Version:   defaultVersion,
+		Platform:  defaultPlatform,
+		AdminUser: defaultAdminUser,
 	}
 }
 
@@ -51,370 +93,137 @@ func (n *N8N) WithImage(image string) *N8N {
 	return n
 }
 
-// Deploy deploys n8n to DigitalOcean
-func (n *N8N) Deploy(ctx context.Context, doToken string) (string, error) {
-	// If the token is an environment variable reference, get the actual value
-	if strings.HasPrefix(doToken, "env:") {
-		envVar := strings.TrimPrefix(doToken, "env:")
-		envValue := os.Getenv(envVar)
-		if envValue == "" {
-			return "", fmt.Errorf("environment variable %s is not set", envVar)
-		}
-		doToken = envValue
-	}
-
-	// Store the token as a secret for use in other methods
-	n.doToken = dag.SetSecret("do-token", doToken)
-
-	fmt.Println("🚀 Starting n8n deployment...")
+// WithDryRun makes Teardown log and return the resources it would delete
+// instead of deleting them
+func (n *N8N) WithDryRun() *N8N {
+	n.DryRun = true
+	return n
+}
 
-	// Clean up old resources
-	fmt.Println("🧹 Cleaning up old resources...")
-	if err := n.cleanupOldResources(ctx); err != nil {
-		return "", fmt.Errorf("failed to clean up old resources: %w", err)
-	}
+// WithVersion pins the n8n image tag
+func (n *N8N) WithVersion(version string) *N8N {
+	n.Version = version
+	return n
+}
 
-	// Generate SSH keys
-	fmt.Println("🔑 Generating SSH keys...")
-	sshKeys, err := n.Ssh().GenerateKeys()
-	if err != nil {
-		return "", fmt.Errorf("failed to generate SSH keys: %w", err)
-	}
+// WithPlatform sets the platform images are pinned for, e.g. "linux/arm64"
+// for hosts other than DigitalOcean droplets
+func (n *N8N) WithPlatform(platform dagger.Platform) *N8N {
+	n.Platform = platform
+	return n
+}
 
-	// Register SSH key with DigitalOcean
-	fmt.Println("📝 Registering SSH key with DigitalOcean...")
-	keyID, err := n.DigitalOcean().RegisterSSHKey(ctx, sshKeys.name, sshKeys.publicKey)
-	if err != nil {
-		return "", fmt.Errorf("failed to register SSH key: %w", err)
+// WithDomain sets the domain and subdomain n8n is served on
+func (n *N8N) WithDomain(
+	domain string,
+	// +optional
+	// +default="n8n"
+	subdomain string,
+) *N8N {
+	n.Domain = domain
+	if subdomain != "" {
+		n.Subdomain = subdomain
 	}
-	sshKeys.keyID = keyID
-	fmt.Printf("✅ SSH key registered with ID: %s\n", keyID)
-
-	// Create droplet using the DigitalOcean module
-	fmt.Println("🚀 Creating droplet...")
-	dag.DigitalOcean().CreateDroplet(ctx, "n8n", n.Region, n.Size, n.Image, keyID, n.getUserData())
-
-	dag.DigitalOcean().CreateDNSRecord(ctx, n.Domain, n.Subdomain, n.Region, n.Size, n.Image, keyID, n.getUserData())
-
-	// Wait for SSH to be available
-	fmt.Println("⏳ Waiting for SSH to be available...")
-	ssh := dag.DigitalOcean().WaitForDroplet(ctx, "n8n", "active", 180*time.Second)
+	return n
 }
 
-func (n *N8N) cleanupOldResources(ctx context.Context) error {
-	fmt.Println("🧹 Cleaning up old resources...")
-	do := dag.Container().
-		From("digitalocean/doctl:1.101.0").
-		WithSecretVariable("DIGITALOCEAN_ACCESS_TOKEN", n.doToken).
-		WithWorkdir("/app").
-		WithExec([]string{"ln", "-s", "/app/doctl", "/usr/local/bin/doctl"})
-
-	// List and delete old droplets
-	output, err := do.WithExec([]string{
-		"doctl", "compute", "droplet", "list",
-		"--format", "ID,Name",
-		"--no-header",
-	}).Stdout(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to list droplets: %w", err)
+// Deploy deploys n8n to DigitalOcean
+func (n *N8N) Deploy(ctx context.Context, doToken *dagger.Secret) (*DeploymentResult, error) {
+	n.DoToken = doToken
+	if err := validateVersion(n.Version); err != nil {
+		return nil, err
 	}
 
-	for _, line := range strings.Split(output, "\n") {
-		if line == "" {
-			continue
-		}
+	fmt.Println("🚀 Starting n8n deployment...")
 
-		parts := strings.Fields(line)
-		if len(parts) < 2 {
-			continue
+	// Secrets of the running deployment must be read before it is replaced
+	creds := &credentials{}
+	if n.hasEnvFile() {
+		if err := n.validateEnvFile(ctx); err != nil {
+			return nil, err
 		}
-
-		dropletID := parts[0]
-		dropletName := parts[1]
-
-		if dropletName == "n8n" {
-			fmt.Printf("🗑️ Deleting old droplet: %s (%s)\n", dropletName, dropletID)
-			_, err := do.WithExec([]string{
-				"doctl", "compute", "droplet", "delete",
-				dropletID,
-				"--force",
-			}).Sync(ctx)
-			if err != nil {
-				return fmt.Errorf("failed to delete droplet %s: %w", dropletID, err)
-			}
+	} else {
+		var err error
+		if creds, err = n.resolveCredentials(ctx); err != nil {
+			return nil, err
 		}
 	}
 
-	return nil
-}
-
-
+	manifest, err := n.resolveManifest(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-func (n *N8N) registerSSHKey(ctx context.Context, name, publicKey string) (string, error) {
-	fmt.Println("📝 Registering SSH key with DigitalOcean...")
-	do := dag.Container().
-		From("digitalocean/doctl:1.101.0").
-		WithSecretVariable("DIGITALOCEAN_ACCESS_TOKEN", n.doToken).
-		WithWorkdir("/app").
-		WithExec([]string{"ln", "-s", "/app/doctl", "/usr/local/bin/doctl"})
+	if err := n.cleanupOldResources(ctx); err != nil {
+		return nil, fmt.Errorf("failed to clean up old resources: %w", err)
+	}
 
-	output, err := do.WithExec([]string{
-		"doctl", "compute", "ssh-key", "create",
-		name,
-		"--public-key", publicKey,
-		"--format", "ID",
-		"--no-header",
-	}).Stdout(ctx)
+	keys, err := n.generateSSHKeys(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to register SSH key: %w", err)
+		return nil, fmt.Errorf("failed to generate SSH keys: %w", err)
 	}
 
-	return strings.TrimSpace(output), nil
-}
-
-func (n *N8N) getUserData() string {
-	return `#!/bin/bash
-set -euxo pipefail
-
-# Wait for cloud-init to complete
-cloud-init status --wait
-
-# Configure system
-echo 'debconf debconf/frontend select Noninteractive' | debconf-set-selections
-echo 'debconf debconf/priority select critical' | debconf-set-selections
-
-# Install required packages
-apt-get update
-DEBIAN_FRONTEND=noninteractive apt-get install -y \
-    apt-transport-https \
-    ca-certificates \
-    curl \
-    gnupg \
-    lsb-release \
-    openssh-server \
-    ufw
-
-# Configure SSH
-mkdir -p /root/.ssh
-chmod 700 /root/.ssh
-sed -i 's/#PermitRootLogin prohibit-password/PermitRootLogin prohibit-password/' /etc/ssh/sshd_config
-sed -i 's/#PasswordAuthentication yes/PasswordAuthentication no/' /etc/ssh/sshd_config
-systemctl enable ssh
-systemctl restart ssh
-
-# Configure firewall
-ufw allow OpenSSH
-ufw allow 5678/tcp  # n8n
-ufw --force enable
-
-# Install Docker
-curl -fsSL https://get.docker.com | sh
-systemctl enable docker
-systemctl start docker
-DEBIAN_FRONTEND=noninteractive apt-get install -y docker-compose-plugin
-
-# Create n8n directory
-mkdir -p /opt/n8n
-chown root:root /opt/n8n
-chmod 755 /opt/n8n
-
-# Signal completion
-touch /root/.cloud-init-complete
-sync`
-}
+	keyID, err := n.registerSSHKey(ctx, keys.Name, keys.PublicKey)
+	if err != nil {
+		return nil, err
+	}
 
-func (n *N8N) createConfigFiles(ctx context.Context, dropletIP string, doToken *dagger.Secret) error {
-	fmt.Println("📝 Creating configuration files...")
-
-	ssh := dag.Container().
-		From("alpine:latest").
-		WithExec([]string{"apk", "add", "openssh-client"}).
-		WithNewFile("/root/.ssh/id_ed25519", n.sshKeys.privateKey+"\n", dagger.ContainerWithNewFileOpts{
-			Permissions: 0600,
-		}).
-		WithExec([]string{"chmod", "700", "/root/.ssh"}).
-		WithExec([]string{"sh", "-c", "echo 'Host *\n\tStrictHostKeyChecking no\n\tUserKnownHostsFile /dev/null' > /root/.ssh/config"}).
-		WithExec([]string{"chmod", "600", "/root/.ssh/config"})
-
-	// Create directory structure
-	_, err := ssh.WithExec([]string{
-		"ssh",
-		"-i", "/root/.ssh/id_ed25519",
-		fmt.Sprintf("root@%s", dropletIP),
-		"mkdir -p /opt/n8n && chmod 755 /opt/n8n",
-	}).Sync(ctx)
+	droplet, err := n.createDroplet(ctx, keyID)
 	if err != nil {
-		return fmt.Errorf("failed to create directory structure: %w", err)
+		return nil, err
 	}
 
-	// Create configuration files
-	files := map[string]string{
-		"docker-compose.yml": n.getDockerComposeContent(),
-		".env":              n.getEnvContent(),
-		"Caddyfile":         n.getCaddyfileContent(),
+	if err := n.upsertDNSRecord(ctx, droplet.IP); err != nil {
+		return nil, err
 	}
 
-	for filename, content := range files {
-		fmt.Printf("📝 Creating %s...\n", filename)
-		mode := "644"
-		if filename == ".env" {
-			mode = "600"
-		}
+	fmt.Println("⏳ Waiting for the droplet to finish provisioning...")
+	err = dag.DigitalOcean(n.DoToken).WaitForCloudInit(ctx, droplet.IP, keys.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("droplet %s did not finish provisioning: %w", droplet.IP, err)
+	}
 
-		tempFile := fmt.Sprintf("/tmp/%s", filename)
-		ssh = ssh.WithNewFile(tempFile, content)
-
-		_, err := ssh.WithExec([]string{
-			"scp",
-			"-i", "/root/.ssh/id_ed25519",
-			tempFile,
-			fmt.Sprintf("root@%s:/opt/n8n/%s", dropletIP, filename),
-		}).Sync(ctx)
-		if err != nil {
-			return fmt.Errorf("failed to copy %s: %w", filename, err)
-		}
+	if err := n.uploadConfig(ctx, droplet.IP, keys.PrivateKey, creds, manifest); err != nil {
+		return nil, err
+	}
 
-		_, err = ssh.WithExec([]string{
-			"ssh",
-			"-i", "/root/.ssh/id_ed25519",
-			fmt.Sprintf("root@%s", dropletIP),
-			fmt.Sprintf("chmod %s /opt/n8n/%s", mode, filename),
-		}).Sync(ctx)
-		if err != nil {
-			return fmt.Errorf("failed to set permissions for %s: %w", filename, err)
-		}
+	if err := n.composeUp(ctx, droplet.IP, keys.PrivateKey); err != nil {
+		return nil, err
 	}
 
-	fmt.Println("✅ Configuration files created successfully")
-	return nil
-}
+	// Allow extra time for schema migrations on a fresh database
+	healthTimeout := 300
+	if n.Database != nil {
+		healthTimeout = 600
+	}
+	if err := n.waitForHealthy(ctx, droplet.IP, keys.PrivateKey, healthTimeout); err != nil {
+		return nil, err
+	}
 
-func (n *N8N) getDockerComposeContent() string {
-	return `version: '3.8'
-
-services:
-  n8n:
-    image: n8nio/n8n:latest
-    restart: always
-    ports:
-      - "127.0.0.1:5678:5678"
-    env_file:
-      - .env
-    volumes:
-      - n8n_data:/home/node/.n8n
-    networks:
-      - n8n-network
-    healthcheck:
-      test: ["CMD", "wget", "--spider", "http://localhost:5678/healthz"]
-      interval: 30s
-      timeout: 10s
-      retries: 3
-      start_period: 30s
-
-  caddy:
-    image: caddy:2.7.6
-    restart: always
-    ports:
-      - "80:80"
-      - "443:443"
-    volumes:
-      - ./Caddyfile:/etc/caddy/Caddyfile:ro
-      - caddy_data:/data
-      - caddy_config:/config
-    networks:
-      - n8n-network
-    depends_on:
-      - n8n
-    healthcheck:
-      test: ["CMD", "wget", "--spider", "http://localhost:80"]
-      interval: 30s
-      timeout: 10s
-      retries: 3
-      start_period: 30s
-
-volumes:
-  n8n_data:
-  caddy_data:
-  caddy_config:
-
-networks:
-  n8n-network:
-    driver: bridge`
-}
+	if err := recordManifest(ctx, droplet.IP, keys.PrivateKey, manifest); err != nil {
+		return nil, err
+	}
 
-func (n *N8N) getEnvContent() string {
-	return fmt.Sprintf(`# N8N Configuration
-N8N_HOST=%s.%s
-N8N_PORT=5678
-N8N_PROTOCOL=https
-NODE_ENV=production
-WEBHOOK_URL=https://%s.%s/
-GENERIC_TIMEZONE=America/Sao_Paulo
-
-# Security Settings
-N8N_BASIC_AUTH_ACTIVE=true
-N8N_BASIC_AUTH_USER=admin
-N8N_BASIC_AUTH_PASSWORD=admin123
-N8N_ENCRYPTION_KEY=%s`, n.Subdomain, n.Domain, n.Subdomain, n.Domain, generateRandomString(32))
+	verification, err := n.VerifyDeployment(ctx, nil, 300)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("✅ n8n is available at %s\n", verification.URL)
+
+	return &DeploymentResult{
+		DropletID:     droplet.ID,
+		DropletIP:     droplet.IP,
+		URL:           verification.URL,
+		AdminUser:     creds.user,
+		AdminPassword: creds.password,
+		EncryptionKey: creds.encryptionKey,
+		SSHPrivateKey: keys.PrivateKey,
+		Verification:  verification,
+		Manifest:      manifest,
+	}, nil
 }
 
-func (n *N8N) getCaddyfileContent() string {
-	return fmt.Sprintf(`%s.%s {
-    # Use HTTPS with automatic certificate management
-    tls internal
-
-    # Enable Gzip compression
-    encode gzip
-
-    # Reverse proxy to n8n with improved settings
-    reverse_proxy n8n:5678 {
-        # Enable WebSocket support
-        header_up X-Real-IP {remote_host}
-        header_up X-Forwarded-For {remote_host}
-        header_up X-Forwarded-Proto {scheme}
-        header_up X-Forwarded-Host {host}
-
-        # Timeouts
-        flush_interval -1
-        transport http {
-            keepalive 30s
-            keepalive_idle_conns 10
-        }
-    }
-
-    # Security headers
-    header {
-        # Enable HSTS
-        Strict-Transport-Security "max-age=31536000; includeSubDomains; preload"
-        # Disable FLoC tracking
-        Permissions-Policy "interest-cohort=()"
-        # XSS protection
-        X-XSS-Protection "1; mode=block"
-        # Prevent clickjacking
-        X-Frame-Options "SAMEORIGIN"
-        # Prevent MIME type sniffing
-        X-Content-Type-Options "nosniff"
-        # Referrer policy
-        Referrer-Policy "strict-origin-when-cross-origin"
-    }
-
-    # Basic logging
-    log {
-        output file /data/access.log {
-            roll_size 10MB
-            roll_keep 10
-        }
-    }
-}`, n.Subdomain, n.Domain)
+// host returns the fully qualified hostname n8n is served on
+func (n *N8N) host() string {
+	return fmt.Sprintf("%s.%s", n.Subdomain, n.Domain)
 }
-
-// generateRandomString generates a random string of specified length
-func generateRandomString(length int) string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#$%^&*"
-	result := make([]byte, length)
-	for i := range result {
-		result[i] = charset[time.Now().UnixNano()%int64(len(charset))]
-		time.Sleep(1 * time.Nanosecond) // Ensure unique values
-	}
-	return string(result)
-}
\ No newline at end of file
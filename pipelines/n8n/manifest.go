@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/pipelines/n8n/internal/dagger"
+)
+
+const (
+	// caddyImage is the Caddy image resolved to a digest on deploy
+	caddyImage = "caddy:2.7.6"
+	// defaultPlatform is the platform of DigitalOcean droplets, which may
+	// differ from the engine's when running on arm64 runners
+	defaultPlatform = "linux/amd64"
+	// manifestDir holds one manifest per deployment on the droplet
+	manifestDir = remoteDir + "/manifests"
+)
+
+// imageRefPattern matches pinned image references; references are
+// interpolated into remote shell commands, so nothing else is allowed
+var imageRefPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9./_-]*(:[A-Za-z0-9._-]+)?@sha256:[a-f0-9]{64}$`)
+
+// manifestIDPattern matches the timestamp identifiers of deployments
+var manifestIDPattern = regexp.MustCompile(`^[0-9]{8}T[0-9]{6}Z$`)
+
+// DeploymentManifest records the exact images a deployment runs
+type DeploymentManifest struct {
+	// Identifier of the deployment, its UTC timestamp
+	ID string `json:"id"`
+	// n8n image tag
+	Version    string `json:"version"`
+	N8NImage   string `json:"n8nImage"`
+	CaddyImage string `json:"caddyImage"`
+	DeployedAt string `json:"deployedAt"`
+}
+
+// newManifest returns a manifest for the given pinned images, stamped now
+func newManifest(version, n8nImage, caddyImage string) *DeploymentManifest {
+	now := time.Now().UTC()
+	return &DeploymentManifest{
+		ID:         now.Format("20060102T150405Z"),
+		Version:    version,
+		N8NImage:   n8nImage,
+		CaddyImage: caddyImage,
+		DeployedAt: now.Format(time.RFC3339),
+	}
+}
+
+// resolveImage pins an image reference to the digest it currently points to
+// for the given platform
+func resolveImage(ctx context.Context, ref string, platform dagger.Platform) (string, error) {
+	pinned, err := dag.Container(dagger.ContainerOpts{Platform: platform}).From(ref).ImageRef(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+	if !imageRefPattern.MatchString(pinned) {
+		return "", fmt.Errorf("unexpected reference %q resolved for %s", pinned, ref)
+	}
+
+	return pinned, nil
+}
+
+// resolveManifest pins the configured n8n version and Caddy image to digests
+func (n *N8N) resolveManifest(ctx context.Context) (*DeploymentManifest, error) {
+	fmt.Println("📌 Resolving image digests...")
+	n8nImage, err := resolveImage(ctx, fmt.Sprintf("%s:%s", n8nRepository, n.Version), n.Platform)
+	if err != nil {
+		return nil, err
+	}
+	caddy, err := resolveImage(ctx, caddyImage, n.Platform)
+	if err != nil {
+		return nil, err
+	}
+
+	return newManifest(n.Version, n8nImage, caddy), nil
+}
+
+// readManifests returns the manifests recorded on the droplet, oldest first
+func readManifests(ctx context.Context, ip string, privateKey *dagger.Secret) ([]DeploymentManifest, error) {
+	output, err := remoteExec(ctx, ip, privateKey, fmt.Sprintf(
+		`for f in $(ls -1 %s/*.json 2>/dev/null | sort); do cat "$f"; echo; done`,
+		manifestDir,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deployment manifests: %w", err)
+	}
+
+	var manifests []DeploymentManifest
+	decoder := json.NewDecoder(strings.NewReader(output))
+	for {
+		var manifest DeploymentManifest
+		err := decoder.Decode(&manifest)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse deployment manifest: %w", err)
+		}
+		if !manifestIDPattern.MatchString(manifest.ID) {
+			return nil, fmt.Errorf("deployment manifest has an invalid id %q", manifest.ID)
+		}
+		manifests = append(manifests, manifest)
+	}
+
+	return manifests, nil
+}
+
+// recordManifest stores the manifest of a healthy deployment on the droplet
+func recordManifest(ctx context.Context, ip string, privateKey *dagger.Secret, manifest *DeploymentManifest) error {
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode deployment manifest: %w", err)
+	}
+
+	file := dag.Directory().WithNewFile("manifest.json", string(content)).File("manifest.json")
+	err = stack(ip, privateKey).
+		WithFile(fmt.Sprintf("manifests/%s.json", manifest.ID), file).
+		Upload(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to record deployment manifest: %w", err)
+	}
+
+	fmt.Printf("📌 Recorded deployment %s (n8n %s)\n", manifest.ID, manifest.Version)
+	return nil
+}
+
+// applyManifest points the compose file on the droplet at the manifest
+// images and recreates the services that changed
+func applyManifest(ctx context.Context, ip string, privateKey *dagger.Secret, manifest *DeploymentManifest) error {
+	for _, ref := range []string{manifest.N8NImage, manifest.CaddyImage} {
+		if !imageRefPattern.MatchString(ref) {
+			return fmt.Errorf("deployment %s has an invalid image reference %q", manifest.ID, ref)
+		}
+	}
+
+	_, err := remoteExec(ctx, ip, privateKey, fmt.Sprintf(
+		`cd %s && sed -i -e 's|image: .*%s[:@].*|image: %s|' -e 's|image: \(docker.io/library/\)\{0,1\}caddy[:@].*|image: %s|' docker-compose.yml && docker compose pull n8n caddy && docker compose up -d`,
+		remoteDir, n8nRepository, manifest.N8NImage, manifest.CaddyImage,
+	))
+	if err != nil {
+		return fmt.Errorf("failed to apply deployment %s: %w", manifest.ID, err)
+	}
+
+	return nil
+}
+
+// Rollback redeploys the images recorded by the deployment before the
+// current one and waits for n8n to become healthy. The current manifest is
+// moved aside, so repeated rollbacks walk further back in history.
+func (n *N8N) Rollback(
+	ctx context.Context,
+	doToken *dagger.Secret,
+	// Private key authorized on the droplet (SSHPrivateKey of the deployment)
+	sshPrivateKey *dagger.Secret,
+	// Health check timeout in seconds
+	// +optional
+	// +default=300
+	timeout int,
+) (*DeploymentManifest, error) {
+	n.DoToken = doToken
+	if timeout <= 0 {
+		timeout = 300
+	}
+
+	ip, err := n.findDropletIP(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ip == "" {
+		return nil, fmt.Errorf("no n8n droplet found to roll back")
+	}
+
+	history, err := readManifests(ctx, ip, sshPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) < 2 {
+		return nil, fmt.Errorf("no previous deployment recorded on %s to roll back to", ip)
+	}
+	current, previous := history[len(history)-1], history[len(history)-2]
+
+	fmt.Printf("⏪ Rolling back n8n on %s from %s (%s) to %s (%s)...\n", ip, current.ID, current.Version, previous.ID, previous.Version)
+	if err := applyManifest(ctx, ip, sshPrivateKey, &previous); err != nil {
+		return nil, err
+	}
+	if err := n.waitForHealthy(ctx, ip, sshPrivateKey, timeout); err != nil {
+		return nil, err
+	}
+
+	_, err = remoteExec(ctx, ip, sshPrivateKey, fmt.Sprintf(
+		"mkdir -p %s/rolled-back && mv %s/%s.json %s/rolled-back/",
+		manifestDir, manifestDir, current.ID, manifestDir,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retire deployment manifest %s: %w", current.ID, err)
+	}
+
+	fmt.Printf("✅ Rolled back to %s\n", previous.ID)
+	return &previous, nil
+}
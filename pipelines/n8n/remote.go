@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/felipepimentel/daggerverse/pipelines/n8n/internal/dagger"
+)
+
+// remoteDir is the directory holding the n8n deployment on the droplet
+const remoteDir = "/opt/n8n"
+
+// stack returns the compose-deploy target for the n8n stack on the droplet
+func stack(ip string, privateKey *dagger.Secret) *dagger.ComposeDeploy {
+	return dag.ComposeDeploy(ip, privateKey, dagger.ComposeDeployOpts{Dir: remoteDir})
+}
+
+// remoteExec runs a shell command on the droplet as root
+func remoteExec(ctx context.Context, ip string, privateKey *dagger.Secret, command string) (string, error) {
+	return stack(ip, privateKey).Exec(ctx, command)
+}
+
+// uploadConfig copies the compose file, Caddyfile and environment to the droplet
+func (n *N8N) uploadConfig(ctx context.Context, ip string, privateKey *dagger.Secret, creds *credentials, manifest *DeploymentManifest) error {
+	fmt.Println("📝 Uploading configuration files...")
+	config := dag.Directory().
+		WithNewFile("docker-compose.yml", n.getDockerComposeContent(manifest)).
+		WithNewFile("Caddyfile", n.getCaddyfileContent())
+
+	deploy := stack(ip, privateKey).WithDirectory(config)
+	if n.hasEnvFile() {
+		deploy = n.withEnvFile(deploy)
+	} else {
+		deploy = n.withGeneratedEnv(deploy, creds)
+	}
+
+	// The n8n image runs as the node user (uid 1000)
+	_, err := remoteExec(ctx, ip, privateKey, "mkdir -p data/n8n && chown 1000:1000 data/n8n")
+	if err != nil {
+		return fmt.Errorf("failed to prepare %s: %w", remoteDir, err)
+	}
+
+	err = deploy.Upload(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to upload configuration: %w", err)
+	}
+
+	fmt.Println("✅ Configuration files uploaded successfully")
+	return nil
+}
+
+// withGeneratedEnv uploads the generated environment file. Secret values are
+// appended on the droplet from secret variables, so they never land in a file
+// or layer.
+func (n *N8N) withGeneratedEnv(deploy *dagger.ComposeDeploy, creds *credentials) *dagger.ComposeDeploy {
+	env := dag.Directory().
+		WithNewFile(".env", n.getEnvContent(creds.user), dagger.DirectoryWithNewFileOpts{Permissions: 0600}).
+		File(".env")
+
+	deploy = deploy.
+		WithFile(".env", env).
+		WithSecretVariable("N8N_BASIC_AUTH_PASSWORD", creds.password).
+		WithSecretVariable("N8N_ENCRYPTION_KEY", creds.encryptionKey)
+	if n.Database != nil {
+		deploy = deploy.WithSecretVariable("DB_POSTGRESDB_PASSWORD", n.Database.Password)
+	}
+	return deploy
+}
+
+// composeUp starts the n8n stack on the droplet
+func (n *N8N) composeUp(ctx context.Context, ip string, privateKey *dagger.Secret) error {
+	if err := stack(ip, privateKey).Up(ctx, dagger.ComposeDeployUpOpts{Pull: true}); err != nil {
+		return fmt.Errorf("failed to start n8n services: %w", err)
+	}
+
+	return nil
+}
+
+// waitForHealthy polls the n8n health endpoint on the droplet until it responds
+func (n *N8N) waitForHealthy(ctx context.Context, ip string, privateKey *dagger.Secret, timeout int) error {
+	err := stack(ip, privateKey).WaitForHealthy(ctx, "curl -fsS http://127.0.0.1:5678"+n.healthPath(),
+		dagger.ComposeDeployWaitForHealthyOpts{Timeout: timeout},
+	)
+	if err != nil {
+		return fmt.Errorf("n8n did not become healthy: %w", err)
+	}
+
+	fmt.Println("✅ n8n is healthy")
+	return nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/felipepimentel/daggerverse/pipelines/n8n/internal/dagger"
+)
+
+// defaultAdminUser is the basic auth user when none is configured
+const defaultAdminUser = "admin"
+
+// credentials holds the n8n admin credentials and encryption key
+type credentials struct {
+	user          string
+	password      *dagger.Secret
+	encryptionKey *dagger.Secret
+}
+
+// WithAdminPassword sets the basic auth credentials instead of generating a password
+func (n *N8N) WithAdminPassword(
+	password *dagger.Secret,
+	// +optional
+	// +default="admin"
+	user string,
+) *N8N {
+	n.AdminPassword = password
+	if user != "" {
+		n.AdminUser = user
+	}
+	return n
+}
+
+// WithEncryptionKey sets the key n8n encrypts stored credentials with.
+// Keep it stable across deployments: rotating it makes stored credentials unreadable.
+func (n *N8N) WithEncryptionKey(key *dagger.Secret) *N8N {
+	n.EncryptionKey = key
+	return n
+}
+
+// WithExistingDeployment reuses the admin password and encryption key of the
+// running deployment, read over SSH with the key returned by a previous Deploy
+func (n *N8N) WithExistingDeployment(sshPrivateKey *dagger.Secret) *N8N {
+	n.ExistingSSHKey = sshPrivateKey
+	return n
+}
+
+// resolveCredentials returns the configured credentials, falling back to the
+// running deployment's secrets and finally to newly generated ones
+func (n *N8N) resolveCredentials(ctx context.Context) (*credentials, error) {
+	creds := &credentials{
+		user:          n.AdminUser,
+		password:      n.AdminPassword,
+		encryptionKey: n.EncryptionKey,
+	}
+	if creds.user == "" {
+		creds.user = defaultAdminUser
+	}
+
+	if (creds.password == nil || creds.encryptionKey == nil) && n.ExistingSSHKey != nil {
+		existing, err := n.readExistingCredentials(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			if creds.password == nil {
+				creds.password = existing.password
+			}
+			if creds.encryptionKey == nil {
+				creds.encryptionKey = existing.encryptionKey
+			}
+		}
+	}
+
+	var err error
+	if creds.password == nil {
+		fmt.Println("🔐 Generating admin password...")
+		if creds.password, err = randomSecret("n8n-admin-password", 24); err != nil {
+			return nil, err
+		}
+	}
+	if creds.encryptionKey == nil {
+		fmt.Println("🔐 Generating encryption key...")
+		if creds.encryptionKey, err = randomSecret("n8n-encryption-key", 32); err != nil {
+			return nil, err
+		}
+	}
+
+	return creds, nil
+}
+
+// readExistingCredentials reads the secrets from the environment file of the
+// running droplet, returning nil when there is no previous deployment
+func (n *N8N) readExistingCredentials(ctx context.Context) (*credentials, error) {
+	ip, err := n.findDropletIP(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ip == "" {
+		fmt.Println("ℹ️ No existing deployment found, generating new secrets")
+		return nil, nil
+	}
+
+	fmt.Printf("🔐 Reading secrets of the existing deployment on %s...\n", ip)
+	env, err := remoteExec(ctx, ip, n.ExistingSSHKey, fmt.Sprintf("cat %s/.env", remoteDir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read existing secrets from %s: %w", ip, err)
+	}
+
+	existing := &credentials{}
+	scanner := bufio.NewScanner(strings.NewReader(env))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok || value == "" {
+			continue
+		}
+		switch key {
+		case "N8N_BASIC_AUTH_PASSWORD":
+			existing.password = dag.SetSecret("n8n-admin-password", value)
+		case "N8N_ENCRYPTION_KEY":
+			existing.encryptionKey = dag.SetSecret("n8n-encryption-key", value)
+		}
+	}
+
+	return existing, nil
+}
+
+// randomSecret returns a secret holding a URL-safe random string generated
+// from length bytes of crypto/rand entropy
+func randomSecret(name string, length int) (*dagger.Secret, error) {
+	buf := make([]byte, length)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate %s: %w", name, err)
+	}
+
+	return dag.SetSecret(name, base64.RawURLEncoding.EncodeToString(buf)), nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/felipepimentel/daggerverse/pipelines/n8n/internal/dagger"
+)
+
+// deployKeyPrefix starts the names of the SSH keys registered by deployments
+const deployKeyPrefix = "n8n-deploy-"
+
+// PlannedAction is a change Teardown makes, or only plans in dry-run mode,
+// e.g. {delete, droplet, 12345}
+type PlannedAction struct {
+	// Always delete for Teardown
+	Action string
+	// droplet, DNS record or SSH key
+	Resource string
+	// ID of the resource
+	ID string
+}
+
+// TeardownResult lists the resources Teardown deleted, or the plan it would
+// carry out in dry-run mode
+type TeardownResult struct {
+	DryRun  bool
+	Actions []PlannedAction
+	Deleted int
+}
+
+// Teardown deletes the deployment: the n8n droplet, the A record of its
+// subdomain and the SSH keys registered by deployments. Data on the droplet
+// is lost, so take a Backup first. After WithDryRun, the actions are logged
+// and returned without deleting anything.
+func (n *N8N) Teardown(
+	ctx context.Context,
+	doToken *dagger.Secret,
+) (*TeardownResult, error) {
+	n.DoToken = doToken
+	result := &TeardownResult{DryRun: n.DryRun}
+
+	var droplets []droplet
+	if err := n.doctl(ctx, nil, &droplets, "compute", "droplet", "list"); err != nil {
+		return nil, fmt.Errorf("failed to list droplets: %w", err)
+	}
+	for _, d := range droplets {
+		if d.Name == dropletName {
+			result.Actions = append(result.Actions, PlannedAction{Action: "delete", Resource: "droplet", ID: strconv.Itoa(d.ID)})
+		}
+	}
+
+	var records []dnsRecord
+	if err := n.doctl(ctx, nil, &records, "compute", "domain", "records", "list", n.Domain); err != nil {
+		return nil, fmt.Errorf("failed to list DNS records for %s: %w", n.Domain, err)
+	}
+	for _, record := range records {
+		if record.Type == "A" && record.Name == n.Subdomain {
+			result.Actions = append(result.Actions, PlannedAction{Action: "delete", Resource: "DNS record", ID: strconv.Itoa(record.ID)})
+		}
+	}
+
+	var keys []struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := n.doctl(ctx, nil, &keys, "compute", "ssh-key", "list"); err != nil {
+		return nil, fmt.Errorf("failed to list SSH keys: %w", err)
+	}
+	for _, key := range keys {
+		if strings.HasPrefix(key.Name, deployKeyPrefix) {
+			result.Actions = append(result.Actions, PlannedAction{Action: "delete", Resource: "SSH key", ID: strconv.Itoa(key.ID)})
+		}
+	}
+
+	for _, action := range result.Actions {
+		if n.DryRun {
+			fmt.Printf("📝 Would %s %s %s\n", action.Action, action.Resource, action.ID)
+			continue
+		}
+
+		fmt.Printf("🗑️ Deleting %s %s\n", action.Resource, action.ID)
+		var args []string
+		switch action.Resource {
+		case "droplet":
+			args = []string{"compute", "droplet", "delete", action.ID, "--force"}
+		case "DNS record":
+			args = []string{"compute", "domain", "records", "delete", n.Domain, action.ID, "--force"}
+		case "SSH key":
+			args = []string{"compute", "ssh-key", "delete", action.ID, "--force"}
+		}
+		if err := n.doctl(ctx, nil, nil, args...); err != nil {
+			return nil, fmt.Errorf("failed to delete %s %s: %w", action.Resource, action.ID, err)
+		}
+		result.Deleted++
+	}
+
+	fmt.Printf("🧹 Teardown finished: %d planned, %d deleted\n", len(result.Actions), result.Deleted)
+	return result, nil
+}
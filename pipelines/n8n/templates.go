@@ -0,0 +1,188 @@
+package main
+
+import "fmt"
+
+// getUserData returns the cloud-init script that prepares the droplet
+func (n *N8N) getUserData() string {
+	return `#!/bin/bash
+set -euxo pipefail
+
+# Configure system
+echo 'debconf debconf/frontend select Noninteractive' | debconf-set-selections
+echo 'debconf debconf/priority select critical' | debconf-set-selections
+
+# Install required packages
+apt-get update
+DEBIAN_FRONTEND=noninteractive apt-get install -y \
+    apt-transport-https \
+    ca-certificates \
+    curl \
+    gnupg \
+    lsb-release \
+    openssh-server \
+    ufw
+
+# Configure SSH
+mkdir -p /root/.ssh
+chmod 700 /root/.ssh
+sed -i 's/#PermitRootLogin prohibit-password/PermitRootLogin prohibit-password/' /etc/ssh/sshd_config
+sed -i 's/#PasswordAuthentication yes/PasswordAuthentication no/' /etc/ssh/sshd_config
+systemctl enable ssh
+systemctl restart ssh
+
+# Configure firewall
+ufw allow OpenSSH
+ufw allow 5678/tcp  # n8n
+ufw --force enable
+
+# Install Docker
+curl -fsSL https://get.docker.com | sh
+systemctl enable docker
+systemctl start docker
+DEBIAN_FRONTEND=noninteractive apt-get install -y docker-compose-plugin
+
+# Create n8n directory
+mkdir -p /opt/n8n
+chown root:root /opt/n8n
+chmod 755 /opt/n8n
+
+# Signal completion
+touch /root/.cloud-init-complete
+sync`
+}
+
+// getDockerComposeContent renders the n8n and Caddy compose file with the
+// images pinned by the manifest. Data lives in bind mounts under ./data so it
+// can be archived with the deployment.
+func (n *N8N) getDockerComposeContent(manifest *DeploymentManifest) string {
+	database := ""
+	n8nDependsOn := ""
+	if n.Database != nil && n.Database.bundled() {
+		database = n.Database.composeService()
+		n8nDependsOn = `
+    depends_on:
+      postgres:
+        condition: service_healthy`
+	}
+
+	return fmt.Sprintf(`version: '3.8'
+
+services:
+  n8n:
+    image: %s
+    restart: always
+    ports:
+      - "127.0.0.1:5678:5678"
+    env_file:
+      - .env
+    volumes:
+      - ./data/n8n:/home/node/.n8n
+    networks:
+      - n8n-network%s
+    healthcheck:
+      test: ["CMD", "wget", "--spider", "http://localhost:5678%s"]
+      interval: 30s
+      timeout: 10s
+      retries: 3
+      start_period: 30s
+%s
+  caddy:
+    image: %s
+    restart: always
+    ports:
+      - "80:80"
+      - "443:443"
+    volumes:
+      - ./Caddyfile:/etc/caddy/Caddyfile:ro
+      - caddy_data:/data
+      - caddy_config:/config
+    networks:
+      - n8n-network
+    depends_on:
+      - n8n
+    healthcheck:
+      test: ["CMD", "wget", "--spider", "http://localhost:80"]
+      interval: 30s
+      timeout: 10s
+      retries: 3
+      start_period: 30s
+
+volumes:
+  caddy_data:
+  caddy_config:
+
+networks:
+  n8n-network:
+    driver: bridge`, manifest.N8NImage, n8nDependsOn, n.healthPath(), database, manifest.CaddyImage)
+}
+
+// getEnvContent renders the non-secret part of the n8n environment file;
+// secret values are appended on upload without passing through this module
+func (n *N8N) getEnvContent(adminUser string) string {
+	env := fmt.Sprintf(`# N8N Configuration
+N8N_HOST=%s.%s
+N8N_PORT=5678
+N8N_PROTOCOL=https
+NODE_ENV=production
+WEBHOOK_URL=https://%s.%s/
+GENERIC_TIMEZONE=America/Sao_Paulo
+
+# Security Settings
+N8N_BASIC_AUTH_ACTIVE=true
+N8N_BASIC_AUTH_USER=%s
+`, n.Subdomain, n.Domain, n.Subdomain, n.Domain, adminUser)
+	if n.Database != nil {
+		env += n.Database.env()
+	}
+	return env
+}
+
+// getCaddyfileContent renders the Caddy reverse proxy configuration
+func (n *N8N) getCaddyfileContent() string {
+	return fmt.Sprintf(`%s.%s {
+    # HTTPS certificates are issued automatically by Let's Encrypt
+
+    # Enable Gzip compression
+    encode gzip
+
+    # Reverse proxy to n8n with improved settings
+    reverse_proxy n8n:5678 {
+        # Enable WebSocket support
+        header_up X-Real-IP {remote_host}
+        header_up X-Forwarded-For {remote_host}
+        header_up X-Forwarded-Proto {scheme}
+        header_up X-Forwarded-Host {host}
+
+        # Timeouts
+        flush_interval -1
+        transport http {
+            keepalive 30s
+            keepalive_idle_conns 10
+        }
+    }
+
+    # Security headers
+    header {
+        # Enable HSTS
+        Strict-Transport-Security "max-age=31536000; includeSubDomains; preload"
+        # Disable FLoC tracking
+        Permissions-Policy "interest-cohort=()"
+        # XSS protection
+        X-XSS-Protection "1; mode=block"
+        # Prevent clickjacking
+        X-Frame-Options "SAMEORIGIN"
+        # Prevent MIME type sniffing
+        X-Content-Type-Options "nosniff"
+        # Referrer policy
+        Referrer-Policy "strict-origin-when-cross-origin"
+    }
+
+    # Basic logging
+    log {
+        output file /data/access.log {
+            roll_size 10MB
+            roll_keep 10
+        }
+    }
+}`, n.Subdomain, n.Domain)
+}
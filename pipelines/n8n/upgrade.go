@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/felipepimentel/daggerverse/pipelines/n8n/internal/dagger"
+)
+
+const (
+	// n8nRepository is the image repository n8n is pulled from
+	n8nRepository = "n8nio/n8n"
+	// defaultVersion is the n8n image tag deployed when none is pinned
+	defaultVersion = "1.74.1"
+)
+
+// versionPattern matches the image tags accepted by WithVersion and Upgrade
+var versionPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// validateVersion rejects empty and malformed image tags
+func validateVersion(version string) error {
+	if !versionPattern.MatchString(version) {
+		return fmt.Errorf("invalid n8n version %q", version)
+	}
+	return nil
+}
+
+// Upgrade updates the n8n image of the running deployment to the digest the
+// given tag points to. n8n is restarted with the new image and, when it does
+// not become healthy, rolled back to the images of the current deployment.
+// Returns the manifest of the deployment running afterwards.
+func (n *N8N) Upgrade(
+	ctx context.Context,
+	doToken *dagger.Secret,
+	// Private key authorized on the droplet (SSHPrivateKey of the deployment)
+	sshPrivateKey *dagger.Secret,
+	// n8n image tag to upgrade to, e.g. "1.75.2"
+	version string,
+	// Health check timeout in seconds
+	// +optional
+	// +default=300
+	timeout int,
+) (*DeploymentManifest, error) {
+	n.DoToken = doToken
+	if err := validateVersion(version); err != nil {
+		return nil, err
+	}
+	if timeout <= 0 {
+		timeout = 300
+	}
+
+	ip, err := n.findDropletIP(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ip == "" {
+		return nil, fmt.Errorf("no n8n droplet found to upgrade")
+	}
+
+	history, err := readManifests(ctx, ip, sshPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, fmt.Errorf("no deployment manifest found on %s, redeploy before upgrading", ip)
+	}
+	previous := history[len(history)-1]
+
+	n8nImage, err := resolveImage(ctx, fmt.Sprintf("%s:%s", n8nRepository, version), n.Platform)
+	if err != nil {
+		return nil, err
+	}
+	if n8nImage == previous.N8NImage {
+		fmt.Printf("ℹ️ n8n is already running %s\n", n8nImage)
+		return &previous, nil
+	}
+	target := newManifest(version, n8nImage, previous.CaddyImage)
+
+	fmt.Printf("⬆️ Upgrading n8n on %s from %s to %s...\n", ip, previous.Version, version)
+	upgradeErr := applyManifest(ctx, ip, sshPrivateKey, target)
+	if upgradeErr == nil {
+		upgradeErr = n.waitForHealthy(ctx, ip, sshPrivateKey, timeout)
+	}
+	if upgradeErr == nil {
+		if err := recordManifest(ctx, ip, sshPrivateKey, target); err != nil {
+			return nil, err
+		}
+		fmt.Printf("✅ n8n upgraded to %s\n", version)
+		return target, nil
+	}
+
+	fmt.Printf("⏪ Upgrade failed, rolling back to %s...\n", previous.Version)
+	if err := applyManifest(ctx, ip, sshPrivateKey, &previous); err != nil {
+		return nil, fmt.Errorf("upgrade to %s failed (%v) and rollback to %s failed: %w", version, upgradeErr, previous.Version, err)
+	}
+	if err := n.waitForHealthy(ctx, ip, sshPrivateKey, timeout); err != nil {
+		return nil, fmt.Errorf("upgrade to %s failed (%v) and %s did not recover: %w", version, upgradeErr, previous.Version, err)
+	}
+
+	return &previous, fmt.Errorf("upgrade to %s failed, rolled back to %s: %w", version, previous.Version, upgradeErr)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/pipelines/n8n/internal/dagger"
+)
+
+// VerificationResult describes the checks run against a live deployment
+type VerificationResult struct {
+	URL               string
+	Healthy           bool
+	CertificateIssuer string
+	CertificateExpiry string
+	// Number of workflows returned by the API smoke test, -1 when skipped
+	WorkflowCount int
+}
+
+// WithAPIKey sets the n8n API key used for the API smoke test after deploy
+func (n *N8N) WithAPIKey(apiKey *dagger.Secret) *N8N {
+	n.APIKey = apiKey
+	return n
+}
+
+// VerifyDeployment checks the public endpoint of the deployment: it polls
+// the health endpoint over HTTPS until it responds, verifies a trusted TLS
+// certificate was issued and, with an API key, lists workflows through the
+// public API
+func (n *N8N) VerifyDeployment(
+	ctx context.Context,
+	// n8n API key for the API smoke test (defaults to the one set with WithAPIKey)
+	// +optional
+	apiKey *dagger.Secret,
+	// Timeout in seconds
+	// +optional
+	// +default=300
+	timeout int,
+) (*VerificationResult, error) {
+	if apiKey == nil {
+		apiKey = n.APIKey
+	}
+	if timeout <= 0 {
+		timeout = 300
+	}
+
+	url := fmt.Sprintf("https://%s", n.host())
+	result := &VerificationResult{URL: url, WorkflowCount: -1}
+
+	// DNS propagation and certificate issuance can take a few minutes
+	fmt.Printf("🔍 Verifying %s (timeout: %ds)...\n", url, timeout)
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+	var lastErr error
+	for time.Now().Before(deadline) {
+		// Error responses, such as a 502 from Caddy while n8n starts, fail the
+		// attempt, and each attempt hits the endpoint again
+		_, lastErr = dag.Curl().HealthCheck(url + n.healthPath()).Sync(ctx)
+		if lastErr == nil {
+			result.Healthy = true
+			break
+		}
+
+		time.Sleep(15 * time.Second)
+	}
+	if !result.Healthy {
+		return result, fmt.Errorf("%s did not become healthy: %w", url, lastErr)
+	}
+	fmt.Println("✅ Public endpoint is healthy")
+
+	certificate, err := dag.Container().
+		From("alpine:3").
+		WithExec([]string{"apk", "add", "--no-cache", "openssl"}).
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)).
+		WithExec([]string{"sh", "-c", fmt.Sprintf(
+			"echo | openssl s_client -connect %s:443 -servername %s -verify_return_error 2>/dev/null | openssl x509 -noout -issuer -enddate",
+			n.host(), n.host(),
+		)}).
+		Stdout(ctx)
+	if err != nil {
+		return result, fmt.Errorf("TLS certificate for %s could not be verified: %w", n.host(), err)
+	}
+	for _, line := range strings.Split(certificate, "\n") {
+		if issuer, ok := strings.CutPrefix(line, "issuer="); ok {
+			result.CertificateIssuer = strings.TrimSpace(issuer)
+		}
+		if expiry, ok := strings.CutPrefix(line, "notAfter="); ok {
+			result.CertificateExpiry = strings.TrimSpace(expiry)
+		}
+	}
+	fmt.Printf("✅ TLS certificate issued by %s, expires %s\n", result.CertificateIssuer, result.CertificateExpiry)
+
+	if apiKey == nil {
+		return result, nil
+	}
+
+	output, err := dag.Container().
+		From("curlimages/curl:latest").
+		WithSecretVariable("N8N_API_KEY", apiKey).
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)).
+		WithExec([]string{"sh", "-c", fmt.Sprintf(
+			`curl -fsS -H "X-N8N-API-KEY: $N8N_API_KEY" -H "Accept: application/json" %s/api/v1/workflows`,
+			url,
+		)}).
+		Stdout(ctx)
+	if err != nil {
+		return result, fmt.Errorf("API smoke test failed: %w", err)
+	}
+
+	var workflows struct {
+		Data []json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(output), &workflows); err != nil {
+		return result, fmt.Errorf("failed to parse workflows response: %w", err)
+	}
+	result.WorkflowCount = len(workflows.Data)
+	fmt.Printf("✅ API smoke test listed %d workflows\n", result.WorkflowCount)
+
+	return result, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/felipepimentel/daggerverse/pipelines/python/internal/dagger"
+)
+
+// Error messages for API checks.
+const (
+	errApiCheck    = "breaking API changes detected"
+	errApiBaseline = "failed to determine API baseline reference"
+)
+
+// apiContainer returns a container with griffe installed and the source mounted.
+func (p *Python) apiContainer(source *dagger.Directory) *dagger.Container {
+	return dag.Container().
+		From(fmt.Sprintf("python:%s", p.pythonVersion)).
+		WithExec([]string{"sh", "-c", "command -v git >/dev/null || (apk add --no-cache git || (apt-get update && apt-get install -y --no-install-recommends git))"}).
+		WithExec([]string{"pip", "install", "--no-cache-dir", "griffe"}).
+		WithDirectory(containerWorkdir, source).
+		WithWorkdir(containerWorkdir)
+}
+
+// ApiSnapshot dumps the public API of a package as JSON using griffe.
+// The snapshot can be stored alongside a release and diffed later.
+func (p *Python) ApiSnapshot(
+	source *dagger.Directory,
+	// Name of the top-level Python package
+	packageName string,
+	// Directory containing the package
+	// +optional
+	// +default="src"
+	searchPath string,
+) *dagger.File {
+	if searchPath == "" {
+		searchPath = "src"
+	}
+
+	return p.apiContainer(source).
+		WithExec([]string{"griffe", "dump", packageName, "--search", searchPath, "--output", "/tmp/api.json"}).
+		File("/tmp/api.json")
+}
+
+// ApiCheck compares the public API of a package against a previous release and
+// fails when breaking changes are found, so they are caught before the
+// versioner bumps a minor instead of a major version.
+// The source must contain the .git directory.
+func (p *Python) ApiCheck(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Name of the top-level Python package
+	packageName string,
+	// Git reference to compare against (defaults to the latest tag)
+	// +optional
+	against string,
+	// Directory containing the package
+	// +optional
+	// +default="src"
+	searchPath string,
+) (string, error) {
+	if searchPath == "" {
+		searchPath = "src"
+	}
+
+	container := p.apiContainer(source)
+
+	if against == "" {
+		ref, err := container.WithExec([]string{"git", "describe", "--tags", "--abbrev=0"}).Stdout(ctx)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", errApiBaseline, err)
+		}
+		against = strings.TrimSpace(ref)
+	}
+
+	fmt.Printf("Checking public API of %s against %s...\n", packageName, against)
+
+	output, err := container.WithExec([]string{
+		"griffe", "check", packageName,
+		"--search", searchPath,
+		"--against", against,
+		"--verbose",
+	}).Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", errApiCheck, err)
+	}
+
+	return output, nil
+}
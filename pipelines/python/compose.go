@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/felipepimentel/daggerverse/pipelines/python/internal/dagger"
+)
+
+// Compose harness defaults.
+const (
+	// DefaultComposeFile is the compose file describing the integration environment.
+	DefaultComposeFile = "docker-compose.test.yml"
+	// yqImage converts compose YAML to JSON so it can be decoded without extra dependencies.
+	yqImage = "mikefarah/yq:4"
+)
+
+// composeFile is the subset of the compose specification used by the harness.
+type composeFile struct {
+	Services map[string]composeService `json:"services"`
+}
+
+// composeService is a single service entry of a compose file.
+type composeService struct {
+	Image       string          `json:"image"`
+	Build       json.RawMessage `json:"build"`
+	Command     json.RawMessage `json:"command"`
+	Environment json.RawMessage `json:"environment"`
+	EnvFile     json.RawMessage `json:"env_file"`
+	Ports       []any           `json:"ports"`
+	Expose      []any           `json:"expose"`
+}
+
+// composeBuild is the long form of the compose build option.
+type composeBuild struct {
+	Context    string `json:"context"`
+	Dockerfile string `json:"dockerfile"`
+}
+
+// composeServices converts the services of a compose file into Dagger services,
+// keyed by service name so they can be bound under the same hostnames. As with
+// compose, relative paths resolve against the directory of the compose file.
+func (p *Python) composeServices(ctx context.Context, source *dagger.Directory, file string) (map[string]*dagger.Service, error) {
+	raw, err := dag.Container().
+		From(yqImage).
+		WithMountedFile("/tmp/compose.yml", source.File(file)).
+		WithExec([]string{"yq", "--output-format", "json", ".", "/tmp/compose.yml"}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read compose file %s: %w", file, err)
+	}
+
+	var compose composeFile
+	if err := json.Unmarshal([]byte(raw), &compose); err != nil {
+		return nil, fmt.Errorf("failed to parse compose file %s: %w", file, err)
+	}
+
+	dir := path.Dir(file)
+	services := make(map[string]*dagger.Service, len(compose.Services))
+	for name, svc := range compose.Services {
+		ctr, err := svc.container(source, dir)
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %w", name, err)
+		}
+
+		envFiles, err := svc.envFiles()
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %w", name, err)
+		}
+		env, err := svc.env()
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %w", name, err)
+		}
+
+		// As with compose, environment entries override env_file values
+		dotenv := dag.Dotenv()
+		for _, file := range envFiles {
+			dotenv = dotenv.WithEnvFile(source.File(path.Join(dir, file)))
+		}
+		for _, key := range sortedKeys(env) {
+			dotenv = dotenv.WithVariable(key, env[key])
+		}
+		ctr = dotenv.Apply(ctr)
+
+		for _, port := range svc.ports() {
+			ctr = ctr.WithExposedPort(port)
+		}
+
+		args, err := svc.args()
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %w", name, err)
+		}
+
+		services[name] = ctr.AsService(dagger.ContainerAsServiceOpts{
+			Args:          args,
+			UseEntrypoint: true,
+		})
+	}
+
+	return services, nil
+}
+
+// container returns the base container of a service, from its image or build
+// context. The build context is relative to dir, the compose file's directory.
+func (s composeService) container(source *dagger.Directory, dir string) (*dagger.Container, error) {
+	if len(s.Build) > 0 {
+		build := composeBuild{}
+		if err := json.Unmarshal(s.Build, &build.Context); err != nil {
+			if err := json.Unmarshal(s.Build, &build); err != nil {
+				return nil, fmt.Errorf("invalid build option: %w", err)
+			}
+		}
+		if build.Context == "" {
+			build.Context = "."
+		}
+		opts := dagger.DirectoryDockerBuildOpts{}
+		if build.Dockerfile != "" {
+			opts.Dockerfile = build.Dockerfile
+		}
+		return source.Directory(path.Join(dir, build.Context)).DockerBuild(opts), nil
+	}
+
+	if s.Image == "" {
+		return nil, fmt.Errorf("either image or build is required")
+	}
+
+	return dag.Container().From(s.Image), nil
+}
+
+// env returns the service environment, accepting both the map and list forms.
+func (s composeService) env() (map[string]string, error) {
+	env := map[string]string{}
+	if len(s.Environment) == 0 {
+		return env, nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(s.Environment, &list); err == nil {
+		for _, item := range list {
+			key, value, _ := strings.Cut(item, "=")
+			env[key] = value
+		}
+		return env, nil
+	}
+
+	var values map[string]any
+	if err := json.Unmarshal(s.Environment, &values); err != nil {
+		return nil, fmt.Errorf("invalid environment option: %w", err)
+	}
+	for key, value := range values {
+		if value == nil {
+			env[key] = ""
+			continue
+		}
+		env[key] = fmt.Sprint(value)
+	}
+
+	return env, nil
+}
+
+// envFiles returns the env_file paths, accepting both the string and list forms.
+func (s composeService) envFiles() ([]string, error) {
+	if len(s.EnvFile) == 0 {
+		return nil, nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(s.EnvFile, &list); err == nil {
+		return list, nil
+	}
+
+	var file string
+	if err := json.Unmarshal(s.EnvFile, &file); err != nil {
+		return nil, fmt.Errorf("invalid env_file option: %w", err)
+	}
+
+	return []string{file}, nil
+}
+
+// args returns the command override, accepting both the string and list forms.
+func (s composeService) args() ([]string, error) {
+	if len(s.Command) == 0 {
+		return nil, nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(s.Command, &list); err == nil {
+		return list, nil
+	}
+
+	var command string
+	if err := json.Unmarshal(s.Command, &command); err != nil {
+		return nil, fmt.Errorf("invalid command option: %w", err)
+	}
+
+	return splitCommand(command)
+}
+
+// splitCommand splits a command string into arguments the way a POSIX shell
+// would, honouring single quotes, double quotes and backslash escapes.
+func splitCommand(command string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+
+	for _, r := range command {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+			inArg = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in command %q", command)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
+
+// ports returns the container ports a service listens on.
+func (s composeService) ports() []int {
+	var ports []int
+	for _, entry := range append(s.Ports, s.Expose...) {
+		spec := fmt.Sprint(entry)
+		// "host:container/proto" -> "container"
+		spec, _, _ = strings.Cut(spec, "/")
+		if idx := strings.LastIndex(spec, ":"); idx >= 0 {
+			spec = spec[idx+1:]
+		}
+		if port, err := strconv.Atoi(spec); err == nil {
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
+
+// sortedKeys returns map keys in a stable order to keep containers cacheable.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// IntegrationTest runs pytest with the services of a compose file bound to the
+// test container under their compose service names.
+func (p *Python) IntegrationTest(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Compose file describing the integration environment, relative to source
+	// +optional
+	// +default="docker-compose.test.yml"
+	composeFile string,
+	// Arguments passed to pytest
+	// +optional
+	pytestArgs []string,
+) (string, error) {
+	if composeFile == "" {
+		composeFile = DefaultComposeFile
+	}
+
+	services, err := p.composeServices(ctx, source, composeFile)
+	if err != nil {
+		return "", err
+	}
+
+	container := p.container().
+		From(fmt.Sprintf("python:%s", p.pythonVersion)).
+		WithExec([]string{"pip", "install", "--no-cache-dir", "poetry"}).
+		WithDirectory(containerWorkdir, source).
+		WithWorkdir(containerWorkdir).
+		WithExec([]string{"poetry", "config", "virtualenvs.create", "false"}).
+		WithExec([]string{"poetry", "install", "--no-interaction"})
+
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("🔗 Binding service %s\n", name)
+		container = container.WithServiceBinding(name, services[name])
+	}
+
+	fmt.Println(logStartTests)
+	output, err := container.
+		WithExec(append([]string{"poetry", "run", "pytest"}, pytestArgs...)).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", errPoetryTest, err)
+	}
+	fmt.Println(logSuccessTests)
+
+	return output, nil
+}
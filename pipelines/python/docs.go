@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/felipepimentel/daggerverse/pipelines/python/internal/dagger"
+)
+
+// Error messages for documentation checks.
+const (
+	errDocCoverage = "docstring coverage below threshold"
+)
+
+// DocCoverage measures the share of public modules, classes and functions
+// with a docstring using interrogate, and fails when it is below the
+// threshold. Private and magic members and __init__ methods are not counted.
+func (p *Python) DocCoverage(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Minimum coverage in percent
+	// +optional
+	// +default=80
+	threshold int,
+	// Directory to measure
+	// +optional
+	// +default="src"
+	searchPath string,
+	// Paths to leave out, relative to the source
+	// +optional
+	// +default=["tests"]
+	exclude []string,
+) (string, error) {
+	if threshold <= 0 {
+		threshold = 80
+	}
+	if searchPath == "" {
+		searchPath = "src"
+	}
+	if len(exclude) == 0 {
+		exclude = []string{"tests"}
+	}
+
+	args := []string{
+		"interrogate", "-vv",
+		"--fail-under", strconv.Itoa(threshold),
+		"--ignore-init-method", "--ignore-init-module",
+		"--ignore-private", "--ignore-semiprivate", "--ignore-magic",
+	}
+	for _, path := range exclude {
+		args = append(args, "--exclude", path)
+	}
+
+	fmt.Printf("📝 Checking docstring coverage of %s (minimum %d%%)...\n", searchPath, threshold)
+	output, err := dag.Container().
+		From(fmt.Sprintf("python:%s", p.pythonVersion)).
+		WithExec([]string{"pip", "install", "--no-cache-dir", "interrogate"}).
+		WithDirectory(containerWorkdir, source).
+		WithWorkdir(containerWorkdir).
+		WithExec(append(args, searchPath)).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", errDocCoverage, err)
+	}
+
+	return output, nil
+}
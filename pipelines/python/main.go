@@ -79,6 +79,8 @@ type Python struct {
 	// githubToken is used for GitHub authentication
 	// +private
 	githubToken *dagger.Secret
+	// platform is the platform images and wheels are built for
+	platform dagger.Platform
 }
 
 // New creates a new instance of Python with the provided configuration.
@@ -112,6 +114,9 @@ func New(
 	// GitHub token for authentication
 	// +optional
 	githubToken *dagger.Secret,
+	// Platform to build for, e.g. "linux/arm64" (defaults to the engine's platform)
+	// +optional
+	platform dagger.Platform,
 ) *Python {
 	if pythonVersion == "" {
 		pythonVersion = DefaultPythonVersion
@@ -132,11 +137,42 @@ func New(
 		skipTests:       skipTests,
 		skipLint:        skipLint,
 		githubToken:     githubToken,
+		platform:        platform,
 	}
 }
 
-// Publish builds and publishes a Python package to PyPI
-func (m *Python) Publish(ctx context.Context, source *dagger.Directory, token *dagger.Secret) error {
+// container returns an empty container for the configured platform; an
+// empty platform selects the engine's default platform.
+func (p *Python) container() *dagger.Container {
+	return dag.Container(dagger.ContainerOpts{Platform: p.platform})
+}
+
+// Publish builds and publishes a Python package to PyPI.
+// When running in GitHub Actions, pass the OIDC request URL and token to use
+// trusted publishing instead of a long-lived API token.
+func (m *Python) Publish(
+	ctx context.Context,
+	source *dagger.Directory,
+	// PyPI API token (not needed with trusted publishing)
+	// +optional
+	token *dagger.Secret,
+	// OIDC token request URL ($ACTIONS_ID_TOKEN_REQUEST_URL) to enable trusted publishing
+	// +optional
+	oidcRequestUrl string,
+	// OIDC token request bearer ($ACTIONS_ID_TOKEN_REQUEST_TOKEN) to enable trusted publishing
+	// +optional
+	oidcRequestToken *dagger.Secret,
+	// Sign and upload PEP 740 attestations (requires trusted publishing)
+	// +optional
+	attestations bool,
+	// Index to upload to: pypi or testpypi
+	// +optional
+	// +default="pypi"
+	repository string,
+	// Skip distributions already on the index instead of failing
+	// +optional
+	skipExisting bool,
+) error {
 	// Create base container with git and poetry
 	container := dag.Container().
 		From("python:3.12-alpine").
@@ -148,21 +184,9 @@ func (m *Python) Publish(ctx context.Context, source *dagger.Directory, token *d
 		WithExec([]string{"git", "config", "--global", "user.email", "github-actions[bot]@users.noreply.github.com"}).
 		WithExec([]string{"git", "config", "--global", "user.name", "github-actions[bot]"})
 
-	// Mount source code
-	container = container.WithMountedDirectory("/src", source).WithWorkdir("/src")
-
-	// Check if repository is shallow
-	isShallow, err := container.WithExec([]string{"git", "rev-parse", "--is-shallow-repository"}).Stdout(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to check if repository is shallow: %w", err)
-	}
-
-	// If repository is shallow, unshallow it
-	if strings.TrimSpace(isShallow) == "true" {
-		container = container.WithExec([]string{"git", "fetch", "--unshallow", "--tags"})
-	} else {
-		container = container.WithExec([]string{"git", "fetch", "--tags"})
-	}
+	// Mount source code with the full history and tags, which semantic-release needs
+	history := dag.GitRepo(dagger.GitRepoOpts{Source: source, Token: m.githubToken}).Fetch().Directory()
+	container = container.WithMountedDirectory("/src", history).WithWorkdir("/src")
 
 	// Run semantic-release to determine and set the new version
 	container = container.WithExec([]string{"semantic-release", "version"})
@@ -179,13 +203,22 @@ with open('pyproject.toml', 'rb') as f:
 	}
 	version = strings.TrimSpace(version)
 
-	// Build the package with the new version
-	dist := dag.Poetry().BuildWithVersion(source, version)
+	// Build the package with the new version, skipping development dependencies
+	dist := dag.Poetry().
+		WithDependencies(dagger.PoetryWithDependenciesOpts{Only: []string{"main"}}).
+		BuildWithVersion(source, version)
 
 	// Publish to PyPI
-	err = dag.Pypi().Publish(ctx, dist, token)
+	err = dag.Pypi().Publish(ctx, dist, dagger.PypiPublishOpts{
+		Token:            token,
+		Repository:       repository,
+		SkipExisting:     skipExisting,
+		OidcRequestURL:   oidcRequestUrl,
+		OidcRequestToken: oidcRequestToken,
+		Attestations:     attestations,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to publish package: %w", err)
+		return fmt.Errorf("%s: %w", errPypiPublish, err)
 	}
 
 	// Create and push git tag if GitHub token is provided
@@ -201,7 +234,7 @@ with open('pyproject.toml', 'rb') as f:
 // Build creates a container with all dependencies installed and configured.
 // It returns the configured container or nil if the build fails.
 func (p *Python) Build(ctx context.Context, source *dagger.Directory) *dagger.Container {
-	container := dag.Container()
+	container := p.container()
 	
 	// Add Docker Hub authentication if credentials are provided
 	if p.dockerUsername != "" && p.dockerPassword != nil {
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/felipepimentel/daggerverse/pipelines/python/internal/dagger"
+)
+
+// Error messages for wheel smoke tests.
+const (
+	errSmokeTest = "wheel smoke test failed"
+	errNoWheel   = "no wheel built"
+)
+
+// smokeWheelsDir is where the built wheels are mounted in smoke test containers.
+const smokeWheelsDir = "/smoke-wheels"
+
+// smokeImage returns the image of a base image family for a Python version:
+// alpine (musl), debian (glibc) or ubi (Red Hat Universal Base Image).
+func smokeImage(family, pythonVersion string) (string, error) {
+	switch family {
+	case "alpine":
+		return fmt.Sprintf("python:%s-alpine", pythonVersion), nil
+	case "debian":
+		return fmt.Sprintf("python:%s-slim", pythonVersion), nil
+	case "ubi":
+		return "registry.access.redhat.com/ubi9/python-" + strings.ReplaceAll(pythonVersion, ".", ""), nil
+	default:
+		return "", fmt.Errorf("unsupported base image %q, expected alpine, debian or ubi", family)
+	}
+}
+
+// WheelSmokeTest builds the wheel and installs and imports it on several base
+// images and Python versions, to catch platform-specific packaging issues
+// (missing musl wheels of dependencies, compiled extensions, wrong
+// python_requires) before publishing. Combine with the platform option to
+// run it on emulated architectures.
+func (p *Python) WheelSmokeTest(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Base image families: alpine, debian and ubi
+	// +optional
+	// +default=["alpine", "debian", "ubi"]
+	images []string,
+	// Python versions, e.g. 3.11
+	// +optional
+	// +default=["3.12"]
+	pythonVersions []string,
+	// Module to import (defaults to the distribution name of the wheel)
+	// +optional
+	module string,
+) (string, error) {
+	if len(images) == 0 {
+		images = []string{"alpine", "debian", "ubi"}
+	}
+	if len(pythonVersions) == 0 {
+		pythonVersions = []string{"3.12"}
+	}
+
+	fmt.Println(logStartBuild)
+	dist := dag.Poetry().Build(source)
+	wheels, err := dist.Glob(ctx, "*.whl")
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", errBuild, err)
+	}
+	if len(wheels) == 0 {
+		return "", fmt.Errorf("%s: %s", errSmokeTest, errNoWheel)
+	}
+	if module == "" {
+		// Wheel names start with the normalized distribution name
+		module = strings.ToLower(strings.SplitN(path.Base(wheels[0]), "-", 2)[0])
+	}
+
+	var report []string
+	failed := 0
+	for _, family := range images {
+		for _, version := range pythonVersions {
+			image, err := smokeImage(family, version)
+			if err != nil {
+				return "", err
+			}
+
+			fmt.Printf("💨 Installing and importing %s on %s...\n", module, image)
+			_, err = p.container().
+				From(image).
+				WithMountedDirectory(smokeWheelsDir, dist).
+				WithExec([]string{"sh", "-c", "pip install --no-cache-dir " + smokeWheelsDir + "/*.whl"}).
+				WithExec([]string{"python", "-c", "import " + module}).
+				Sync(ctx)
+			if err != nil {
+				failed++
+				report = append(report, fmt.Sprintf("❌ %s: %v", image, err))
+				continue
+			}
+			report = append(report, "✅ "+image)
+		}
+	}
+
+	output := strings.Join(report, "\n")
+	if failed > 0 {
+		return output, fmt.Errorf("%s on %d of %d images:\n%s", errSmokeTest, failed, len(report), output)
+	}
+	return output, nil
+}
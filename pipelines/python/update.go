@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/pipelines/python/internal/dagger"
+)
+
+// Error messages for dependency updates.
+const (
+	errUpdate     = "failed to update dependencies"
+	errUpdatePR   = "failed to open dependency update pull request"
+	errUpdateTool = "unsupported update tool"
+)
+
+// lockfiles maps the supported update tools to their lockfile and the
+// command upgrading every dependency in it.
+var lockfiles = map[string]struct {
+	name    string
+	install string
+	update  []string
+}{
+	"poetry": {"poetry.lock", "poetry", []string{"poetry", "update", "--lock", "--no-interaction"}},
+	"uv":     {"uv.lock", "uv", []string{"uv", "lock", "--upgrade"}},
+}
+
+// UpdateDependencies upgrades the locked dependencies and, when a GitHub token
+// is available, opens or updates a pull request with the new lockfile and a
+// summary of the upgraded packages; each run resets its branch to the base.
+// Without a token it only returns the summary, which makes it usable as a dry
+// run. Scheduled from CI, it keeps dependencies current without a separate bot.
+func (p *Python) UpdateDependencies(
+	ctx context.Context,
+	source *dagger.Directory,
+	// Tool managing the lockfile: poetry or uv
+	// +optional
+	// +default="poetry"
+	tool string,
+	// GitHub token to open the pull request (defaults to the pipeline's token)
+	// +optional
+	token *dagger.Secret,
+	// GitHub repository, e.g. owner/repo
+	// +optional
+	repo string,
+	// Branch to push the update to
+	// +optional
+	// +default="deps/update"
+	branch string,
+	// Branch the pull request targets (defaults to the default branch)
+	// +optional
+	base string,
+	// Labels added to the pull request
+	// +optional
+	// +default=["dependencies"]
+	labels []string,
+) (string, error) {
+	if tool == "" {
+		tool = "poetry"
+	}
+	lockfile, ok := lockfiles[tool]
+	if !ok {
+		return "", fmt.Errorf("%s %q, expected poetry or uv", errUpdateTool, tool)
+	}
+	if token == nil {
+		token = p.githubToken
+	}
+	if len(labels) == 0 {
+		labels = []string{"dependencies"}
+	}
+
+	before, err := source.File(lockfile.name).Contents(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: no %s in source: %w", errUpdate, lockfile.name, err)
+	}
+
+	fmt.Printf("🔄 Updating %s...\n", lockfile.name)
+	updated := p.container().
+		From(fmt.Sprintf("python:%s", p.pythonVersion)).
+		WithExec([]string{"pip", "install", "--no-cache-dir", lockfile.install}).
+		WithDirectory(containerWorkdir, source).
+		WithWorkdir(containerWorkdir).
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)).
+		WithExec(lockfile.update).
+		File(lockfile.name)
+
+	after, err := updated.Contents(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", errUpdate, err)
+	}
+
+	summary := lockfileChanges(lockedVersions(before), lockedVersions(after))
+	if summary == "" {
+		fmt.Println("✅ Dependencies are up to date")
+		return "Dependencies are up to date", nil
+	}
+	if token == nil {
+		return summary, nil
+	}
+
+	body := "Upgrades the locked dependencies with `" + strings.Join(lockfile.update, " ") + "`.\n\n" + summary
+	url, err := dag.Gh().PullRequest().CreateOrUpdate(
+		ctx,
+		branch,
+		dag.Directory().WithFile(lockfile.name, updated),
+		"chore(deps): update dependencies",
+		dagger.GhPullRequestCreateOrUpdateOpts{
+			Body:        body,
+			Base:        base,
+			Labels:      labels,
+			AuthorName:  p.gitName,
+			AuthorEmail: p.gitEmail,
+			Token:       token,
+			Repo:        repo,
+		},
+	)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", errUpdatePR, err)
+	}
+
+	fmt.Printf("✅ Dependency update pull request: %s\n", url)
+	return url, nil
+}
+
+// lockedVersions returns the version of each [[package]] entry of a
+// poetry.lock or uv.lock file.
+func lockedVersions(lockfile string) map[string]string {
+	versions := map[string]string{}
+	name := ""
+	for _, line := range strings.Split(lockfile, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "["):
+			name = ""
+			if line == "[[package]]" {
+				name = "?"
+			}
+		case name != "" && strings.HasPrefix(line, "name = "):
+			name = strings.Trim(strings.TrimPrefix(line, "name = "), `"`)
+		case name != "" && name != "?" && strings.HasPrefix(line, "version = "):
+			versions[name] = strings.Trim(strings.TrimPrefix(line, "version = "), `"`)
+			name = ""
+		}
+	}
+	return versions
+}
+
+// lockfileChanges renders the added, removed and upgraded packages as a
+// markdown table linking to their release on PyPI; it is empty when nothing
+// changed.
+func lockfileChanges(before, after map[string]string) string {
+	names := map[string]bool{}
+	for name := range before {
+		names[name] = true
+	}
+	for name := range after {
+		names[name] = true
+	}
+
+	var rows []string
+	for name := range names {
+		from, to := before[name], after[name]
+		if from == to {
+			continue
+		}
+
+		release := "removed"
+		if to != "" {
+			release = fmt.Sprintf("[release](https://pypi.org/project/%s/%s/)", name, to)
+		}
+		rows = append(rows, fmt.Sprintf("| %s | %s | %s | %s |", name, orDash(from), orDash(to), release))
+	}
+	if len(rows) == 0 {
+		return ""
+	}
+	sort.Strings(rows)
+
+	return "| Package | From | To | Changes |\n|---|---|---|---|\n" + strings.Join(rows, "\n") + "\n"
+}
+
+// orDash returns the value, or a dash when it is empty.
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
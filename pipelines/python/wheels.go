@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/felipepimentel/daggerverse/pipelines/python/internal/dagger"
+)
+
+// Wheel vendoring defaults.
+const (
+	// wheelsDir is where vendored wheels are written inside the container.
+	wheelsDir = "/wheels"
+	// cacheNamespace prefixes the cache volumes of the pipeline.
+	cacheNamespace = "python-pipeline"
+)
+
+// VendorWheels pre-downloads all project dependencies into a directory of wheels.
+// The result can be passed to OfflineInstall (or used with pip install --no-index
+// --find-links) so later stages and air-gapped builds never reach a package index.
+func (p *Python) VendorWheels(
+	source *dagger.Directory,
+	// Include development dependencies
+	// +optional
+	// +default=false
+	withDev bool,
+) *dagger.Directory {
+	exportArgs := []string{
+		"poetry", "export",
+		"--format", "requirements.txt",
+		"--without-hashes",
+		"--output", "/tmp/requirements.txt",
+	}
+	if withDev {
+		exportArgs = append(exportArgs, "--with", "dev")
+	}
+
+	return p.container().
+		From(fmt.Sprintf("python:%s", p.pythonVersion)).
+		WithMountedCache("/root/.cache/pip", dag.Cache(cacheNamespace).Volume("pip-cache")).
+		WithExec([]string{"pip", "install", "poetry", "poetry-plugin-export"}).
+		WithDirectory(containerWorkdir, source).
+		WithWorkdir(containerWorkdir).
+		WithExec(exportArgs).
+		WithExec([]string{"pip", "wheel", "--wheel-dir", wheelsDir, "--requirement", "/tmp/requirements.txt"}).
+		WithExec([]string{"pip", "wheel", "--no-deps", "--wheel-dir", wheelsDir, "."}).
+		Directory(wheelsDir)
+}
+
+// WarmCache downloads all project dependencies into the shared pip cache volume.
+// It returns the vendored wheels so callers can also keep them as an artifact.
+func (p *Python) WarmCache(
+	source *dagger.Directory,
+	// Include development dependencies
+	// +optional
+	// +default=true
+	withDev bool,
+) *dagger.Directory {
+	return p.VendorWheels(source, withDev)
+}
+
+// OfflineInstall installs the project from a vendored wheels directory without
+// contacting any package index.
+func (p *Python) OfflineInstall(
+	source *dagger.Directory,
+	// Wheels directory produced by VendorWheels
+	wheels *dagger.Directory,
+) *dagger.Container {
+	return p.container().
+		From(fmt.Sprintf("python:%s", p.pythonVersion)).
+		WithMountedDirectory(wheelsDir, wheels).
+		WithDirectory(containerWorkdir, source).
+		WithWorkdir(containerWorkdir).
+		WithExec([]string{"sh", "-c", "pip install --no-index --find-links " + wheelsDir + " " + wheelsDir + "/*.whl"})
+}
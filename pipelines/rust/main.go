@@ -0,0 +1,267 @@
+// Package main provides a pipeline for Rust projects: cargo build and test,
+// clippy and rustfmt checks, and publishing to crates.io.
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/felipepimentel/daggerverse/pipelines/rust/internal/dagger"
+)
+
+// Tool versions and paths.
+const (
+	// DefaultRustVersion is the default tag of the rust image.
+	DefaultRustVersion = "1"
+	// zigbuildImage provides cargo-zigbuild, which links for other targets with zig.
+	zigbuildImage = "ghcr.io/rust-cross/cargo-zigbuild:0.19.7"
+
+	// containerWorkdir is the working directory inside the container.
+	containerWorkdir = "/src"
+	// targetDir is where cargo writes build output, kept in a cache volume.
+	targetDir = "/cargo-target"
+	// outputDir is where binaries are copied out of the cache volume.
+	outputDir = "/out"
+)
+
+// Error messages for common failures.
+const (
+	errTest    = "cargo test failed"
+	errClippy  = "clippy found issues"
+	errFmt     = "code is not formatted, run cargo fmt"
+	errPublish = "failed to publish crate"
+)
+
+// copyBinariesScript copies the executables of a build profile directory
+// ($1) to $2; the target directory itself lives in a cache volume, which
+// cannot be exported.
+const copyBinariesScript = `set -e
+mkdir -p "$2"
+find "$1" -maxdepth 1 -type f \( -perm -u+x -o -name '*.exe' \) -exec cp {} "$2"/ \;`
+
+// Rust runs the build, test and publish workflow of a Rust project.
+type Rust struct {
+	// Project source, with Cargo.toml at its root
+	// +private
+	Source *dagger.Directory
+
+	// Tag of the rust image
+	// +private
+	Version string
+
+	// Prefix of the registry and target cache volumes
+	// +private
+	CacheNamespace string
+}
+
+// New creates a new instance of Rust for a project.
+func New(
+	// Project source, with Cargo.toml at its root
+	source *dagger.Directory,
+	// Tag of the rust image, e.g. 1.83 or 1-slim
+	// +optional
+	// +default="1"
+	version string,
+	// Prefix of the cache volumes; set it per project to keep caches apart
+	// +optional
+	// +default="rust"
+	cacheNamespace string,
+) *Rust {
+	if version == "" {
+		version = DefaultRustVersion
+	}
+	if cacheNamespace == "" {
+		cacheNamespace = "rust"
+	}
+
+	return &Rust{
+		Source:         source,
+		Version:        version,
+		CacheNamespace: cacheNamespace,
+	}
+}
+
+// Container returns the rust container with the source mounted and the
+// registry and target caches set up.
+func (m *Rust) Container() *dagger.Container {
+	return m.withCaches(dag.Container().From("rust:" + m.Version))
+}
+
+// Build compiles the project and returns its binaries. With targets, e.g.
+// x86_64-unknown-linux-musl, the binaries of each target are written to a
+// directory named after it.
+func (m *Rust) Build(
+	// Target triples to cross-compile for (defaults to the host)
+	// +optional
+	targets []string,
+	// Build with the release profile
+	// +optional
+	// +default=true
+	release bool,
+	// Features to enable
+	// +optional
+	features []string,
+	// Link with zig through cargo-zigbuild, for targets needing a foreign
+	// linker such as aarch64-unknown-linux-gnu or the macOS targets
+	// +optional
+	zig bool,
+) *dagger.Directory {
+	profile := "debug"
+	args := []string{"cargo", "build", "--locked"}
+	if release {
+		profile = "release"
+		args = append(args, "--release")
+	}
+	for _, feature := range features {
+		args = append(args, "--features", feature)
+	}
+
+	if len(targets) == 0 {
+		return m.Container().
+			WithExec(args).
+			WithExec([]string{"sh", "-c", copyBinariesScript, "sh", targetDir + "/" + profile, outputDir}).
+			Directory(outputDir)
+	}
+
+	container := m.Container()
+	if zig {
+		container = m.withCaches(dag.Container().From(zigbuildImage))
+		args[1] = "zigbuild"
+	}
+
+	output := dag.Directory()
+	for _, target := range targets {
+		fmt.Printf("🏗️  Building for %s...\n", target)
+		built := container.
+			WithExec([]string{"rustup", "target", "add", target}).
+			WithExec(append(append([]string{}, args...), "--target", target)).
+			WithExec([]string{"sh", "-c", copyBinariesScript, "sh", targetDir + "/" + target + "/" + profile, outputDir + "/" + target})
+		output = output.WithDirectory(target, built.Directory(outputDir+"/"+target))
+	}
+
+	return output
+}
+
+// Test runs cargo test and returns its output.
+func (m *Rust) Test(
+	ctx context.Context,
+	// Extra cargo test arguments, e.g. --all-features or --workspace
+	// +optional
+	args []string,
+) (string, error) {
+	fmt.Println("🧪 Running tests...")
+	output, err := m.Container().
+		WithExec(append([]string{"cargo", "test", "--locked"}, args...)).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", errTest, err)
+	}
+
+	fmt.Println("✅ Tests passed")
+	return output, nil
+}
+
+// Clippy lints the project with clippy, failing on warnings by default.
+func (m *Rust) Clippy(
+	ctx context.Context,
+	// Fail on warnings
+	// +optional
+	// +default=true
+	denyWarnings bool,
+	// Extra cargo clippy arguments, e.g. --all-targets
+	// +optional
+	args []string,
+) (string, error) {
+	command := append([]string{"cargo", "clippy", "--locked"}, args...)
+	if denyWarnings {
+		command = append(command, "--", "-D", "warnings")
+	}
+
+	output, err := m.Container().
+		WithExec([]string{"rustup", "component", "add", "clippy"}).
+		WithExec(command).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", errClippy, err)
+	}
+
+	fmt.Println("✅ Clippy passed")
+	return output, nil
+}
+
+// Fmt checks the formatting of the project with rustfmt.
+func (m *Rust) Fmt(ctx context.Context) (string, error) {
+	output, err := m.Container().
+		WithExec([]string{"rustup", "component", "add", "rustfmt"}).
+		WithExec([]string{"cargo", "fmt", "--all", "--check"}).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", errFmt, err)
+	}
+
+	fmt.Println("✅ Formatting is correct")
+	return output, nil
+}
+
+// Publish publishes the crate to crates.io, or checks that it would publish
+// with dryRun.
+func (m *Rust) Publish(
+	ctx context.Context,
+	// crates.io API token
+	// +optional
+	token *dagger.Secret,
+	// Package to publish in a workspace
+	// +optional
+	pkg string,
+	// Package and verify the crate without uploading it
+	// +optional
+	dryRun bool,
+) (string, error) {
+	if token == nil && !dryRun {
+		return "", fmt.Errorf("%s: a crates.io token is required unless dryRun is set", errPublish)
+	}
+
+	args := []string{"cargo", "publish", "--locked"}
+	if pkg != "" {
+		args = append(args, "--package", pkg)
+	}
+	if dryRun {
+		args = append(args, "--dry-run")
+	}
+
+	container := m.Container()
+	if token != nil {
+		container = container.WithSecretVariable("CARGO_REGISTRY_TOKEN", token)
+	}
+
+	fmt.Println("📦 Publishing to crates.io...")
+	output, err := container.
+		WithEnvVariable("CACHE_BUSTER", time.Now().Format(time.RFC3339Nano)).
+		WithExec(args).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", errPublish, err)
+	}
+
+	if dryRun {
+		fmt.Println("📝 Dry run passed, crate would be published")
+		return output, nil
+	}
+
+	fmt.Println("✅ Crate published")
+	return output, nil
+}
+
+// withCaches mounts the source and the registry and target caches.
+func (m *Rust) withCaches(container *dagger.Container) *dagger.Container {
+	cache := dag.Cache(m.CacheNamespace)
+
+	return container.
+		WithMountedCache("/usr/local/cargo/registry", cache.Volume("registry")).
+		WithMountedCache("/usr/local/cargo/git", cache.Volume("git")).
+		WithMountedCache(targetDir, cache.Volume("target")).
+		WithEnvVariable("CARGO_TARGET_DIR", targetDir).
+		WithDirectory(containerWorkdir, m.Source).
+		WithWorkdir(containerWorkdir)
+}
@@ -0,0 +1,45 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+# Prints a JSON array with the modules (directories containing dagger.json)
+# that changed since their last release tag. Modules without any tag are
+# always included. Set FORCE_ALL=true to include every module.
+
+FORCE_ALL="${FORCE_ALL:-false}"
+
+MODULES=$(find . -name "dagger.json" -not -path "*/node_modules/*" -exec dirname {} \; | sed 's|^./||' | sort)
+
+CHANGED=()
+for MODULE_NAME in $MODULES; do
+    # Handle root directory specially
+    if [ "$MODULE_NAME" = "." ]; then
+        TAG_PREFIX="root"
+    else
+        TAG_PREFIX="$MODULE_NAME"
+    fi
+
+    if [ "$FORCE_ALL" = "true" ]; then
+        CHANGED+=("$MODULE_NAME")
+        continue
+    fi
+
+    LAST_TAG=$(git tag -l "$TAG_PREFIX/v*" | sort -V | tail -n1)
+    if [ -z "$LAST_TAG" ]; then
+        echo "Module $MODULE_NAME has no release yet" >&2
+        CHANGED+=("$MODULE_NAME")
+        continue
+    fi
+
+    if ! git diff --quiet "$LAST_TAG" HEAD -- "$MODULE_NAME"; then
+        echo "Module $MODULE_NAME changed since $LAST_TAG" >&2
+        CHANGED+=("$MODULE_NAME")
+    else
+        echo "Module $MODULE_NAME unchanged since $LAST_TAG, skipping" >&2
+    fi
+done
+
+if [ ${#CHANGED[@]} -eq 0 ]; then
+    echo "[]"
+else
+    printf '%s\n' "${CHANGED[@]}" | jq -R -s -c 'split("\n")[:-1]'
+fi
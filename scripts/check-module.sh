@@ -0,0 +1,44 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+# Runs the checks a module must pass before it is tagged:
+# - `dagger functions` loads the module and lists its functions
+# - the module's tests module, if present, runs its `all` function
+#
+# Tests modules are looked up at <module>/tests and tests/<module name>.
+
+MODULE_NAME="${1:-}"
+if [ -z "$MODULE_NAME" ]; then
+    echo "Error: MODULE_NAME is required"
+    exit 1
+fi
+
+if [ ! -d "$MODULE_NAME" ]; then
+    echo "Error: Module directory $MODULE_NAME does not exist"
+    exit 1
+fi
+
+echo "Checking that module $MODULE_NAME loads"
+if ! dagger functions -m "$MODULE_NAME"; then
+    echo "::error::Module $MODULE_NAME failed to load"
+    exit 1
+fi
+
+TESTS_MODULE=""
+for CANDIDATE in "$MODULE_NAME/tests" "tests/$(basename "$MODULE_NAME")"; do
+    if [ -f "$CANDIDATE/dagger.json" ]; then
+        TESTS_MODULE="$CANDIDATE"
+        break
+    fi
+done
+
+if [ -z "$TESTS_MODULE" ]; then
+    echo "::notice::No tests module found for $MODULE_NAME, skipping tests"
+    exit 0
+fi
+
+echo "Running tests module $TESTS_MODULE"
+if ! dagger call -m "$TESTS_MODULE" all; then
+    echo "::error::Tests failed for module $MODULE_NAME"
+    exit 1
+fi
@@ -0,0 +1,53 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+# Installs a pinned Dagger CLI from the official releases, verifying the
+# archive against the published checksums.
+
+DAGGER_VERSION="${1:-${DAGGER_VERSION:-}}"
+if [ -z "$DAGGER_VERSION" ]; then
+    echo "Error: DAGGER_VERSION is required"
+    exit 1
+fi
+DAGGER_VERSION="${DAGGER_VERSION#v}"
+
+INSTALL_DIR="${INSTALL_DIR:-$HOME/.local/bin}"
+
+case "$(uname -m)" in
+    x86_64) ARCH="amd64" ;;
+    aarch64 | arm64) ARCH="arm64" ;;
+    *)
+        echo "Error: unsupported architecture $(uname -m)"
+        exit 1
+        ;;
+esac
+OS="$(uname -s | tr '[:upper:]' '[:lower:]')"
+
+BASE_URL="https://dl.dagger.io/dagger/releases/${DAGGER_VERSION}"
+ARCHIVE="dagger_v${DAGGER_VERSION}_${OS}_${ARCH}.tar.gz"
+
+TMP_DIR=$(mktemp -d)
+trap 'rm -rf "$TMP_DIR"' EXIT
+
+echo "Installing Dagger CLI v${DAGGER_VERSION} (${OS}/${ARCH})"
+curl -fsSL --retry 3 -o "$TMP_DIR/$ARCHIVE" "$BASE_URL/$ARCHIVE"
+curl -fsSL --retry 3 -o "$TMP_DIR/checksums.txt" "$BASE_URL/checksums.txt"
+
+# Verify the archive against the published checksum
+(
+    cd "$TMP_DIR"
+    if ! grep " ${ARCHIVE}\$" checksums.txt | sha256sum --check --status; then
+        echo "::error::Checksum verification failed for $ARCHIVE"
+        exit 1
+    fi
+)
+
+mkdir -p "$INSTALL_DIR"
+tar -xzf "$TMP_DIR/$ARCHIVE" -C "$TMP_DIR" dagger
+install -m 0755 "$TMP_DIR/dagger" "$INSTALL_DIR/dagger"
+
+if [ -n "${GITHUB_PATH:-}" ]; then
+    echo "$INSTALL_DIR" >> "$GITHUB_PATH"
+fi
+
+"$INSTALL_DIR/dagger" version
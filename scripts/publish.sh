@@ -50,8 +50,27 @@ else
     PUBLISH_CMD="dagger publish"
 fi
 
-$PUBLISH_CMD || {
-    echo "::error::Failed to publish module $MODULE_NAME"
-    echo "::error::Please check if the module is properly configured and try again"
-    exit 1
-} 
\ No newline at end of file
+# Retry transient publish failures with exponential backoff
+MAX_ATTEMPTS="${PUBLISH_RETRIES:-3}"
+DELAY="${PUBLISH_RETRY_DELAY:-10}"
+ATTEMPT=1
+PUBLISH_LOG=$(mktemp)
+until $PUBLISH_CMD 2>&1 | tee "$PUBLISH_LOG"; do
+    if [ "$ATTEMPT" -ge "$MAX_ATTEMPTS" ]; then
+        echo "::error::Failed to publish module $MODULE_NAME after $ATTEMPT attempts"
+        echo "::error::Please check if the module is properly configured and try again"
+        exit 1
+    fi
+    echo "::warning::Publish attempt $ATTEMPT for $MODULE_NAME failed, retrying in ${DELAY}s"
+    sleep "$DELAY"
+    ATTEMPT=$((ATTEMPT + 1))
+    DELAY=$((DELAY * 2))
+done
+
+# Surface the published module URL to the workflow
+PUBLISH_URL=$(grep -oE 'https://daggerverse\.dev/[^[:space:]]+' "$PUBLISH_LOG" | tail -n1 || true)
+echo "Published $MODULE_NAME ${TAG}: ${PUBLISH_URL:-no URL reported}"
+if [ -n "${GITHUB_OUTPUT:-}" ]; then
+    echo "url=${PUBLISH_URL}" >> "$GITHUB_OUTPUT"
+    echo "tag=${TAG}" >> "$GITHUB_OUTPUT"
+fi
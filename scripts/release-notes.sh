@@ -0,0 +1,67 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+# Renders markdown release notes for a module from the conventional commits
+# that touched its directory between two refs.
+#
+# Usage: release-notes.sh MODULE_PATH [FROM_REF] [TO_REF]
+
+MODULE_PATH="${1:-}"
+FROM_REF="${2:-}"
+TO_REF="${3:-HEAD}"
+
+if [ -z "$MODULE_PATH" ]; then
+    echo "Error: MODULE_PATH is required" >&2
+    exit 1
+fi
+
+if [ -n "$FROM_REF" ]; then
+    RANGE="$FROM_REF..$TO_REF"
+else
+    RANGE="$TO_REF"
+fi
+
+FEATURES=""
+FIXES=""
+BREAKING=""
+OTHER=""
+
+while IFS=$'\t' read -r HASH SUBJECT; do
+    [ -z "$HASH" ] && continue
+
+    # Skip release commits created by semantic-release
+    case "$SUBJECT" in
+        *"[skip ci]"*) continue ;;
+    esac
+
+    LINE="- ${SUBJECT} (${HASH:0:7})"
+    BODY=$(git log -1 --pretty=%b "$HASH")
+
+    if [[ "$SUBJECT" =~ ^[a-z]+(\(.+\))?!: ]] || [[ "$BODY" == *"BREAKING CHANGE"* ]]; then
+        BREAKING+="$LINE"$'\n'
+    elif [[ "$SUBJECT" =~ ^feat(\(.+\))?: ]]; then
+        FEATURES+="$LINE"$'\n'
+    elif [[ "$SUBJECT" =~ ^(fix|perf)(\(.+\))?: ]]; then
+        FIXES+="$LINE"$'\n'
+    else
+        OTHER+="$LINE"$'\n'
+    fi
+done < <(git log --no-merges --pretty='%H%x09%s' "$RANGE" -- "$MODULE_PATH")
+
+print_section() {
+    if [ -n "$2" ]; then
+        echo "### $1"
+        echo
+        printf '%s' "$2"
+        echo
+    fi
+}
+
+print_section "⚠ Breaking Changes" "$BREAKING"
+print_section "Features" "$FEATURES"
+print_section "Bug Fixes" "$FIXES"
+print_section "Other Changes" "$OTHER"
+
+if [ -z "$BREAKING$FEATURES$FIXES$OTHER" ]; then
+    echo "No changes to $MODULE_PATH."
+fi
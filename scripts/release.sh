@@ -50,9 +50,26 @@ fi
 
 echo "Creating new release for module $MODULE_NAME"
 
+PREVIOUS_TAG=$(git tag -l "$MODULE_NAME/v*" | sort -V | tail -n1)
+
 # Actual release
 if ! npx semantic-release; then
     echo "::error::Failed to create release for module $MODULE_NAME"
     echo "::error::Please check the semantic-release logs for more details"
     exit 1
+fi
+
+# Replace the repository-wide notes with notes scoped to this module
+git fetch --tags origin
+NEW_TAG=$(git tag -l "$MODULE_NAME/v*" | sort -V | tail -n1)
+if [ -n "$NEW_TAG" ] && [ "$NEW_TAG" != "$PREVIOUS_TAG" ]; then
+    NOTES_FILE=$(mktemp)
+    "$(dirname "$0")/release-notes.sh" "$MODULE_PATH" "$PREVIOUS_TAG" "$NEW_TAG" > "$NOTES_FILE"
+
+    echo "Publishing release notes for $NEW_TAG"
+    if gh release view "$NEW_TAG" >/dev/null 2>&1; then
+        gh release edit "$NEW_TAG" --notes-file "$NOTES_FILE"
+    else
+        gh release create "$NEW_TAG" --title "$NEW_TAG" --notes-file "$NOTES_FILE"
+    fi
 fi 
\ No newline at end of file
@@ -0,0 +1,46 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+# Prepends the notes of a new module version to <module>/CHANGELOG.md,
+# listing only the commits that touched the module since its previous tag.
+#
+# Usage: update-changelog.sh MODULE_PATH VERSION
+# Requires MODULE_NAME (the tag prefix) in the environment.
+
+MODULE_PATH="${1:-}"
+VERSION="${2:-}"
+if [ -z "$MODULE_PATH" ] || [ -z "$VERSION" ]; then
+    echo "Error: MODULE_PATH and VERSION are required" >&2
+    exit 1
+fi
+
+TAG_PREFIX="${MODULE_NAME:-$MODULE_PATH}"
+CHANGELOG="$MODULE_PATH/CHANGELOG.md"
+PREVIOUS_TAG=$(git tag -l "$TAG_PREFIX/v*" | sort -V | tail -n1)
+
+NOTES=$("$(dirname "$0")/release-notes.sh" "$MODULE_PATH" "$PREVIOUS_TAG" HEAD)
+
+HEADER="## ${VERSION} ($(date -u +%Y-%m-%d))"
+if [ -n "$PREVIOUS_TAG" ] && [ -n "${GITHUB_REPOSITORY:-}" ]; then
+    HEADER="## [${VERSION}](https://github.com/${GITHUB_REPOSITORY}/compare/${PREVIOUS_TAG}...${TAG_PREFIX}/v${VERSION}) ($(date -u +%Y-%m-%d))"
+fi
+
+# Keep previous entries below the new one, without the file title
+PREVIOUS=""
+if [ -f "$CHANGELOG" ]; then
+    PREVIOUS=$(sed '1{/^# Changelog/d}' "$CHANGELOG")
+fi
+
+{
+    echo "# Changelog"
+    echo
+    echo "$HEADER"
+    echo
+    echo "$NOTES"
+    if [ -n "$PREVIOUS" ]; then
+        echo
+        echo "$PREVIOUS" | sed '/./,$!d'
+    fi
+} > "$CHANGELOG"
+
+echo "Updated $CHANGELOG for ${TAG_PREFIX}/v${VERSION}"